@@ -13,8 +13,12 @@ import (
 	httpHandler "github.com/backend-challenge/user-api/internal/adapters/http"
 	"github.com/backend-challenge/user-api/internal/adapters/jwt"
 	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/adapters/notifier"
+	"github.com/backend-challenge/user-api/internal/adapters/payment"
 	"github.com/backend-challenge/user-api/internal/adapters/redis"
 	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
 	"github.com/backend-challenge/user-api/pkg/config"
 	"github.com/backend-challenge/user-api/pkg/logger"
 	redisClient "github.com/redis/go-redis/v9"
@@ -59,28 +63,124 @@ func main() {
 	db := mongoClient.Database("userdb")
 	userRepo := mongodb.NewUserRepository(db)
 	lotteryRepo := mongodb.NewLotteryRepository(db, rdb)
+	drawRepo := mongodb.NewLotteryDrawRepository(db)
+	orderRepo := mongodb.NewOrderRepository(db)
 	sessionManager := redis.NewSessionManager(rdb)
 	tokenService := jwt.NewTokenService(cfg.JWTSecret, cfg.JWTAccessTokenSec, cfg.JWTRefreshTokenSec)
 
-	userService := application.NewUserService(userRepo)
-	authService := application.NewAuthService(userRepo, sessionManager, tokenService)
-	lotteryService := application.NewLotteryService(lotteryRepo)
+	authService := application.NewAuthService(userRepo, sessionManager, tokenService, newNotifier(cfg), domain.AccountPolicy{
+		PasswordResetTTL:     time.Duration(cfg.PasswordResetTTLSec) * time.Second,
+		PasswordResetURL:     cfg.PasswordResetURL,
+		EmailVerificationTTL: time.Duration(cfg.EmailVerificationTTLSec) * time.Second,
+		EmailVerificationURL: cfg.EmailVerificationURL,
+	})
+	userService := application.NewUserService(userRepo, authService)
+	reservationQuota := redis.NewReservationQuota(rdb)
+	reservationPolicy := domain.ReservationPolicy{
+		TTL:               time.Duration(cfg.LotteryReservationTTLSec) * time.Second,
+		MaxTicketsPerUser: cfg.LotteryMaxTicketsPerUser,
+		ExtensionDuration: time.Duration(cfg.LotteryHoldExtensionSec) * time.Second,
+		MaxExtensions:     cfg.LotteryMaxHoldExtensions,
+	}
+	lotteryService := application.NewLotteryService(lotteryRepo, drawRepo, reservationQuota, reservationPolicy)
+	drawService := application.NewLotteryDrawService(drawRepo, lotteryRepo, domain.DrawTemplate{
+		TicketCount:    cfg.LotteryDrawTicketCount,
+		SetsPerNumber:  cfg.LotteryDrawSetsPerNumber,
+		TicketPrice:    cfg.LotteryTicketPrice,
+		SalesOpenLead:  time.Duration(cfg.LotterySalesOpenLeadSec) * time.Second,
+		SalesCloseLead: time.Duration(cfg.LotterySalesCloseLeadSec) * time.Second,
+	})
+	paymentGateway := payment.NewFakeGateway(cfg.PaymentWebhookSecret, cfg.PaymentCheckoutURL)
+	orderService := application.NewOrderService(orderRepo, lotteryRepo, drawRepo, reservationQuota, paymentGateway, reservationPolicy,
+		time.Duration(cfg.PaymentWindowSec)*time.Second)
+
+	// Accounts from before email verification existed count as verified
+	if migrated, err := userRepo.MigrateEmailVerified(ctx); err != nil {
+		logger.Error("Failed to migrate user email verification", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else if migrated > 0 {
+		logger.Info("Marked existing users verified", map[string]interface{}{
+			"count": migrated,
+		})
+	}
+
+	// Make the configured accounts admins so roles can be assigned through the API
+	if promoted, err := userService.GrantAdmin(ctx, cfg.AdminEmails); err != nil {
+		logger.Error("Failed to grant admin role", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else if promoted > 0 {
+		logger.Info("Granted admin role", map[string]interface{}{
+			"count": promoted,
+		})
+	}
 
-	// Seed lottery tickets if they don't exist
+	// Drop the unique number index from before draw rounds, so every round can stock every number
+	indexCtx, cancelIndex := context.WithTimeout(context.Background(), 30*time.Second)
+	dropped, err := lotteryRepo.MigrateLegacyIndexes(indexCtx)
+	cancelIndex()
+	if err != nil {
+		logger.Error("Failed to migrate lottery indexes", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else if dropped {
+		logger.Info("Dropped legacy lottery number index")
+	}
+
+	// Backfill digit and set fields on tickets created before they existed
 	go func() {
-		seedTimeoutCtx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+		migrateTimeoutCtx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
 		defer cancel()
-		if err := lotteryRepo.SeedTickets(seedTimeoutCtx, 1000000); err != nil {
-			logger.Error("Failed to seed lottery tickets", map[string]interface{}{
+		migrated, err := lotteryRepo.MigrateDigitFields(migrateTimeoutCtx)
+		if err != nil {
+			logger.Error("Failed to migrate lottery digit fields", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if migrated > 0 {
+			logger.Info("Migrated lottery digit fields", map[string]interface{}{
+				"count": migrated,
+			})
+		}
+		migrated, err = lotteryRepo.MigrateTicketSets(migrateTimeoutCtx)
+		if err != nil {
+			logger.Error("Failed to migrate lottery ticket sets", map[string]interface{}{
 				"error": err.Error(),
 			})
+		} else if migrated > 0 {
+			logger.Info("Migrated lottery ticket sets", map[string]interface{}{
+				"count": migrated,
+			})
 		}
 	}()
 
 	// ข้อ 6. Concurrency Task
 	// Run a background goroutine every 10 seconds to log the total number of users in the database.
 	go logUserCountPeriodically(ctx, userService)
-	router := httpHandler.SetupRouter(userService, authService, lotteryService)
+	// Return expired lottery reservations to the available pool every minute
+	go reapExpiredReservationsPeriodically(ctx, lotteryService)
+	// Fail orders whose payment never arrived and put their tickets back on sale
+	go expirePendingOrdersPeriodically(ctx, orderService)
+	// Keep the next lottery round scheduled, and stocked unless inventories are seeded with cmd/seed
+	go scheduleDrawsPeriodically(ctx, drawService, cfg.LotteryAutoStock)
+	// Refill Redis lottery pattern pools queued by searches
+	prefillDone := make(chan struct{})
+	go func() {
+		defer close(prefillDone)
+		lotteryRepo.RunPrefillWorkers(ctx, cfg.LotteryPrefillWorkers)
+		logger.Info("Stopped lottery pool prefill workers")
+	}()
+	// Fan ticket events from Redis out to availability streams. Stopping it on shutdown closes
+	// every open stream, so the server does not wait on them until the shutdown timeout.
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		lotteryRepo.RunEventHub(ctx)
+		logger.Info("Stopped lottery event hub")
+	}()
+	idempotencyStore := redis.NewIdempotencyStore(rdb)
+	router := httpHandler.SetupRouter(userService, authService, lotteryService, drawService, orderService,
+		idempotencyStore, time.Duration(cfg.IdempotencyTTLSec)*time.Second, cfg.LotteryRequireVerifiedEmail)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
@@ -119,6 +219,8 @@ func main() {
 			"error": err.Error(),
 		})
 	}
+	<-prefillDone
+	<-eventsDone
 
 	logger.Info("Server stopped")
 }
@@ -157,6 +259,14 @@ func connectRedis(cfg *config.Config) *redisClient.Client {
 	return rdb
 }
 
+// newNotifier delivers notifications to NOTIFIER_FILE when it is set, otherwise to the log
+func newNotifier(cfg *config.Config) ports.Notifier {
+	if cfg.NotifierFile != "" {
+		return notifier.NewFileNotifier(cfg.NotifierFile)
+	}
+	return notifier.NewLogNotifier()
+}
+
 func logUserCountPeriodically(ctx context.Context, userService *application.UserService) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
@@ -180,3 +290,99 @@ func logUserCountPeriodically(ctx context.Context, userService *application.User
 		}
 	}
 }
+
+func reapExpiredReservationsPeriodically(ctx context.Context, lotteryService *application.LotteryService) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping expired reservation reaper goroutine")
+			return
+		case <-ticker.C:
+			reclaimed, err := lotteryService.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				logger.Error("Failed to release expired reservations", map[string]interface{}{
+					"error":     err.Error(),
+					"reclaimed": reclaimed,
+				})
+				continue
+			}
+			logger.Info("Released expired lottery reservations", map[string]interface{}{
+				"reclaimed": reclaimed,
+			})
+		}
+	}
+}
+
+func expirePendingOrdersPeriodically(ctx context.Context, orderService *application.OrderService) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping pending order expiry goroutine")
+			return
+		case <-ticker.C:
+			expired, err := orderService.ExpirePendingOrders(ctx)
+			if err != nil {
+				logger.Error("Failed to expire pending orders", map[string]interface{}{
+					"error":   err.Error(),
+					"expired": expired,
+				})
+				continue
+			}
+			if expired > 0 {
+				logger.Info("Expired unpaid orders", map[string]interface{}{
+					"expired": expired,
+				})
+			}
+		}
+	}
+}
+
+func scheduleDrawsPeriodically(ctx context.Context, drawService *application.LotteryDrawService, autoStock bool) {
+	if !autoStock {
+		logger.Info("Automatic lottery stocking is disabled, seed draw inventories with cmd/seed")
+	}
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		draw, err := drawService.EnsureNextDraw(ctx)
+		if err != nil {
+			logger.Error("Failed to schedule next lottery draw", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			logger.Debug("Next lottery draw scheduled", map[string]interface{}{
+				"draw_id":   draw.ID,
+				"draw_date": draw.DrawDate.Format(time.RFC3339),
+			})
+		}
+
+		if autoStock {
+			stocked, err := drawService.StockInventories(ctx)
+			if err != nil {
+				logger.Error("Failed to stock lottery draw inventories", map[string]interface{}{
+					"error":   err.Error(),
+					"stocked": stocked,
+				})
+			} else if stocked > 0 {
+				logger.Info("Stocked lottery draw inventories", map[string]interface{}{
+					"stocked": stocked,
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping lottery draw scheduler goroutine")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/config"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	redisClient "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Seed stocks a lottery round with tickets outside the API process. Progress is recorded
+// after every batch, so running the same command again after it was stopped resumes it.
+//
+//	go run ./cmd/seed -draw 2026-11-01 -from 0 -to 1000000 -batch 10000
+func main() {
+	drawID := flag.String("draw", "", "draw round to stock (default: the next round, scheduled if needed)")
+	from := flag.Int("from", 0, "first number to stock")
+	to := flag.Int("to", 0, "stock numbers below this one (default: the round's ticket count)")
+	batch := flag.Int("batch", domain.DefaultSeedBatchSize, "tickets written per batch")
+	restart := flag.Bool("restart", false, "ignore recorded progress and seed the range again")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize logger
+	logger.Init(cfg.LogLevel)
+
+	// Stop between batches on interrupt, the next run resumes from the last recorded batch
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	mongoClient, err := connectMongoDB(ctx, cfg.MongoDBURI)
+	if err != nil {
+		log.Fatalf("MongoDB connection failed: %v", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	rdb := redisClient.NewClient(&redisClient.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort),
+		Password: cfg.RedisPassword,
+		DB:       0,
+	})
+	defer rdb.Close()
+
+	db := mongoClient.Database("userdb")
+	lotteryRepo := mongodb.NewLotteryRepository(db, rdb)
+	drawRepo := mongodb.NewLotteryDrawRepository(db)
+	drawService := application.NewLotteryDrawService(drawRepo, lotteryRepo, domain.DrawTemplate{
+		TicketCount:    cfg.LotteryDrawTicketCount,
+		SetsPerNumber:  cfg.LotteryDrawSetsPerNumber,
+		TicketPrice:    cfg.LotteryTicketPrice,
+		SalesOpenLead:  time.Duration(cfg.LotterySalesOpenLeadSec) * time.Second,
+		SalesCloseLead: time.Duration(cfg.LotterySalesCloseLeadSec) * time.Second,
+	})
+
+	// A database the API has not migrated yet still has the unique number index of a single round
+	if _, err := lotteryRepo.MigrateLegacyIndexes(ctx); err != nil {
+		logger.Error("Failed to migrate lottery indexes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	draw, err := drawService.SeedDraw(ctx, domain.TicketSeedPlan{
+		DrawID:    *drawID,
+		From:      *from,
+		To:        *to,
+		BatchSize: *batch,
+		Restart:   *restart,
+	})
+	if err != nil {
+		logger.Error("Failed to seed lottery tickets", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	logger.Info("Lottery seed finished", map[string]interface{}{
+		"draw_id": draw.ID,
+		"stocked": draw.StockedAt != nil,
+	})
+}
+
+func connectMongoDB(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -9,10 +9,27 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Device optionally names the client in the session list, e.g. "Somchai's iPhone"
+	Device string `json:"device"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refreshToken"`
+	Device       string `json:"device"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
 }
 
 type LoginResponse struct {
@@ -20,11 +37,24 @@ type LoginResponse struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+type SessionResponse struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	CreatedAt  string `json:"createdAt,omitempty"`
+	LastSeenAt string `json:"lastSeenAt,omitempty"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
 type UserResponse struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"createdAt"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	Roles         []string `json:"roles"`
+	EmailVerified bool     `json:"emailVerified"`
+	CreatedAt     string   `json:"createdAt"`
 }
 
 type CreateUserRequest struct {
@@ -38,7 +68,199 @@ type UpdateUserRequest struct {
 	Email string `json:"email"`
 }
 
+// PatchUserRequest changes only the fields that are present
+type PatchUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+type PurchaseLotteriesRequest struct {
+	TicketIDs []string `json:"ticketIds"`
+}
+
+type LotteryTicketResponse struct {
+	ID             string `json:"id"`
+	Number         string `json:"number"`
+	Set            int    `json:"set,omitempty"`
+	Status         string `json:"status"`
+	ReservedUntil  string `json:"reservedUntil,omitempty"`
+	HoldExtensions int    `json:"holdExtensions,omitempty"`
+	DrawID         string `json:"drawId,omitempty"`
+	UpdatedAt      string `json:"updatedAt"`
+}
+
+// LotteryEventResponse is one server-sent event of the availability stream
+type LotteryEventResponse struct {
+	Type          string `json:"type"`
+	TicketID      string `json:"ticketId"`
+	Number        string `json:"number"`
+	Set           int    `json:"set,omitempty"`
+	DrawID        string `json:"drawId"`
+	ReservedUntil string `json:"reservedUntil,omitempty"`
+	At            string `json:"at"`
+}
+
+type LotteryStatsResponse struct {
+	DrawID              string                    `json:"drawId,omitempty"`
+	Total               int64                     `json:"total"`
+	ByStatus            map[string]int64          `json:"byStatus"`
+	ActiveReservations  int64                     `json:"activeReservations"`
+	ExpiredReservations int64                     `json:"expiredReservations"`
+	SalesSince          string                    `json:"salesSince"`
+	SalesPerHour        []HourlySalesResponse     `json:"salesPerHour"`
+	HotPatterns         []PatternSearchesResponse `json:"hotPatterns"`
+	Pool                PoolStatsResponse         `json:"pool"`
+	GeneratedAt         string                    `json:"generatedAt"`
+}
+
+type HourlySalesResponse struct {
+	Hour  string `json:"hour"`
+	Count int64  `json:"count"`
+}
+
+type PatternSearchesResponse struct {
+	Pattern  string `json:"pattern"`
+	Searches int64  `json:"searches"`
+}
+
+type PoolStatsResponse struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// LotteryHistoryEntryResponse is one change in a ticket's audit trail. Actor is only set for
+// the caller's own changes and for the system.
+type LotteryHistoryEntryResponse struct {
+	Type          string `json:"type"`
+	Actor         string `json:"actor,omitempty"`
+	FromStatus    string `json:"fromStatus"`
+	ToStatus      string `json:"toStatus"`
+	Number        string `json:"number"`
+	Set           int    `json:"set,omitempty"`
+	DrawID        string `json:"drawId,omitempty"`
+	ReservedUntil string `json:"reservedUntil,omitempty"`
+	At            string `json:"at"`
+}
+
+type AddToCartRequest struct {
+	Number string `json:"number"`
+	// Copies is how many copies (sets) of the number to hold; omitted means one
+	Copies int `json:"copies,omitempty"`
+}
+
+type CartResponse struct {
+	Tickets          []LotteryTicketResponse `json:"tickets"`
+	Count            int                     `json:"count"`
+	MaxTickets       int                     `json:"maxTickets"`
+	ExpiresAt        string                  `json:"expiresAt,omitempty"`
+	RemainingSeconds int64                   `json:"remainingSeconds"`
+	CanExtend        bool                    `json:"canExtend"`
+}
+
+type ReserveLotteryRequest struct {
+	Pattern string `json:"pattern"`
+}
+
+type LotteryPageResponse struct {
+	Pattern    string                  `json:"pattern"`
+	Results    []LotteryTicketResponse `json:"results"`
+	Count      int                     `json:"count"`
+	Total      int64                   `json:"total"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+type DrawTemplateRequest struct {
+	TicketCount       int   `json:"ticketCount"`
+	SetsPerNumber     int   `json:"setsPerNumber"`
+	TicketPrice       int64 `json:"ticketPrice"`
+	SalesOpenLeadSec  int64 `json:"salesOpenLeadSec"`
+	SalesCloseLeadSec int64 `json:"salesCloseLeadSec"`
+}
+
+type CreateDrawRequest struct {
+	DrawDate string               `json:"drawDate"`
+	Template *DrawTemplateRequest `json:"template,omitempty"`
+}
+
+type CreateNextDrawRequest struct {
+	Template *DrawTemplateRequest `json:"template,omitempty"`
+}
+
+type UpdateSalesWindowRequest struct {
+	SalesOpenAt  string `json:"salesOpenAt"`
+	SalesCloseAt string `json:"salesCloseAt"`
+}
+
+type RecordDrawResultsRequest struct {
+	Prizes map[string][]string `json:"prizes"`
+}
+
+type LotteryPrizeResponse struct {
+	Tier    string   `json:"tier"`
+	Numbers []string `json:"numbers"`
+	Amount  int64    `json:"amount"`
+}
+
+type LotteryDrawResponse struct {
+	ID            string                 `json:"id"`
+	DrawDate      string                 `json:"drawDate"`
+	Status        string                 `json:"status"`
+	SalesOpenAt   string                 `json:"salesOpenAt"`
+	SalesCloseAt  string                 `json:"salesCloseAt"`
+	OnSale        bool                   `json:"onSale"`
+	TicketCount   int                    `json:"ticketCount"`
+	SetsPerNumber int                    `json:"setsPerNumber"`
+	TicketPrice   int64                  `json:"ticketPrice"`
+	StockedAt     string                 `json:"stockedAt,omitempty"`
+	Prizes        []LotteryPrizeResponse `json:"prizes,omitempty"`
+	DrawnAt       string                 `json:"drawnAt,omitempty"`
+}
+
+type TicketCheckResponse struct {
+	Ticket   LotteryTicketResponse  `json:"ticket"`
+	Prizes   []LotteryPrizeResponse `json:"prizes"`
+	Winnings int64                  `json:"winnings"`
+}
+
+type DrawCheckResponse struct {
+	Draw          LotteryDrawResponse   `json:"draw"`
+	Tickets       []TicketCheckResponse `json:"tickets"`
+	Count         int                   `json:"count"`
+	WinningCount  int                   `json:"winningCount"`
+	TotalWinnings int64                 `json:"totalWinnings"`
+}
+
+type OrderItemResponse struct {
+	TicketID string `json:"ticketId"`
+	Number   string `json:"number"`
+	Set      int    `json:"set,omitempty"`
+	Price    int64  `json:"price"`
+	Refunded bool   `json:"refunded,omitempty"`
+}
+
+type OrderResponse struct {
+	ID             string              `json:"id"`
+	Status         string              `json:"status"`
+	DrawID         string              `json:"drawId"`
+	Items          []OrderItemResponse `json:"items"`
+	Count          int                 `json:"count"`
+	Total          int64               `json:"total"`
+	RefundedAmount int64               `json:"refundedAmount,omitempty"`
+	Currency       string              `json:"currency"`
+	PaymentID      string              `json:"paymentId,omitempty"`
+	CheckoutURL    string              `json:"checkoutUrl,omitempty"`
+	FailureReason  string              `json:"failureReason,omitempty"`
+	ExpiresAt      string              `json:"expiresAt"`
+	PaidAt         string              `json:"paidAt,omitempty"`
+	CreatedAt      string              `json:"createdAt"`
+}
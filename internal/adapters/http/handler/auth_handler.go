@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/backend-challenge/user-api/internal/adapters/http/dto"
+	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
 	"github.com/gin-gonic/gin"
 )
@@ -34,12 +36,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, &dto.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusCreated, toUserResponse(user))
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -52,7 +49,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
+	accessToken, refreshToken, err := h.authService.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c, req.Device))
 	if err != nil {
 		c.Error(err)
 		return
@@ -88,6 +85,49 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// LogoutAll signs the caller out of every session, including the current one
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	revoked, err := h.authService.RevokeAllSessions(c.Request.Context(), c.GetString("user_id"), "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out of all sessions",
+		"revoked": revoked,
+	})
+}
+
+// ListSessions lists where the caller is signed in
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.SessionResponse, len(sessions))
+	for i := range sessions {
+		response[i] = toSessionResponse(&sessions[i])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": response,
+	})
+}
+
+// RevokeSession signs the caller out of one of their sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	if err := h.authService.RevokeSession(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req dto.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -98,7 +138,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	accessToken, refreshToken, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c, req.Device))
 	if err != nil {
 		c.Error(err)
 		return
@@ -109,3 +149,123 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		RefreshToken: refreshToken,
 	})
 }
+
+// ChangePassword replaces the caller's password and signs out their other sessions
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.ChangePassword(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"),
+		req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+	})
+}
+
+// ForgotPassword sends a password reset link. The response is the same whether or not the
+// email belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email belongs to an account, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully",
+	})
+}
+
+// VerifyEmail confirms the email of an account with the token from its verification link
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	if err := h.authService.VerifyEmail(c.Request.Context(), c.Query("token")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerification sends the caller a new email verification link
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	if err := h.authService.ResendVerification(c.Request.Context(), c.GetString("user_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// clientInfo describes the client of a login or refresh request
+func clientInfo(c *gin.Context, device string) domain.ClientInfo {
+	return domain.ClientInfo{
+		Device:    device,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func toSessionResponse(session *domain.LoginSession) dto.SessionResponse {
+	return dto.SessionResponse{
+		ID:         session.ID,
+		Device:     session.Device,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  formatSessionTime(session.CreatedAt),
+		LastSeenAt: formatSessionTime(session.LastSeenAt),
+		ExpiresAt:  formatSessionTime(session.ExpiresAt),
+		Current:    session.Current,
+	}
+}
+
+// formatSessionTime leaves out the times a session created before they were recorded does not have
+func formatSessionTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
+}
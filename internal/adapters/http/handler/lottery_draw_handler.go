@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/http/dto"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/gin-gonic/gin"
+)
+
+const drawDateLayout = "2006-01-02"
+
+type LotteryDrawHandler struct {
+	service ports.LotteryDrawService
+}
+
+func NewLotteryDrawHandler(service ports.LotteryDrawService) *LotteryDrawHandler {
+	return &LotteryDrawHandler{
+		service: service,
+	}
+}
+
+func (h *LotteryDrawHandler) CreateDraw(c *gin.Context) {
+	var req dto.CreateDrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	drawDate, err := time.ParseInLocation(drawDateLayout, req.DrawDate, domain.DrawLocation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "drawDate must be formatted as YYYY-MM-DD",
+		})
+		return
+	}
+
+	draw, err := h.service.CreateDraw(c.Request.Context(), drawDate, toDrawTemplate(req.Template))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toLotteryDrawResponse(draw))
+}
+
+// CreateNextDraw schedules the round after the latest one, copying its settings by default.
+func (h *LotteryDrawHandler) CreateNextDraw(c *gin.Context) {
+	var req dto.CreateNextDrawRequest
+	// Body เป็น Optional ใช้การตั้งค่าของงวดล่าสุดเมื่อไม่ได้ส่งมา
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	draw, err := h.service.CreateNextDraw(c.Request.Context(), toDrawTemplate(req.Template))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toLotteryDrawResponse(draw))
+}
+
+func (h *LotteryDrawHandler) UpdateSalesWindow(c *gin.Context) {
+	var req dto.UpdateSalesWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	openAt, openErr := time.Parse(time.RFC3339, req.SalesOpenAt)
+	closeAt, closeErr := time.Parse(time.RFC3339, req.SalesCloseAt)
+	if openErr != nil || closeErr != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "salesOpenAt and salesCloseAt must be RFC3339 timestamps",
+		})
+		return
+	}
+
+	draw, err := h.service.UpdateSalesWindow(c.Request.Context(), c.Param("id"), openAt, closeAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLotteryDrawResponse(draw))
+}
+
+func (h *LotteryDrawHandler) ListDraws(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "limit must be a number",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	draws, err := h.service.ListDraws(c.Request.Context(), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.LotteryDrawResponse, len(draws))
+	for i, d := range draws {
+		response[i] = toLotteryDrawResponse(d)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": response,
+		"count":   len(response),
+	})
+}
+
+func (h *LotteryDrawHandler) GetDraw(c *gin.Context) {
+	draw, err := h.service.GetDraw(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLotteryDrawResponse(draw))
+}
+
+func (h *LotteryDrawHandler) RecordResults(c *gin.Context) {
+	var req dto.RecordDrawResultsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	results := make(map[domain.PrizeTier][]string, len(req.Prizes))
+	for tier, numbers := range req.Prizes {
+		results[domain.PrizeTier(tier)] = numbers
+	}
+
+	draw, err := h.service.RecordResults(c.Request.Context(), c.Param("id"), results)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLotteryDrawResponse(draw))
+}
+
+func (h *LotteryDrawHandler) CheckTickets(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.service.CheckTickets(c.Request.Context(), c.Param("id"), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	tickets := make([]dto.TicketCheckResponse, len(result.Tickets))
+	winning := 0
+	for i, t := range result.Tickets {
+		tickets[i] = dto.TicketCheckResponse{
+			Ticket:   toLotteryTicketResponse(t.Ticket),
+			Prizes:   toLotteryPrizeResponses(t.Prizes),
+			Winnings: t.Winnings(),
+		}
+		if len(t.Prizes) > 0 {
+			winning++
+		}
+	}
+
+	c.JSON(http.StatusOK, &dto.DrawCheckResponse{
+		Draw:          toLotteryDrawResponse(result.Draw),
+		Tickets:       tickets,
+		Count:         len(tickets),
+		WinningCount:  winning,
+		TotalWinnings: result.TotalWinnings,
+	})
+}
+
+func toDrawTemplate(req *dto.DrawTemplateRequest) *domain.DrawTemplate {
+	if req == nil {
+		return nil
+	}
+	sets := req.SetsPerNumber
+	if sets == 0 {
+		// ไม่ระบุจำนวนชุด ให้มีเลขละหนึ่งใบ
+		sets = 1
+	}
+	price := req.TicketPrice
+	if price == 0 {
+		// ไม่ระบุราคา ใช้ราคามาตรฐานของสลากกินแบ่ง
+		price = domain.DefaultTicketPrice
+	}
+	return &domain.DrawTemplate{
+		TicketCount:    req.TicketCount,
+		SetsPerNumber:  sets,
+		TicketPrice:    price,
+		SalesOpenLead:  time.Duration(req.SalesOpenLeadSec) * time.Second,
+		SalesCloseLead: time.Duration(req.SalesCloseLeadSec) * time.Second,
+	}
+}
+
+func toLotteryDrawResponse(d *domain.LotteryDraw) dto.LotteryDrawResponse {
+	resp := dto.LotteryDrawResponse{
+		ID:            d.ID,
+		DrawDate:      d.DrawDate.Format(drawDateLayout),
+		Status:        string(d.Status),
+		SalesOpenAt:   d.SalesOpenAt.Format(time.RFC3339),
+		SalesCloseAt:  d.SalesCloseAt.Format(time.RFC3339),
+		OnSale:        d.IsOnSale(time.Now()),
+		TicketCount:   d.TicketCount,
+		SetsPerNumber: d.SetsPerNumber,
+		TicketPrice:   d.TicketPrice,
+		Prizes:        toLotteryPrizeResponses(d.Prizes),
+	}
+	if d.StockedAt != nil {
+		resp.StockedAt = d.StockedAt.Format(time.RFC3339)
+	}
+	if d.DrawnAt != nil {
+		resp.DrawnAt = d.DrawnAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toLotteryPrizeResponses(prizes []domain.LotteryPrize) []dto.LotteryPrizeResponse {
+	response := make([]dto.LotteryPrizeResponse, len(prizes))
+	for i, p := range prizes {
+		response[i] = dto.LotteryPrizeResponse{
+			Tier:    string(p.Tier),
+			Numbers: p.Numbers,
+			Amount:  p.Amount,
+		}
+	}
+	return response
+}
@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/backend-challenge/user-api/internal/adapters/http/dto"
+	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
 	"github.com/gin-gonic/gin"
 )
 
+// watchHeartbeatInterval keeps idle availability streams open through proxies
+const watchHeartbeatInterval = 15 * time.Second
+
 type LotteryHandler struct {
 	service ports.LotteryService
 }
@@ -32,7 +40,7 @@ func (h *LotteryHandler) Search(c *gin.Context) {
 
 	tickets, err := h.service.SearchLottery(c.Request.Context(), pattern, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -42,3 +50,400 @@ func (h *LotteryHandler) Search(c *gin.Context) {
 		"count":   len(tickets),
 	})
 }
+
+// Watch streams reserve, release and sell events of tickets matching pattern as server-sent
+// events, so buyers learn a ticket is back on sale without searching (and reserving) again.
+// The stream ends when the client disconnects or the server shuts down.
+func (h *LotteryHandler) Watch(c *gin.Context) {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pattern is required"})
+		return
+	}
+
+	events, err := h.service.WatchLottery(c.Request.Context(), pattern)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// ปิด Buffer ของ Reverse Proxy เพื่อให้ Event ถึงผู้ใช้ทันที
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), toLotteryEventResponse(event))
+			return true
+		case <-heartbeat.C:
+			// Comment line ตาม SSE ไม่ถูกส่งถึง Event Listener ของผู้ใช้ แต่ช่วยไม่ให้ Proxy ตัดการเชื่อมต่อ
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// History returns a ticket's audit trail so disputes over who reserved or bought it can be settled
+func (h *LotteryHandler) History(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ticketID := c.Param("id")
+	entries, err := h.service.GetTicketHistory(c.Request.Context(), ticketID, userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.LotteryHistoryEntryResponse, len(entries))
+	for i, e := range entries {
+		response[i] = toLotteryHistoryEntryResponse(e)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticketId": ticketID,
+		"results":  response,
+		"count":    len(response),
+	})
+}
+
+// Stats reports inventory statistics for operations. drawId limits the counts to one draw round.
+func (h *LotteryHandler) Stats(c *gin.Context) {
+	stats, err := h.service.GetLotteryStats(c.Request.Context(), c.Query("drawId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLotteryStatsResponse(stats))
+}
+
+// Reserve is the explicit search-and-reserve action; Search is kept for existing clients.
+func (h *LotteryHandler) Reserve(c *gin.Context) {
+	var req dto.ReserveLotteryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tickets, err := h.service.SearchLottery(c.Request.Context(), req.Pattern, userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.LotteryTicketResponse, len(tickets))
+	for i, t := range tickets {
+		response[i] = toLotteryTicketResponse(t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pattern": req.Pattern,
+		"results": response,
+		"count":   len(response),
+	})
+}
+
+func (h *LotteryHandler) Browse(c *gin.Context) {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pattern is required"})
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "limit must be a number",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.service.BrowseLottery(c.Request.Context(), pattern, c.Query("cursor"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	results := make([]dto.LotteryTicketResponse, len(page.Tickets))
+	for i, t := range page.Tickets {
+		results[i] = toLotteryTicketResponse(t)
+	}
+
+	c.JSON(http.StatusOK, &dto.LotteryPageResponse{
+		Pattern:    pattern,
+		Results:    results,
+		Count:      len(results),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	})
+}
+
+func (h *LotteryHandler) ListReservations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tickets, err := h.service.ListReservations(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.LotteryTicketResponse, len(tickets))
+	for i, t := range tickets {
+		response[i] = toLotteryTicketResponse(t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": response,
+		"count":   len(response),
+	})
+}
+
+func (h *LotteryHandler) ReleaseReservation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.service.ReleaseReservation(c.Request.Context(), c.Param("id"), userID.(string)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reservation released successfully",
+	})
+}
+
+func (h *LotteryHandler) ReleaseAllReservations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	released, err := h.service.ReleaseAllReservations(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Reservations released successfully",
+		"released": released,
+	})
+}
+
+func (h *LotteryHandler) GetCart(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	cart, err := h.service.GetCart(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toCartResponse(cart))
+}
+
+func (h *LotteryHandler) AddToCart(c *gin.Context) {
+	var req dto.AddToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tickets, err := h.service.AddToCart(c.Request.Context(), req.Number, req.Copies, userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Clients that do not ask for copies still get the single ticket they always did
+	if req.Copies == 0 {
+		c.JSON(http.StatusCreated, toLotteryTicketResponse(tickets[0]))
+		return
+	}
+
+	response := make([]dto.LotteryTicketResponse, len(tickets))
+	for i, t := range tickets {
+		response[i] = toLotteryTicketResponse(t)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"number":  req.Number,
+		"results": response,
+		"count":   len(response),
+	})
+}
+
+func (h *LotteryHandler) ExtendHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	cart, err := h.service.ExtendHold(c.Request.Context(), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toCartResponse(cart))
+}
+
+func toCartResponse(cart *domain.Cart) *dto.CartResponse {
+	tickets := make([]dto.LotteryTicketResponse, len(cart.Tickets))
+	for i, t := range cart.Tickets {
+		tickets[i] = toLotteryTicketResponse(t)
+	}
+
+	resp := &dto.CartResponse{
+		Tickets:    tickets,
+		Count:      len(tickets),
+		MaxTickets: cart.MaxTickets,
+		CanExtend:  cart.CanExtend,
+	}
+	if cart.ExpiresAt != nil {
+		resp.ExpiresAt = cart.ExpiresAt.Format(time.RFC3339)
+		if remaining := time.Until(*cart.ExpiresAt); remaining > 0 {
+			resp.RemainingSeconds = int64(remaining.Seconds())
+		}
+	}
+	return resp
+}
+
+func toLotteryTicketResponse(t domain.LotteryTicket) dto.LotteryTicketResponse {
+	resp := dto.LotteryTicketResponse{
+		ID:             t.ID,
+		Number:         t.Number,
+		Set:            t.Set,
+		Status:         string(t.Status),
+		HoldExtensions: t.HoldExtensions,
+		DrawID:         t.DrawID,
+		UpdatedAt:      t.UpdatedAt.Format(time.RFC3339),
+	}
+	if t.ReservedUntil != nil {
+		resp.ReservedUntil = t.ReservedUntil.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toLotteryEventResponse(e domain.LotteryEvent) dto.LotteryEventResponse {
+	resp := dto.LotteryEventResponse{
+		Type:     string(e.Type),
+		TicketID: e.TicketID,
+		Number:   e.Number,
+		Set:      e.Set,
+		DrawID:   e.DrawID,
+		At:       e.At.Format(time.RFC3339),
+	}
+	if e.ReservedUntil != nil {
+		resp.ReservedUntil = e.ReservedUntil.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toLotteryStatsResponse(s *domain.LotteryStats) dto.LotteryStatsResponse {
+	// ใส่ทุกสถานะเสมอ แม้ไม่มีตั๋วในสถานะนั้น
+	byStatus := map[string]int64{
+		string(domain.LotteryStatusAvailable): 0,
+		string(domain.LotteryStatusReserved):  0,
+		string(domain.LotteryStatusSold):      0,
+	}
+	for status, count := range s.ByStatus {
+		byStatus[string(status)] = count
+	}
+
+	sales := make([]dto.HourlySalesResponse, len(s.SalesPerHour))
+	for i, h := range s.SalesPerHour {
+		sales[i] = dto.HourlySalesResponse{Hour: h.Hour.Format(time.RFC3339), Count: h.Count}
+	}
+	patterns := make([]dto.PatternSearchesResponse, len(s.HotPatterns))
+	for i, p := range s.HotPatterns {
+		patterns[i] = dto.PatternSearchesResponse{Pattern: p.Pattern, Searches: p.Searches}
+	}
+
+	return dto.LotteryStatsResponse{
+		DrawID:              s.DrawID,
+		Total:               s.Total,
+		ByStatus:            byStatus,
+		ActiveReservations:  s.ActiveReservations,
+		ExpiredReservations: s.ExpiredReservations,
+		SalesSince:          s.SalesSince.Format(time.RFC3339),
+		SalesPerHour:        sales,
+		HotPatterns:         patterns,
+		Pool: dto.PoolStatsResponse{
+			Hits:    s.Pool.Hits,
+			Misses:  s.Pool.Misses,
+			HitRate: s.Pool.HitRate(),
+		},
+		GeneratedAt: s.GeneratedAt.Format(time.RFC3339),
+	}
+}
+
+func toLotteryHistoryEntryResponse(e domain.LotteryHistoryEntry) dto.LotteryHistoryEntryResponse {
+	resp := dto.LotteryHistoryEntryResponse{
+		Type:       string(e.Type),
+		Actor:      e.Actor,
+		FromStatus: string(e.FromStatus),
+		ToStatus:   string(e.ToStatus),
+		Number:     e.Number,
+		Set:        e.Set,
+		DrawID:     e.DrawID,
+		At:         e.At.Format(time.RFC3339),
+	}
+	if e.ReservedUntil != nil {
+		resp.ReservedUntil = e.ReservedUntil.Format(time.RFC3339)
+	}
+	return resp
+}
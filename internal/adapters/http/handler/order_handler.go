@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/http/dto"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/gin-gonic/gin"
+)
+
+// paymentSignatureHeader carries the gateway's signature of a callback body
+const paymentSignatureHeader = "X-Payment-Signature"
+
+type OrderHandler struct {
+	service ports.OrderService
+}
+
+func NewOrderHandler(service ports.OrderService) *OrderHandler {
+	return &OrderHandler{
+		service: service,
+	}
+}
+
+// Checkout places an order for held tickets. The tickets are sold once the payment is confirmed.
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	var req dto.PurchaseLotteriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.checkout(c, req.TicketIDs)
+}
+
+// CheckoutTicket places an order for a single held ticket
+func (h *OrderHandler) CheckoutTicket(c *gin.Context) {
+	h.checkout(c, []string{c.Param("id")})
+}
+
+func (h *OrderHandler) checkout(c *gin.Context, ticketIDs []string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	order, err := h.service.Checkout(c.Request.Context(), ticketIDs, userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toOrderResponse(order))
+}
+
+// CheckoutSet reserves every copy (the whole set) of a number and places an order for it.
+func (h *OrderHandler) CheckoutSet(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	order, err := h.service.CheckoutSet(c.Request.Context(), c.Param("number"), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toOrderResponse(order))
+}
+
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "limit must be a number",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	orders, err := h.service.ListOrders(c.Request.Context(), userID.(string), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := make([]dto.OrderResponse, len(orders))
+	for i, o := range orders {
+		response[i] = toOrderResponse(o)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": response,
+		"count":   len(response),
+	})
+}
+
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	order, err := h.service.GetOrder(c.Request.Context(), c.Param("id"), userID.(string))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toOrderResponse(order))
+}
+
+// PaymentWebhook receives the payment gateway's callbacks. It is not behind the auth middleware;
+// the gateway signs every body instead.
+func (h *OrderHandler) PaymentWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	order, err := h.service.HandlePaymentEvent(c.Request.Context(), payload, c.GetHeader(paymentSignatureHeader))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orderId": order.ID,
+		"status":  string(order.Status),
+	})
+}
+
+func toOrderResponse(o *domain.Order) dto.OrderResponse {
+	items := make([]dto.OrderItemResponse, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = dto.OrderItemResponse{
+			TicketID: item.TicketID,
+			Number:   item.Number,
+			Set:      item.Set,
+			Price:    item.Price,
+			Refunded: item.Refunded,
+		}
+	}
+
+	resp := dto.OrderResponse{
+		ID:             o.ID,
+		Status:         string(o.Status),
+		DrawID:         o.DrawID,
+		Items:          items,
+		Count:          len(items),
+		Total:          o.Total,
+		RefundedAmount: o.RefundedAmount,
+		Currency:       o.Currency,
+		PaymentID:      o.PaymentID,
+		CheckoutURL:    o.CheckoutURL,
+		FailureReason:  o.FailureReason,
+		ExpiresAt:      o.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:      o.CreatedAt.Format(time.RFC3339),
+	}
+	if o.PaidAt != nil {
+		resp.PaidAt = o.PaidAt.Format(time.RFC3339)
+	}
+	return resp
+}
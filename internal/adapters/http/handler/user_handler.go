@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/backend-challenge/user-api/internal/adapters/http/dto"
+	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
 	"github.com/gin-gonic/gin"
 )
@@ -34,12 +35,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, &dto.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusCreated, toUserResponse(user))
 }
 
 func (h *UserHandler) GetUser(c *gin.Context) {
@@ -51,12 +47,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, &dto.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	})
+	c.JSON(http.StatusOK, toUserResponse(user))
 }
 
 func (h *UserHandler) ListUsers(c *gin.Context) {
@@ -68,12 +59,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	response := make([]*dto.UserResponse, len(users))
 	for i, user := range users {
-		response[i] = &dto.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		}
+		response[i] = toUserResponse(user)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -97,14 +83,73 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, &dto.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// GetMe returns the caller's own account
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, err := h.userService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// PatchMe updates the name and/or email of the caller's own account
+func (h *UserHandler) PatchMe(c *gin.Context) {
+	var req dto.PatchUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.PatchUser(c.Request.Context(), c.GetString("user_id"), req.Name, req.Email)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// UpdateUserRoles replaces the roles of a user. Only admins may call it.
+func (h *UserHandler) UpdateUserRoles(c *gin.Context) {
+	var req dto.UpdateUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.UpdateUserRoles(c.Request.Context(), c.Param("id"), req.Roles)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// DeleteMe deletes the caller's own account and logs it out everywhere
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	if err := h.userService.DeleteUser(c.Request.Context(), c.GetString("user_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account deleted successfully",
 	})
 }
 
+// DeleteUser deletes an account and logs it out everywhere
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 
@@ -117,3 +162,18 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		"message": "User deleted successfully",
 	})
 }
+
+func toUserResponse(user *domain.User) *dto.UserResponse {
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = string(role)
+	}
+	return &dto.UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Roles:         roles,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
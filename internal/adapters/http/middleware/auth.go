@@ -34,7 +34,7 @@ func AuthMiddleware(authService ports.AuthService) gin.HandlerFunc {
 
 		token := parts[1]
 
-		_, userID, err := authService.ValidateToken(c.Request.Context(), token)
+		claims, userID, err := authService.ValidateToken(c.Request.Context(), token)
 		if err != nil {
 			statusCode := http.StatusUnauthorized
 			errorType := "invalid_token"
@@ -52,6 +52,8 @@ func AuthMiddleware(authService ports.AuthService) gin.HandlerFunc {
 		}
 
 		c.Set("user_id", userID)
+		c.Set("roles", claims.Roles)
+		c.Set("session_id", claims.SessionID)
 
 		c.Next()
 	}
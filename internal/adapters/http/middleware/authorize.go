@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// Policy decides whether the authenticated caller may use a route
+type Policy func(c *gin.Context, userID string, roles []domain.Role) bool
+
+// RequireRoles allows callers holding any of the given roles
+func RequireRoles(roles ...domain.Role) Policy {
+	return func(c *gin.Context, userID string, held []domain.Role) bool {
+		return domain.HasAnyRole(held, roles...)
+	}
+}
+
+// SelfOrRoles allows the user named by the path parameter to act on their own account,
+// and callers holding any of the given roles to act on any account.
+func SelfOrRoles(param string, roles ...domain.Role) Policy {
+	return func(c *gin.Context, userID string, held []domain.Role) bool {
+		return c.Param(param) == userID || domain.HasAnyRole(held, roles...)
+	}
+}
+
+// Authorize rejects the request with ErrForbidden unless the policy allows it.
+// It must run after AuthMiddleware, which sets the caller's user ID and roles.
+func Authorize(policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		roles, _ := c.Get("roles")
+		held, _ := roles.([]domain.Role)
+
+		if userID == "" || !policy(c, userID, held) {
+			c.Error(domain.ErrForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
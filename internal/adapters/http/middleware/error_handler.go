@@ -23,16 +23,31 @@ func ErrorHandler() gin.HandlerFunc {
 			if errors.As(err, &appErr) {
 				errorType = appErr.Status
 				switch appErr {
-				case domain.ErrUserNotFound:
+				case domain.ErrUserNotFound, domain.ErrSessionNotFound:
 					statusCode = http.StatusNotFound
-				case domain.ErrEmailAlreadyExists:
+				case domain.ErrEmailAlreadyExists, domain.ErrEmailVerified:
 					statusCode = http.StatusConflict
 				case domain.ErrInvalidCredentials:
 					statusCode = http.StatusUnauthorized
-				case domain.ErrRequestInvalid:
+				case domain.ErrRequestInvalid, domain.ErrInvalidResetToken, domain.ErrInvalidVerifyToken:
 					statusCode = http.StatusBadRequest
-				case domain.ErrInvalidToken, domain.ErrTokenBlacklisted:
+				case domain.ErrInvalidToken, domain.ErrTokenBlacklisted, domain.ErrInvalidWebhook:
 					statusCode = http.StatusUnauthorized
+				case domain.ErrForbidden, domain.ErrEmailNotVerified:
+					statusCode = http.StatusForbidden
+				case domain.ErrTicketNotFound, domain.ErrDrawNotFound, domain.ErrOrderNotFound:
+					statusCode = http.StatusNotFound
+				case domain.ErrTicketNotReserved, domain.ErrTicketAlreadySold, domain.ErrTicketReserved,
+					domain.ErrHoldNotExtendable, domain.ErrDrawExists, domain.ErrDrawAlreadyDrawn, domain.ErrDrawNotDrawn,
+					domain.ErrSalesClosed, domain.ErrNotEnoughCopies, domain.ErrSetUnavailable,
+					domain.ErrOrderStatusConflict, domain.ErrTicketInOrder, domain.ErrIdempotencyInProgress:
+					statusCode = http.StatusConflict
+				case domain.ErrIdempotencyKeyReused:
+					statusCode = http.StatusUnprocessableEntity
+				case domain.ErrQuotaExceeded:
+					statusCode = http.StatusTooManyRequests
+				case domain.ErrReservationExpired:
+					statusCode = http.StatusGone
 				}
 			}
 
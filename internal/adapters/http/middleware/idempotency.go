@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyLockTTL        = 1 * time.Minute
+	idempotencyReleaseTimeout = 5 * time.Second
+)
+
+// Idempotency replays the stored response of a request retried with the same Idempotency-Key
+// within ttl instead of running the handler again. Keys are scoped to the user and the request
+// path, and reusing a key for a different request is rejected. It must run after AuthMiddleware.
+//
+// Responses the handler renders with a status below 500 are stored. Requests that end in an
+// error are not, so a retry runs again.
+func Idempotency(store ports.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			c.Error(fmt.Errorf("%w: %s must be at most %d characters", domain.ErrRequestInvalid, IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(fmt.Errorf("%w: %v", domain.ErrRequestInvalid, err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := c.Get("user_id")
+		key := fmt.Sprintf("%v:%s:%s:%s", userID, c.Request.Method, c.Request.URL.Path, idempotencyKey)
+		fingerprint := requestFingerprint(c.Request, body)
+
+		existing, err := store.Begin(c.Request.Context(), key, fingerprint, idempotencyLockTTL)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			replay(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// คำขอจบลงไม่ว่าผู้ใช้จะตัดการเชื่อมต่อหรือไม่ จึงบันทึกผลโดยไม่ผูกกับ Context ของคำขอ
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), idempotencyReleaseTimeout)
+		defer cancel()
+
+		// ข้อผิดพลาดใน c.Errors ถูกเขียนโดย ErrorHandler หลัง Middleware นี้ จึงไม่ถูกเก็บไว้ตอบซ้ำ
+		status := recorder.Status()
+		if len(c.Errors) > 0 || status >= http.StatusInternalServerError {
+			if err := store.Release(ctx, key); err != nil {
+				logger.Error("Failed to release idempotency key", map[string]interface{}{
+					"key":   key,
+					"error": err.Error(),
+				})
+			}
+			return
+		}
+
+		response := &domain.IdempotentResponse{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := store.Complete(ctx, key, response, ttl); err != nil {
+			logger.Error("Failed to store idempotent response", map[string]interface{}{
+				"key":   key,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func replay(c *gin.Context, existing *domain.IdempotentResponse, fingerprint string) {
+	switch {
+	case existing.Fingerprint != fingerprint:
+		c.Error(domain.ErrIdempotencyKeyReused)
+		c.Abort()
+	case !existing.Completed:
+		c.Error(domain.ErrIdempotencyInProgress)
+		c.Abort()
+	default:
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(existing.Status, existing.ContentType, existing.Body)
+		c.Abort()
+	}
+}
+
+// requestFingerprint identifies a request by its query and body, so a key cannot be replayed
+// for a request with different parameters
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.RawQuery))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies everything the handler writes so it can be stored for replay
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
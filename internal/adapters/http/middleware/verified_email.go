@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects callers whose email is not verified with ErrEmailNotVerified.
+// The account is looked up on every request, so verifying takes effect without signing in again.
+// When required is false every caller passes. It must run after AuthMiddleware.
+func RequireVerifiedEmail(userService ports.UserService, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		user, err := userService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if !user.EmailVerified {
+			c.Error(domain.ErrEmailNotVerified)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
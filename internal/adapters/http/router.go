@@ -1,8 +1,11 @@
 package http
 
 import (
+	"time"
+
 	"github.com/backend-challenge/user-api/internal/adapters/http/handler"
 	"github.com/backend-challenge/user-api/internal/adapters/http/middleware"
+	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +14,11 @@ func SetupRouter(
 	userService ports.UserService,
 	authService ports.AuthService,
 	lotteryService ports.LotteryService,
+	drawService ports.LotteryDrawService,
+	orderService ports.OrderService,
+	idempotencyStore ports.IdempotencyStore,
+	idempotencyTTL time.Duration,
+	requireVerifiedEmail bool,
 ) *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -20,6 +28,17 @@ func SetupRouter(
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userService)
 	lotteryHandler := handler.NewLotteryHandler(lotteryService)
+	drawHandler := handler.NewLotteryDrawHandler(drawService)
+	orderHandler := handler.NewOrderHandler(orderService)
+	// คำขอที่จองหรือซื้อตั๋วส่งซ้ำได้อย่างปลอดภัยด้วย Idempotency-Key
+	idempotent := middleware.Idempotency(idempotencyStore, idempotencyTTL)
+	// สิทธิ์ตาม Role: ผู้ใช้ทั่วไปจัดการได้เฉพาะบัญชีของตัวเอง
+	adminOnly := middleware.Authorize(middleware.RequireRoles(domain.RoleAdmin))
+	staffOnly := middleware.Authorize(middleware.RequireRoles(domain.RoleAdmin, domain.RoleOperator))
+	selfOrAdmin := middleware.Authorize(middleware.SelfOrRoles("id", domain.RoleAdmin))
+	selfOrStaff := middleware.Authorize(middleware.SelfOrRoles("id", domain.RoleAdmin, domain.RoleOperator))
+	// ผู้ใช้ที่ยังไม่ยืนยันอีเมล Login ได้แต่จองตั๋วไม่ได้ เมื่อเปิด LOTTERY_REQUIRE_VERIFIED_EMAIL
+	verified := middleware.RequireVerifiedEmail(userService, requireVerifiedEmail)
 
 	v1 := router.Group("/api/v1")
 	{
@@ -28,7 +47,15 @@ func SetupRouter(
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", middleware.AuthMiddleware(authService), authHandler.LogoutAll)
+			auth.GET("/sessions", middleware.AuthMiddleware(authService), authHandler.ListSessions)
+			auth.DELETE("/sessions/:id", middleware.AuthMiddleware(authService), authHandler.RevokeSession)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/password/change", middleware.AuthMiddleware(authService), authHandler.ChangePassword)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.GET("/verify", authHandler.VerifyEmail)
+			auth.POST("/verify/resend", middleware.AuthMiddleware(authService), authHandler.ResendVerification)
 		}
 
 		users := v1.Group("/users")
@@ -36,16 +63,53 @@ func SetupRouter(
 		{
 			users.POST("", userHandler.CreateUser)
 			users.GET("", userHandler.ListUsers)
-			users.GET("/:id", userHandler.GetUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+			users.GET("/me", userHandler.GetMe)
+			users.PATCH("/me", userHandler.PatchMe)
+			users.DELETE("/me", userHandler.DeleteMe)
+			users.GET("/:id", selfOrStaff, userHandler.GetUser)
+			users.PUT("/:id", selfOrAdmin, userHandler.UpdateUser)
+			users.PUT("/:id/roles", adminOnly, userHandler.UpdateUserRoles)
+			users.DELETE("/:id", selfOrAdmin, userHandler.DeleteUser)
 		}
 
 		lotteries := v1.Group("/lotteries")
 		lotteries.Use(middleware.AuthMiddleware(authService))
 		{
-			lotteries.GET("/search", lotteryHandler.Search)
+			lotteries.GET("/search", verified, idempotent, lotteryHandler.Search)
+			lotteries.GET("/browse", lotteryHandler.Browse)
+			lotteries.GET("/watch", lotteryHandler.Watch)
+			lotteries.GET("/stats", staffOnly, lotteryHandler.Stats)
+			lotteries.POST("/reserve", verified, idempotent, lotteryHandler.Reserve)
+			lotteries.POST("/purchase", idempotent, orderHandler.Checkout)
+			lotteries.POST("/:id/purchase", idempotent, orderHandler.CheckoutTicket)
+			lotteries.GET("/:id/history", lotteryHandler.History)
+			lotteries.POST("/sets/:number/purchase", verified, idempotent, orderHandler.CheckoutSet)
+			lotteries.GET("/reservations", lotteryHandler.ListReservations)
+			lotteries.DELETE("/reservations", lotteryHandler.ReleaseAllReservations)
+			lotteries.DELETE("/reservations/:id", lotteryHandler.ReleaseReservation)
+			lotteries.GET("/cart", lotteryHandler.GetCart)
+			lotteries.POST("/cart/items", verified, idempotent, lotteryHandler.AddToCart)
+			lotteries.DELETE("/cart/items/:id", lotteryHandler.ReleaseReservation)
+			lotteries.POST("/cart/extend", lotteryHandler.ExtendHold)
+			lotteries.GET("/draws", drawHandler.ListDraws)
+			lotteries.POST("/draws", staffOnly, drawHandler.CreateDraw)
+			lotteries.POST("/draws/next", staffOnly, drawHandler.CreateNextDraw)
+			lotteries.GET("/draws/:id", drawHandler.GetDraw)
+			lotteries.PUT("/draws/:id/sales", staffOnly, drawHandler.UpdateSalesWindow)
+			lotteries.POST("/draws/:id/results", staffOnly, drawHandler.RecordResults)
+			lotteries.GET("/draws/:id/check", drawHandler.CheckTickets)
 		}
+
+		orders := v1.Group("/orders")
+		orders.Use(middleware.AuthMiddleware(authService))
+		{
+			orders.POST("", idempotent, orderHandler.Checkout)
+			orders.GET("", orderHandler.ListOrders)
+			orders.GET("/:id", orderHandler.GetOrder)
+		}
+
+		// Callback จาก Payment Gateway ยืนยันตัวตนด้วยลายเซ็น ไม่ใช่ JWT
+		v1.POST("/payments/webhook", orderHandler.PaymentWebhook)
 	}
 
 	router.GET("/health", func(c *gin.Context) {
@@ -0,0 +1,91 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// digitFields are the per-position digit fields stored on every lottery document.
+// Together with the {status, d0..d5} index they turn any wildcard pattern into an
+// indexed equality lookup instead of a $regex scan on number.
+var digitFields = [domain.LotteryNumberLength]string{"d0", "d1", "d2", "d3", "d4", "d5"}
+
+var anyDigit = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+func digitIndexKeys() bson.D {
+	keys := make(bson.D, len(digitFields))
+	for i, field := range digitFields {
+		keys[i] = bson.E{Key: field, Value: 1}
+	}
+	return keys
+}
+
+func numberDigits(number string) [domain.LotteryNumberLength]int {
+	var digits [domain.LotteryNumberLength]int
+	for i := 0; i < len(number) && i < domain.LotteryNumberLength; i++ {
+		digits[i] = int(number[i] - '0')
+	}
+	return digits
+}
+
+// LotteryPatternFilter builds the index-friendly Mongo filter for pattern. Wildcard positions
+// before the last constrained one are expanded to $in of all digits, so the compound index
+// bounds stay point intervals instead of falling back to a full scan.
+func LotteryPatternFilter(pattern *domain.LotteryPattern) bson.M {
+	filter := bson.M{}
+
+	last := -1
+	for i := 0; i < domain.LotteryNumberLength; i++ {
+		if pattern.AllowedDigits(i) != nil {
+			last = i
+		}
+	}
+
+	for i := 0; i <= last; i++ {
+		digits := pattern.AllowedDigits(i)
+		switch {
+		case digits == nil:
+			filter[digitFields[i]] = bson.M{"$in": anyDigit}
+		case len(digits) == 1:
+			filter[digitFields[i]] = digits[0]
+		default:
+			filter[digitFields[i]] = bson.M{"$in": digits}
+		}
+	}
+
+	// เงื่อนไข contains ไม่สามารถแยกตามตำแหน่งได้ จึงใช้ Regex กรองเพิ่มหลังจากใช้ Index แล้ว
+	if pattern.HasContains() {
+		filter["number"] = bson.M{"$regex": pattern.Regex()}
+	}
+
+	return filter
+}
+
+// withPatternFilter merges the pattern filter into extra
+func withPatternFilter(pattern *domain.LotteryPattern, extra bson.M) bson.M {
+	filter := LotteryPatternFilter(pattern)
+	for k, v := range extra {
+		filter[k] = v
+	}
+	return filter
+}
+
+// MigrateDigitFields backfills the digit position fields on documents created before they existed.
+// The update runs server side as a single pipeline update.
+func (r *LotteryRepository) MigrateDigitFields(ctx context.Context) (int64, error) {
+	set := bson.M{}
+	for i, field := range digitFields {
+		set[field] = bson.M{"$toInt": bson.M{"$substrCP": bson.A{"$number", i, 1}}}
+	}
+
+	filter := bson.M{digitFields[domain.LotteryNumberLength-1]: bson.M{"$exists": false}}
+	result, err := r.collection.UpdateMany(ctx, filter, bson.A{bson.M{"$set": set}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate lottery digit fields: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -7,27 +7,47 @@ import (
 )
 
 type lotteryDoc struct {
-	ID            string               `bson:"_id,omitempty"`
-	Number        string               `bson:"number"`
-	Status        domain.LotteryStatus `bson:"status"`
-	ReservedUntil *time.Time           `bson:"reserved_until,omitempty"`
-	ReservedBy    string               `bson:"reserved_by,omitempty"`
-	CreatedAt     time.Time            `bson:"created_at"`
-	UpdatedAt     time.Time            `bson:"updated_at"`
+	ID             string               `bson:"_id,omitempty"`
+	Number         string               `bson:"number"`
+	Set            int                  `bson:"set"`
+	Status         domain.LotteryStatus `bson:"status"`
+	ReservedUntil  *time.Time           `bson:"reserved_until,omitempty"`
+	ReservedBy     string               `bson:"reserved_by,omitempty"`
+	HoldExtensions int                  `bson:"hold_extensions,omitempty"`
+	DrawID         string               `bson:"draw_id,omitempty"`
+	ClaimToken     string               `bson:"claim_token,omitempty"`
+	D0             int                  `bson:"d0"`
+	D1             int                  `bson:"d1"`
+	D2             int                  `bson:"d2"`
+	D3             int                  `bson:"d3"`
+	D4             int                  `bson:"d4"`
+	D5             int                  `bson:"d5"`
+	CreatedAt      time.Time            `bson:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at"`
 }
 
 func fromLotteryDomain(l *domain.LotteryTicket) *lotteryDoc {
 	if l == nil {
 		return nil
 	}
+	digits := numberDigits(l.Number)
 	return &lotteryDoc{
-		ID:            l.ID,
-		Number:        l.Number,
-		Status:        l.Status,
-		ReservedUntil: l.ReservedUntil,
-		ReservedBy:    l.ReservedBy,
-		CreatedAt:     l.CreatedAt,
-		UpdatedAt:     l.UpdatedAt,
+		ID:             l.ID,
+		Number:         l.Number,
+		Set:            l.Set,
+		Status:         l.Status,
+		ReservedUntil:  l.ReservedUntil,
+		ReservedBy:     l.ReservedBy,
+		HoldExtensions: l.HoldExtensions,
+		DrawID:         l.DrawID,
+		D0:             digits[0],
+		D1:             digits[1],
+		D2:             digits[2],
+		D3:             digits[3],
+		D4:             digits[4],
+		D5:             digits[5],
+		CreatedAt:      l.CreatedAt,
+		UpdatedAt:      l.UpdatedAt,
 	}
 }
 
@@ -36,12 +56,15 @@ func (d *lotteryDoc) toLotteryDomain() *domain.LotteryTicket {
 		return nil
 	}
 	return &domain.LotteryTicket{
-		ID:            d.ID,
-		Number:        d.Number,
-		Status:        d.Status,
-		ReservedUntil: d.ReservedUntil,
-		ReservedBy:    d.ReservedBy,
-		CreatedAt:     d.CreatedAt,
-		UpdatedAt:     d.UpdatedAt,
+		ID:             d.ID,
+		Number:         d.Number,
+		Set:            d.Set,
+		Status:         d.Status,
+		ReservedUntil:  d.ReservedUntil,
+		ReservedBy:     d.ReservedBy,
+		HoldExtensions: d.HoldExtensions,
+		DrawID:         d.DrawID,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
 	}
 }
@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+type lotteryDrawDoc struct {
+	ID            string            `bson:"_id"`
+	DrawDate      time.Time         `bson:"draw_date"`
+	Status        domain.DrawStatus `bson:"status"`
+	SalesOpenAt   time.Time         `bson:"sales_open_at"`
+	SalesCloseAt  time.Time         `bson:"sales_close_at"`
+	TicketCount   int               `bson:"ticket_count"`
+	SetsPerNumber int               `bson:"sets_per_number,omitempty"`
+	TicketPrice   int64             `bson:"ticket_price,omitempty"`
+	StockedAt     *time.Time        `bson:"stocked_at,omitempty"`
+	Prizes        []lotteryPrizeDoc `bson:"prizes,omitempty"`
+	DrawnAt       *time.Time        `bson:"drawn_at,omitempty"`
+	CreatedAt     time.Time         `bson:"created_at"`
+	UpdatedAt     time.Time         `bson:"updated_at"`
+}
+
+type lotteryPrizeDoc struct {
+	Tier    domain.PrizeTier `bson:"tier"`
+	Numbers []string         `bson:"numbers"`
+	Amount  int64            `bson:"amount"`
+}
+
+func fromLotteryDrawDomain(d *domain.LotteryDraw) *lotteryDrawDoc {
+	if d == nil {
+		return nil
+	}
+	prizes := make([]lotteryPrizeDoc, len(d.Prizes))
+	for i, p := range d.Prizes {
+		prizes[i] = lotteryPrizeDoc{Tier: p.Tier, Numbers: p.Numbers, Amount: p.Amount}
+	}
+	return &lotteryDrawDoc{
+		ID:            d.ID,
+		DrawDate:      d.DrawDate,
+		Status:        d.Status,
+		SalesOpenAt:   d.SalesOpenAt,
+		SalesCloseAt:  d.SalesCloseAt,
+		TicketCount:   d.TicketCount,
+		SetsPerNumber: d.SetsPerNumber,
+		TicketPrice:   d.TicketPrice,
+		StockedAt:     d.StockedAt,
+		Prizes:        prizes,
+		DrawnAt:       d.DrawnAt,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+func (d *lotteryDrawDoc) toLotteryDrawDomain() *domain.LotteryDraw {
+	if d == nil {
+		return nil
+	}
+	prizes := make([]domain.LotteryPrize, len(d.Prizes))
+	for i, p := range d.Prizes {
+		prizes[i] = domain.LotteryPrize{Tier: p.Tier, Numbers: p.Numbers, Amount: p.Amount}
+	}
+	// งวดที่สร้างก่อนรองรับหลายชุดต่อเลข มีเลขละหนึ่งใบ
+	sets := d.SetsPerNumber
+	if sets == 0 {
+		sets = 1
+	}
+	// งวดที่สร้างก่อนมีราคาตั๋ว ใช้ราคามาตรฐาน
+	price := d.TicketPrice
+	if price == 0 {
+		price = domain.DefaultTicketPrice
+	}
+	return &domain.LotteryDraw{
+		ID:            d.ID,
+		DrawDate:      d.DrawDate.In(domain.DrawLocation),
+		Status:        d.Status,
+		SalesOpenAt:   d.SalesOpenAt,
+		SalesCloseAt:  d.SalesCloseAt,
+		TicketCount:   d.TicketCount,
+		SetsPerNumber: sets,
+		TicketPrice:   price,
+		StockedAt:     d.StockedAt,
+		Prizes:        prizes,
+		DrawnAt:       d.DrawnAt,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
@@ -0,0 +1,226 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LotteryDrawRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLotteryDrawRepository(db *mongo.Database) *LotteryDrawRepository {
+	collection := db.Collection("lottery_draws")
+
+	indexModels := []mongo.IndexModel{
+		{
+			// หนึ่งวันออกรางวัลมีได้เพียงงวดเดียว
+			Keys:    bson.D{{Key: "draw_date", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "draw_date", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "sales_close_at", Value: 1}},
+		},
+	}
+	collection.Indexes().CreateMany(context.Background(), indexModels)
+
+	return &LotteryDrawRepository{
+		collection: collection,
+	}
+}
+
+func (r *LotteryDrawRepository) Create(ctx context.Context, draw *domain.LotteryDraw) error {
+	if draw.ID == "" {
+		draw.ID = uuid.New().String()
+	}
+	now := time.Now()
+	draw.CreatedAt = now
+	draw.UpdatedAt = now
+
+	_, err := r.collection.InsertOne(ctx, fromLotteryDrawDomain(draw))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrDrawExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *LotteryDrawRepository) FindByID(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+	var doc lotteryDrawDoc
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrDrawNotFound
+		}
+		return nil, err
+	}
+
+	return doc.toLotteryDrawDomain(), nil
+}
+
+// FindNext returns the earliest scheduled round whose sales have not closed by the given time
+func (r *LotteryDrawRepository) FindNext(ctx context.Context, after time.Time) (*domain.LotteryDraw, error) {
+	filter := bson.M{
+		"status":         domain.DrawStatusScheduled,
+		"sales_close_at": bson.M{"$gt": after},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "draw_date", Value: 1}})
+	return r.findOne(ctx, filter, opts)
+}
+
+// FindOnSale returns the earliest stocked round whose sales window contains now
+func (r *LotteryDrawRepository) FindOnSale(ctx context.Context, now time.Time) (*domain.LotteryDraw, error) {
+	filter := bson.M{
+		"status":         domain.DrawStatusScheduled,
+		"stocked_at":     bson.M{"$exists": true},
+		"sales_open_at":  bson.M{"$lte": now},
+		"sales_close_at": bson.M{"$gt": now},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "draw_date", Value: 1}})
+	return r.findOne(ctx, filter, opts)
+}
+
+// FindLatest returns the round with the latest draw date
+func (r *LotteryDrawRepository) FindLatest(ctx context.Context) (*domain.LotteryDraw, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "draw_date", Value: -1}})
+	return r.findOne(ctx, bson.M{}, opts)
+}
+
+// FindUnstocked returns scheduled rounds whose ticket inventory has not been created yet
+func (r *LotteryDrawRepository) FindUnstocked(ctx context.Context) ([]*domain.LotteryDraw, error) {
+	filter := bson.M{
+		"status":     domain.DrawStatusScheduled,
+		"stocked_at": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "draw_date", Value: 1}})
+	return r.find(ctx, filter, opts)
+}
+
+func (r *LotteryDrawRepository) MarkStocked(ctx context.Context, id string, stockedAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"stocked_at": stockedAt,
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrDrawNotFound
+	}
+	return nil
+}
+
+// UpdateSalesWindow stores the round's sales window while it has not been drawn
+func (r *LotteryDrawRepository) UpdateSalesWindow(ctx context.Context, draw *domain.LotteryDraw) error {
+	draw.UpdatedAt = time.Now()
+	filter := bson.M{
+		"_id":    draw.ID,
+		"status": domain.DrawStatusScheduled,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"sales_open_at":  draw.SalesOpenAt,
+			"sales_close_at": draw.SalesCloseAt,
+			"updated_at":     draw.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.FindByID(ctx, draw.ID); err != nil {
+			return err
+		}
+		return domain.ErrDrawAlreadyDrawn
+	}
+	return nil
+}
+
+// List returns the most recent draws first
+func (r *LotteryDrawRepository) List(ctx context.Context, limit int) ([]*domain.LotteryDraw, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "draw_date", Value: -1}}).SetLimit(int64(limit))
+	return r.find(ctx, bson.M{}, opts)
+}
+
+func (r *LotteryDrawRepository) findOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*domain.LotteryDraw, error) {
+	var doc lotteryDrawDoc
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrDrawNotFound
+		}
+		return nil, err
+	}
+
+	return doc.toLotteryDrawDomain(), nil
+}
+
+func (r *LotteryDrawRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.LotteryDraw, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDrawDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	draws := make([]*domain.LotteryDraw, len(docs))
+	for i := range docs {
+		draws[i] = docs[i].toLotteryDrawDomain()
+	}
+	return draws, nil
+}
+
+// SaveResults stores the draw's prizes. Results can only be recorded once, so concurrent
+// submissions for the same draw cannot overwrite each other.
+func (r *LotteryDrawRepository) SaveResults(ctx context.Context, draw *domain.LotteryDraw) error {
+	draw.UpdatedAt = time.Now()
+	doc := fromLotteryDrawDomain(draw)
+
+	filter := bson.M{
+		"_id":    draw.ID,
+		"status": domain.DrawStatusScheduled,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     doc.Status,
+			"prizes":     doc.Prizes,
+			"drawn_at":   doc.DrawnAt,
+			"updated_at": doc.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.FindByID(ctx, draw.ID); err != nil {
+			return err
+		}
+		return domain.ErrDrawAlreadyDrawn
+	}
+
+	return nil
+}
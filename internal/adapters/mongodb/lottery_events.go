@@ -0,0 +1,186 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/logger"
+)
+
+const (
+	// lotteryEventsChannel is the Redis pub/sub channel every API instance publishes ticket changes to
+	lotteryEventsChannel = "lottery_events"
+	// lotteryWatcherBuffer is how many events a slow watcher may fall behind before events are dropped
+	lotteryWatcherBuffer = 64
+)
+
+// lotteryEventMessage is a LotteryEvent as published on Redis
+type lotteryEventMessage struct {
+	Type          domain.LotteryEventType `json:"type"`
+	TicketID      string                  `json:"ticket_id"`
+	DrawID        string                  `json:"draw_id"`
+	Number        string                  `json:"number"`
+	Set           int                     `json:"set"`
+	ReservedUntil *time.Time              `json:"reserved_until,omitempty"`
+	At            time.Time               `json:"at"`
+}
+
+func (m *lotteryEventMessage) toDomain() domain.LotteryEvent {
+	return domain.LotteryEvent{
+		Type:          m.Type,
+		TicketID:      m.TicketID,
+		DrawID:        m.DrawID,
+		Number:        m.Number,
+		Set:           m.Set,
+		ReservedUntil: m.ReservedUntil,
+		At:            m.At,
+	}
+}
+
+// lotteryEventHub fans the events of one Redis subscription out to the watchers of this
+// instance, so watchers do not each hold a Redis connection.
+type lotteryEventHub struct {
+	mu       sync.Mutex
+	watchers map[chan domain.LotteryEvent]string
+	closed   bool
+}
+
+func newLotteryEventHub() *lotteryEventHub {
+	return &lotteryEventHub{
+		watchers: make(map[chan domain.LotteryEvent]string),
+	}
+}
+
+// add registers a watcher of a draw round. It returns nil once the hub has stopped.
+func (h *lotteryEventHub) add(drawID string) chan domain.LotteryEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	ch := make(chan domain.LotteryEvent, lotteryWatcherBuffer)
+	h.watchers[ch] = drawID
+	return ch
+}
+
+func (h *lotteryEventHub) remove(ch chan domain.LotteryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.watchers[ch]; ok {
+		delete(h.watchers, ch)
+		close(ch)
+	}
+}
+
+// broadcast hands an event to every watcher of its draw round. A watcher whose buffer is full
+// misses the event rather than holding up the others.
+func (h *lotteryEventHub) broadcast(event domain.LotteryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, drawID := range h.watchers {
+		if drawID != event.DrawID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// stop closes every watcher and refuses new ones
+func (h *lotteryEventHub) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.watchers {
+		delete(h.watchers, ch)
+		close(ch)
+	}
+}
+
+// publishEvents announces a change of tickets to the watchers of every API instance.
+// Publishing is best effort: a lost event only means a watcher finds out on its next search.
+func (r *LotteryRepository) publishEvents(ctx context.Context, eventType domain.LotteryEventType, docs ...lotteryDoc) {
+	if len(docs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	pipe := r.redis.Pipeline()
+	for i := range docs {
+		event := domain.NewLotteryEvent(eventType, docs[i].toLotteryDomain(), now)
+		payload, err := json.Marshal(&lotteryEventMessage{
+			Type:          event.Type,
+			TicketID:      event.TicketID,
+			DrawID:        event.DrawID,
+			Number:        event.Number,
+			Set:           event.Set,
+			ReservedUntil: event.ReservedUntil,
+			At:            event.At,
+		})
+		if err != nil {
+			continue
+		}
+		pipe.Publish(ctx, lotteryEventsChannel, payload)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Debug("Failed to publish lottery events", map[string]interface{}{
+			"type":  string(eventType),
+			"count": len(docs),
+			"error": err.Error(),
+		})
+	}
+}
+
+// Watch streams the ticket events of a draw round published by any API instance until ctx is
+// cancelled or RunEventHub stops, then closes the channel. Events only arrive while
+// RunEventHub is running.
+func (r *LotteryRepository) Watch(ctx context.Context, drawID string) (<-chan domain.LotteryEvent, error) {
+	ch := r.events.add(drawID)
+	if ch == nil {
+		// Server กำลังปิดตัว ส่ง Channel ที่ปิดแล้วกลับไปให้ผู้เรียกจบการทำงานทันที
+		closed := make(chan domain.LotteryEvent)
+		close(closed)
+		return closed, nil
+	}
+
+	go func() {
+		<-ctx.Done()
+		r.events.remove(ch)
+	}()
+	return ch, nil
+}
+
+// RunEventHub subscribes to the ticket events of every API instance and hands them to the
+// watchers of this instance until ctx is cancelled. It then closes every watcher, so open
+// streams end and the HTTP server can shut down.
+func (r *LotteryRepository) RunEventHub(ctx context.Context) {
+	defer r.events.stop()
+
+	pubsub := r.redis.Subscribe(ctx, lotteryEventsChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var message lotteryEventMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				logger.Error("Failed to decode lottery event", map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+			r.events.broadcast(message.toDomain())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lotteryHistoryWriteTimeout bounds writing the audit trail of a change that already happened
+const lotteryHistoryWriteTimeout = 5 * time.Second
+
+// lotteryHistoryDoc is one entry of the append-only lottery_events collection. Entries are
+// only ever inserted.
+type lotteryHistoryDoc struct {
+	ID            primitive.ObjectID      `bson:"_id"`
+	TicketID      string                  `bson:"ticket_id"`
+	DrawID        string                  `bson:"draw_id,omitempty"`
+	Number        string                  `bson:"number"`
+	Set           int                     `bson:"set"`
+	Type          domain.LotteryEventType `bson:"type"`
+	Actor         string                  `bson:"actor"`
+	FromStatus    domain.LotteryStatus    `bson:"from_status"`
+	ToStatus      domain.LotteryStatus    `bson:"to_status"`
+	ReservedUntil *time.Time              `bson:"reserved_until,omitempty"`
+	At            time.Time               `bson:"at"`
+}
+
+func (d *lotteryHistoryDoc) toDomain() domain.LotteryHistoryEntry {
+	return domain.LotteryHistoryEntry{
+		ID:            d.ID.Hex(),
+		TicketID:      d.TicketID,
+		DrawID:        d.DrawID,
+		Number:        d.Number,
+		Set:           d.Set,
+		Type:          d.Type,
+		Actor:         d.Actor,
+		FromStatus:    d.FromStatus,
+		ToStatus:      d.ToStatus,
+		ReservedUntil: d.ReservedUntil,
+		At:            d.At,
+	}
+}
+
+func newLotteryHistoryCollection(db *mongo.Database) *mongo.Collection {
+	collection := db.Collection("lottery_events")
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "ticket_id", Value: 1}, {Key: "at", Value: 1}},
+		},
+		{
+			// ตรวจสอบข้อโต้แย้งจากเลขและงวด เช่น "ฉันซื้อเลขนี้" โดยไม่ต้องรู้ ID ของตั๋ว
+			Keys: bson.D{{Key: "draw_id", Value: 1}, {Key: "number", Value: 1}, {Key: "at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "actor", Value: 1}, {Key: "at", Value: -1}},
+		},
+	}
+	collection.Indexes().CreateMany(context.Background(), indexModels)
+
+	return collection
+}
+
+// ticketChange describes a change applied to a batch of tickets
+type ticketChange struct {
+	eventType domain.LotteryEventType
+	actor     string
+	from      domain.LotteryStatus
+	to        domain.LotteryStatus
+}
+
+// recordChanges appends a change of docs to the audit trail and announces it to watchers.
+// docs are the tickets as they are after the change.
+func (r *LotteryRepository) recordChanges(ctx context.Context, change ticketChange, docs ...lotteryDoc) {
+	if len(docs) == 0 {
+		return
+	}
+	r.appendHistory(ctx, change, docs)
+
+	// ผู้ที่รอดูสนใจแค่ว่าตั๋วว่างหรือไม่ การหมดเวลาจึงแจ้งเป็นการปล่อยคืน
+	eventType := change.eventType
+	if eventType == domain.LotteryEventExpired {
+		eventType = domain.LotteryEventReleased
+	}
+	r.publishEvents(ctx, eventType, docs...)
+}
+
+// appendHistory inserts one entry per ticket. The change has already been applied, so the
+// write uses its own context and a failure is logged rather than undoing it.
+func (r *LotteryRepository) appendHistory(ctx context.Context, change ticketChange, docs []lotteryDoc) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lotteryHistoryWriteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	entries := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		entry := &lotteryHistoryDoc{
+			// ObjectID เรียงตามลำดับที่สร้าง ประวัติที่เกิดในมิลลิวินาทีเดียวกันจึงยังเรียงถูก
+			ID:         primitive.NewObjectID(),
+			TicketID:   doc.ID,
+			DrawID:     doc.DrawID,
+			Number:     doc.Number,
+			Set:        doc.Set,
+			Type:       change.eventType,
+			Actor:      change.actor,
+			FromStatus: change.from,
+			ToStatus:   change.to,
+			At:         now,
+		}
+		if change.to == domain.LotteryStatusReserved {
+			entry.ReservedUntil = doc.ReservedUntil
+		}
+		entries[i] = entry
+	}
+
+	if _, err := r.history.InsertMany(ctx, entries, options.InsertMany().SetOrdered(false)); err != nil {
+		logger.Error("Failed to append lottery ticket history", map[string]interface{}{
+			"type":  string(change.eventType),
+			"actor": change.actor,
+			"count": len(docs),
+			"error": err.Error(),
+		})
+	}
+}
+
+// FindHistory returns up to limit changes of a ticket, oldest first. Tickets changed before the
+// audit trail existed have no entries for those changes.
+func (r *LotteryRepository) FindHistory(ctx context.Context, ticketID string, limit int) ([]domain.LotteryHistoryEntry, error) {
+	ticket, err := r.findByID(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.history.Find(ctx, bson.M{"ticket_id": ticket.ID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryHistoryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.LotteryHistoryEntry, len(docs))
+	for i := range docs {
+		entries[i] = docs[i].toDomain()
+	}
+	return entries, nil
+}
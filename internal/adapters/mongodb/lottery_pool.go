@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// lotteryPoolInflightKey is a sorted set of ticket IDs popped from a pattern pool but not yet
+	// confirmed in MongoDB, stored as "<pool key>|<ticket id>" and scored by a recovery deadline.
+	lotteryPoolInflightKey = "lottery_pool_inflight"
+	poolClaimTimeout       = 1 * time.Minute
+	poolRestoreTimeout     = 5 * time.Second
+	poolRecoverBatchSize   = 1000
+)
+
+// popPoolScript pops ticket IDs from a pool and records them as in flight in the same step,
+// so a request that dies before reaching MongoDB cannot silently drop them.
+//
+// KEYS[1] pool key, KEYS[2] inflight key, ARGV[1] count, ARGV[2] deadline (ms)
+var popPoolScript = redis.NewScript(`
+local nums = redis.call('SPOP', KEYS[1], ARGV[1])
+for _, n in ipairs(nums) do
+	redis.call('ZADD', KEYS[2], ARGV[2], KEYS[1] .. '|' .. n)
+end
+return nums
+`)
+
+// restorePoolScript puts ticket IDs back into a pool that still exists and clears them from in flight.
+//
+// KEYS[1] pool key, KEYS[2] inflight key, ARGV ticket IDs
+var restorePoolScript = redis.NewScript(`
+local exists = redis.call('EXISTS', KEYS[1])
+for _, n in ipairs(ARGV) do
+	redis.call('ZREM', KEYS[2], KEYS[1] .. '|' .. n)
+	if exists == 1 then
+		redis.call('SADD', KEYS[1], n)
+	end
+end
+return exists
+`)
+
+// popPool takes up to count ticket IDs from the pool at redisKey and marks them in flight.
+func (r *LotteryRepository) popPool(ctx context.Context, redisKey string, count int) ([]string, error) {
+	deadline := time.Now().Add(poolClaimTimeout).UnixMilli()
+	return popPoolScript.Run(ctx, r.redis, []string{redisKey, lotteryPoolInflightKey}, count, deadline).StringSlice()
+}
+
+// ackPool confirms popped ticket IDs were handled in MongoDB, whether claimed or found unavailable.
+func (r *LotteryRepository) ackPool(ctx context.Context, redisKey string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = redisKey + "|" + id
+	}
+	r.redis.ZRem(ctx, lotteryPoolInflightKey, members...)
+}
+
+// restorePool returns popped ticket IDs to their pool after the MongoDB step failed. It uses its
+// own context because the request context is usually the one that was cancelled.
+func (r *LotteryRepository) restorePool(ctx context.Context, redisKey string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), poolRestoreTimeout)
+	defer cancel()
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	restorePoolScript.Run(ctx, r.redis, []string{redisKey, lotteryPoolInflightKey}, args...)
+}
+
+// recoverInflight returns ticket IDs whose claim deadline passed, left behind by requests that
+// died between popping the pool and confirming in MongoDB. Returned tickets are re-checked
+// against MongoDB on the next reservation, so restoring one that was claimed is harmless.
+func (r *LotteryRepository) recoverInflight(ctx context.Context) (int, error) {
+	now := time.Now().UnixMilli()
+	recovered := 0
+
+	for {
+		members, err := r.redis.ZRangeByScore(ctx, lotteryPoolInflightKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   formatScore(now),
+			Count: poolRecoverBatchSize,
+		}).Result()
+		if err != nil {
+			return recovered, err
+		}
+		if len(members) == 0 {
+			return recovered, nil
+		}
+
+		byPool := make(map[string][]string)
+		for _, member := range members {
+			sep := strings.LastIndex(member, "|")
+			if sep < 0 {
+				r.redis.ZRem(ctx, lotteryPoolInflightKey, member)
+				continue
+			}
+			byPool[member[:sep]] = append(byPool[member[:sep]], member[sep+1:])
+		}
+		for redisKey, ids := range byPool {
+			args := make([]interface{}, len(ids))
+			for i, id := range ids {
+				args[i] = id
+			}
+			if err := restorePoolScript.Run(ctx, r.redis, []string{redisKey, lotteryPoolInflightKey}, args...).Err(); err != nil {
+				return recovered, err
+			}
+			recovered += len(ids)
+		}
+
+		if len(members) < poolRecoverBatchSize {
+			return recovered, nil
+		}
+	}
+}
+
+func formatScore(ms int64) string {
+	return strconv.FormatInt(ms, 10)
+}
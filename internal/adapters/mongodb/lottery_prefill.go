@@ -0,0 +1,102 @@
+package mongodb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/logger"
+)
+
+const (
+	prefillQueueSize  = 256
+	prefillJobTimeout = 10 * time.Second
+)
+
+type prefillJob struct {
+	drawID   string
+	pattern  *domain.LotteryPattern
+	redisKey string
+}
+
+// poolPrefiller refills Redis pattern pools on a bounded set of workers. A pattern is queued
+// at most once at a time, so a burst of searches for the same pattern costs one MongoDB query.
+type poolPrefiller struct {
+	jobs    chan prefillJob
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+func newPoolPrefiller() *poolPrefiller {
+	return &poolPrefiller{
+		jobs:    make(chan prefillJob, prefillQueueSize),
+		pending: make(map[string]struct{}),
+	}
+}
+
+// enqueue schedules a refill unless one is already pending for the pool or the queue is full.
+// Dropping is safe: the next search for the pattern schedules it again.
+func (p *poolPrefiller) enqueue(drawID string, pattern *domain.LotteryPattern, redisKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pending[redisKey]; ok {
+		return
+	}
+	select {
+	case p.jobs <- prefillJob{drawID: drawID, pattern: pattern, redisKey: redisKey}:
+		p.pending[redisKey] = struct{}{}
+	default:
+		logger.Debug("Lottery pool prefill queue is full, skipping refill", map[string]interface{}{
+			"pattern": pattern.Key(),
+		})
+	}
+}
+
+func (p *poolPrefiller) done(redisKey string) {
+	p.mu.Lock()
+	delete(p.pending, redisKey)
+	p.mu.Unlock()
+}
+
+// RunPrefillWorkers refills Redis pattern pools with the given number of workers until ctx
+// is cancelled, then waits for in-progress refills to stop. Searches only queue refills, so
+// pools are not refilled unless this is running.
+func (r *LotteryRepository) RunPrefillWorkers(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-r.prefill.jobs:
+					r.runPrefillJob(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *LotteryRepository) runPrefillJob(ctx context.Context, job prefillJob) {
+	defer r.prefill.done(job.redisKey)
+
+	jobCtx, cancel := context.WithTimeout(ctx, prefillJobTimeout)
+	defer cancel()
+
+	if err := r.prefillRedis(jobCtx, job.drawID, job.pattern, job.redisKey); err != nil && ctx.Err() == nil {
+		logger.Error("Failed to prefill lottery pool", map[string]interface{}{
+			"draw_id": job.drawID,
+			"pattern": job.pattern.Key(),
+			"error":   err.Error(),
+		})
+	}
+}
@@ -2,20 +2,46 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	lotteryPatternKeyPrefix   = "lottery_pattern:"
+	lotteryPatternRegistryKey = "lottery_patterns"
+)
+
+// legacyNumberIndex is the unique index on number alone from before tickets were scoped to a
+// draw round. It would stop the same number being stocked for another round or as another set.
+const legacyNumberIndex = "number_1"
+
+// lotteryPoolKey is the Redis set of available ticket IDs of a draw round matching a pattern key.
+// Every copy of a number has its own ID, so each member is exactly one copy.
+func lotteryPoolKey(drawID, patternKey string) string {
+	return lotteryPatternKeyPrefix + drawID + ":" + patternKey
+}
+
+// lotteryPoolRegistryKey is the Redis set of pattern keys with a cached pool in a draw round
+func lotteryPoolRegistryKey(drawID string) string {
+	return lotteryPatternRegistryKey + ":" + drawID
+}
+
 type LotteryRepository struct {
 	collection *mongo.Collection
 	redis      *redis.Client
+	history    *mongo.Collection
+	seeds      *mongo.Collection
+	prefill    *poolPrefiller
+	events     *lotteryEventHub
 }
 
 func NewLotteryRepository(db *mongo.Database, rdb *redis.Client) *LotteryRepository {
@@ -24,11 +50,33 @@ func NewLotteryRepository(db *mongo.Database, rdb *redis.Client) *LotteryReposit
 	// Create indexes
 	indexModels := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{Key: "number", Value: 1}},
+			// เลขเดียวกันมีได้หลายชุดต่องวด แต่ละชุดมีหนึ่งใบ
+			Keys:    bson.D{{Key: "draw_id", Value: 1}, {Key: "number", Value: 1}, {Key: "set", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 		{
-			Keys: bson.D{{Key: "status", Value: 1}, {Key: "number", Value: 1}},
+			// ค้นหาตามตำแหน่งตัวเลขสำหรับการจอง (ดู LotteryPatternFilter)
+			Keys: append(bson.D{{Key: "draw_id", Value: 1}, {Key: "status", Value: 1}}, digitIndexKeys()...),
+		},
+		{
+			// ค้นหาและเรียงตามเลขและชุดสำหรับการ Browse โดยไม่สนสถานะ
+			Keys: append(append(bson.D{{Key: "draw_id", Value: 1}}, digitIndexKeys()...), bson.E{Key: "set", Value: 1}),
+		},
+		{
+			Keys: bson.D{{Key: "reserved_by", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			// ตรวจผลรางวัลจากตั๋วที่ผู้ใช้ซื้อในแต่ละงวด
+			Keys: bson.D{{Key: "reserved_by", Value: 1}, {Key: "draw_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "claim_token", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			// นับยอดขายรายชั่วโมงสำหรับหน้าสถิติ
+			Keys:    bson.D{{Key: "sold_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
 		},
 		{
 			Keys: bson.D{{Key: "reserved_until", Value: 1}},
@@ -40,138 +88,312 @@ func NewLotteryRepository(db *mongo.Database, rdb *redis.Client) *LotteryReposit
 	return &LotteryRepository{
 		collection: collection,
 		redis:      rdb,
+		history:    newLotteryHistoryCollection(db),
+		seeds:      db.Collection("lottery_seed_progress"),
+		prefill:    newPoolPrefiller(),
+		events:     newLotteryEventHub(),
 	}
 }
 
-func (r *LotteryRepository) patternToRegex(pattern string) string {
-	regex := strings.ReplaceAll(pattern, "*", ".")
-	return fmt.Sprintf("^%s$", regex)
-}
+// maxClaimAttempts bounds the Mongo fallback rounds of SearchAndReserve. Each round is one
+// Find for candidates and one UpdateMany to claim them, so a search costs a fixed number of
+// round-trips no matter how many tickets it reserves.
+const maxClaimAttempts = 3
 
-func (r *LotteryRepository) SearchAndReserve(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error) {
-	// แปลงรูปแบบ wildcard (เช่น ****23) ให้เป็น Regular Expression ของ MongoDB (เช่น ^....23$)
-	regexPattern := r.patternToRegex(pattern)
-	redisKey := fmt.Sprintf("lottery_pattern:%s", pattern)
+// SearchAndReserve reserves up to limit tickets matching pattern from the draw round's inventory.
+// It refuses with ErrSalesClosed when the round is outside its sales window.
+func (r *LotteryRepository) SearchAndReserve(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
 	now := time.Now()
-	// กำหนดเวลาหมดอายุของการจอง (เช่น 5 นาที)
-	reservedUntil := now.Add(5 * time.Minute)
+	if !draw.IsOnSale(now) {
+		return nil, domain.ErrSalesClosed
+	}
 
-	tickets := make([]domain.LotteryTicket, 0)
+	// ใช้ Key ที่ถูก Normalize แล้ว เพื่อให้ Pattern ที่มีความหมายเดียวกันในงวดเดียวกันใช้ Pool เดียวกัน
+	redisKey := lotteryPoolKey(draw.ID, pattern.Key())
+	// สร้าง Claim Token ของคำขอนี้ เพื่ออ่านกลับเฉพาะเลขที่คำขอนี้จองได้จริง
+	claim := newTicketClaim(userID, now, now.Add(ttl))
 
 	// 1. ใช้ค่าจาก redis ก่อน
-	// ใช้คำสั่ง SPopN เพื่อดึงหมายเลขออกมาแบบระบุจำนวนและรับประกันความเป็น Atomic (ใช้คนเดียวแน่นอน ดึงแล้วลบทันที)
-	ticketNumbers, err := r.redis.SPopN(ctx, redisKey, int64(limit)).Result()
-	if err == nil && len(ticketNumbers) > 0 {
-		for _, num := range ticketNumbers {
-			// เมื่อได้เลขจาก Redis แล้ว ต้องทำการอัปเดตสถานะใน MongoDB เพื่อยืนยันการจองจริง
-			filter := bson.M{"number": num, "status": domain.LotteryStatusAvailable}
-			update := bson.M{
-				"$set": bson.M{
-					"status":         domain.LotteryStatusReserved,
-					"reserved_by":    userID,
-					"reserved_until": reservedUntil,
-					"updated_at":     now,
-				},
-			}
-			// ใช้ FindOneAndUpdate เพื่อให้อัปเดตและดึงข้อมูลกลับมาในคำสั่งเดียวแบบ Atomic ป้องกันข้อมูลซ้ำซ้อน
-			opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-			var doc lotteryDoc
-			err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
-			if err == nil {
-				tickets = append(tickets, *doc.toLotteryDomain())
-			}
+	// ใช้คำสั่ง SPopN เพื่อดึงตั๋วออกมาแบบระบุจำนวนและรับประกันความเป็น Atomic (ใช้คนเดียวแน่นอน ดึงแล้วลบทันที)
+	// Pool เก็บ ID ของตั๋วแต่ละชุด ไม่ใช่เลข เพื่อให้หนึ่งสมาชิกเท่ากับตั๋วหนึ่งใบเสมอ
+	// ตั๋วที่ดึงออกมาจะถูกบันทึกเป็น In-flight ในคำสั่งเดียวกัน หากคำขอตายกลางทางจะถูกคืนเข้า Pool ภายหลัง
+	ticketIDs, err := r.popPool(ctx, redisKey, limit)
+	if err == nil && len(ticketIDs) > 0 {
+		// ยืนยันการจองใน MongoDB ด้วย UpdateMany ครั้งเดียวสำหรับทุกใบที่ได้จาก Redis
+		ids := make([]interface{}, len(ticketIDs))
+		for i, id := range ticketIDs {
+			ids[i] = lotteryIDFilter(id)
+		}
+		filter := bson.M{"draw_id": draw.ID, "_id": bson.M{"$in": ids}}
+		if _, err := r.claimTickets(ctx, filter, claim); err != nil {
+			// MongoDB ล้มเหลวหรือ Context ถูกยกเลิก UpdateMany อาจ Claim ไปแล้วบางใบ
+			// ให้ปล่อยใบที่ Claim ไว้ก่อน แล้วคืนตั๋วทั้งหมดกลับเข้า Pool เพื่อไม่ให้ Pool กับ MongoDB ไม่ตรงกัน
+			r.releaseClaim(ctx, claim)
+			r.restorePool(ctx, redisKey, ticketIDs)
+			return nil, err
 		}
+		// ตั๋วที่ไม่ถูก Claim แปลว่าไม่ว่างแล้วใน MongoDB จึงไม่ต้องคืนเข้า Pool
+		r.ackPool(ctx, redisKey, ticketIDs)
 	}
+	// นับสถิติการค้นหา: Pool ให้ตั๋วครบตามที่ขอถือว่า Hit ไม่เช่นนั้นต้องไปค้นใน MongoDB ถือว่า Miss
+	r.recordSearch(ctx, draw.ID, pattern.Key(), claim.count >= limit)
 
 	// 2. หากใน Redis มีเลขไม่พอ ให้ไปค้นหาโดยตรงจาก MongoDB
-	remaining := limit - len(tickets)
-	if remaining > 0 {
-		for i := 0; i < remaining; i++ {
-			// ค้นหาลอตเตอรี่ที่ตรงกับ Pattern และมีสถานะเป็น 'Available'
-			// หรือเป็นลอตเตอรี่ที่จองไว้แล้วแต่หมดเวลาการจอง
-			filter := bson.M{
-				"number": bson.M{"$regex": regexPattern},
-				"$or": []bson.M{
-					{"status": domain.LotteryStatusAvailable},
-					{
-						"status":         domain.LotteryStatusReserved,
-						"reserved_until": bson.M{"$lt": now},
-					},
-				},
-			}
-
-			update := bson.M{
-				"$set": bson.M{
-					"status":         domain.LotteryStatusReserved,
-					"reserved_by":    userID,
-					"reserved_until": reservedUntil,
-					"updated_at":     now,
-				},
-			}
+	// ค้นหาลอตเตอรี่ที่ตรงกับ Pattern และว่าง (หรือจองไว้แต่หมดเวลาแล้ว) ผ่าน Index ตำแหน่งตัวเลข
+	// แล้ว Claim ทั้งชุดด้วย UpdateMany หากมีผู้อื่นแย่งไปก่อนจะลองใหม่ไม่เกิน maxClaimAttempts รอบ
+	for attempt := 0; attempt < maxClaimAttempts && claim.count < limit; attempt++ {
+		ids, err := r.findClaimCandidates(ctx, draw.ID, pattern, now, limit-claim.count)
+		if err != nil {
+			r.releaseClaim(ctx, claim)
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
 
-			// ทำการจองทันทีที่ค้นพบเลขที่ตรงเงื่อนไขแบบ Atomic เพื่อป้องกัน Race Condition
-			opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-			var doc lotteryDoc
-			err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
-			if err != nil {
-				if err == mongo.ErrNoDocuments {
-					break
-				}
-				return nil, err
-			}
-			tickets = append(tickets, *doc.toLotteryDomain())
+		if _, err := r.claimTickets(ctx, bson.M{"_id": bson.M{"$in": ids}}, claim); err != nil {
+			r.releaseClaim(ctx, claim)
+			return nil, err
 		}
 	}
 
-	// 3. เรียกทำงานเบื้องหลัง (Background Goroutine) เพื่อเติมเลขเข้า Redis สำหรับการค้นหาครั้งต่อไป
-	// มันจะช่วยเพิ่มประสิทธิภาพ เมื่อมีผู้ใช้จำนวนมากค้นหาแบบเดิมพร้อมๆกัน
-	go r.prefillRedis(pattern, redisKey)
+	docs, err := r.readClaimDocs(ctx, claim)
+	if err != nil {
+		// ตั๋วถูก Claim แล้วแต่อ่านกลับไม่ได้ ต้องปล่อยคืน ไม่เช่นนั้นจะค้างอยู่กับผู้ใช้จนหมดเวลาจอง
+		r.releaseClaim(ctx, claim)
+		return nil, err
+	}
+	r.recordChanges(ctx, reserveChange(userID), docs...)
+
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+
+	// 3. ส่งงานเติมเลขเข้า Redis ให้ Worker เบื้องหลัง (ดู RunPrefillWorkers) สำหรับการค้นหาครั้งต่อไป
+	// Pattern เดียวกันจะเข้าคิวได้ครั้งละงานเดียว แม้มีผู้ใช้จำนวนมากค้นหาแบบเดิมพร้อมๆกัน
+	r.prefill.enqueue(draw.ID, pattern, redisKey)
 
 	return tickets, nil
 }
 
-func (r *LotteryRepository) prefillRedis(pattern string, redisKey string) {
-	// 1. ตรวจสอบปริมาณข้อมูลใน Redis Pool ของ Pattern นี้
-	// หากยังมีข้อมูลเหลือมากกว่า 50 รายการ หรือเกิดข้อผิดพลาด ให้หยุดการทำงาน (เพื่อประหยัดทรัพยากร)
-	count, err := r.redis.SCard(context.Background(), redisKey).Result()
-	if err != nil || count > 50 {
-		return
+// ticketClaim is one SearchAndReserve call's reservation, identified by a unique token
+type ticketClaim struct {
+	token         string
+	userID        string
+	now           time.Time
+	reservedUntil time.Time
+	count         int
+	// sent is set once an UpdateMany with the token was sent. A failed one may still have
+	// claimed some tickets, so count alone cannot tell whether there is anything to release.
+	sent bool
+}
+
+func newTicketClaim(userID string, now, reservedUntil time.Time) *ticketClaim {
+	return &ticketClaim{
+		token:         uuid.New().String(),
+		userID:        userID,
+		now:           now,
+		reservedUntil: reservedUntil,
+	}
+}
+
+// claimableFilter matches tickets that are available or whose reservation has expired
+func claimableFilter(now time.Time) bson.M {
+	return bson.M{
+		"$or": []bson.M{
+			{"status": domain.LotteryStatusAvailable},
+			{
+				"status":         domain.LotteryStatusReserved,
+				"reserved_until": bson.M{"$lt": now},
+			},
+		},
 	}
+}
 
-	ctx := context.Background()
-	regexPattern := r.patternToRegex(pattern)
+func (r *LotteryRepository) findClaimCandidates(ctx context.Context, drawID string, pattern *domain.LotteryPattern, now time.Time, limit int) ([]interface{}, error) {
+	return r.findClaimable(ctx, withPatternFilter(pattern, bson.M{"draw_id": drawID}), now, limit)
+}
+
+// findClaimable returns the IDs of up to limit claimable tickets matching filter
+func (r *LotteryRepository) findClaimable(ctx context.Context, filter bson.M, now time.Time, limit int) ([]interface{}, error) {
+	for k, v := range claimableFilter(now) {
+		filter[k] = v
+	}
+	opts := options.Find().SetLimit(int64(limit)).SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc["_id"]
+	}
+	return ids, nil
+}
+
+// claimTickets reserves every claimable ticket matching filter for the claim in a single UpdateMany.
+// The claimable condition is re-checked per document by the update itself, so a ticket taken by
+// another request in between is skipped instead of being handed out twice.
+func (r *LotteryRepository) claimTickets(ctx context.Context, filter bson.M, claim *ticketClaim) (int64, error) {
+	for k, v := range claimableFilter(claim.now) {
+		filter[k] = v
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          domain.LotteryStatusReserved,
+			"reserved_by":     claim.userID,
+			"reserved_until":  claim.reservedUntil,
+			"hold_extensions": 0,
+			"claim_token":     claim.token,
+			"updated_at":      claim.now,
+		},
+	}
+
+	claim.sent = true
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	claim.count += int(result.ModifiedCount)
+	return result.ModifiedCount, nil
+}
+
+func (r *LotteryRepository) readClaimDocs(ctx context.Context, claim *ticketClaim) ([]lotteryDoc, error) {
+	if claim.count == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"claim_token": claim.token})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// releaseClaim undoes a claim that could not be completed, putting its tickets back on sale.
+// It uses its own context so a cancelled request cannot leave the tickets held.
+func (r *LotteryRepository) releaseClaim(ctx context.Context, claim *ticketClaim) error {
+	if !claim.sent {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), poolRestoreTimeout)
+	defer cancel()
 
-	// 2. ดึงหมายเลขลอตเตอรี่ที่ว่าง (Available) จาก MongoDB เพื่อนำไปเติมใน Redis Pool
-	// ค้นหาโดยใช้ Regex และจำกัดจำนวนไว้ที่ 100 รายการ เพื่อไม่ให้โหลดข้อมูลหนักเกินไป
 	filter := bson.M{
-		"number": bson.M{"$regex": regexPattern},
-		"status": domain.LotteryStatusAvailable,
+		"claim_token": claim.token,
+		"reserved_by": claim.userID,
+		"status":      domain.LotteryStatusReserved,
 	}
-	// เลือกเฉพาะฟิลด์ "number" เท่านั้น เพื่อลดปริมาณข้อมูลที่รับส่ง
-	opts := options.Find().SetLimit(100).SetProjection(bson.M{"number": 1})
+	_, err := r.collection.UpdateMany(ctx, filter, releaseUpdate(time.Now()))
+	return err
+}
+
+// BrowseTickets lists tickets matching pattern in number and set order without reserving them.
+// Only copies after the cursor are returned, so the last copy of a page is its cursor.
+func (r *LotteryRepository) BrowseTickets(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error) {
+	filter := LotteryPatternFilter(pattern)
+	filter["draw_id"] = drawID
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pageFilter := LotteryPatternFilter(pattern)
+	pageFilter["draw_id"] = drawID
+	if after.Number != "" {
+		// ใช้ $and เพราะ filter อาจมีเงื่อนไข Regex ของ number อยู่แล้ว
+		next := []bson.M{{"number": bson.M{"$gt": after.Number}}}
+		if after.Set > 0 {
+			// ชุดที่เหลือของเลขเดียวกันที่ยังไม่ได้แสดงในหน้าก่อน
+			next = append(next, bson.M{"number": after.Number, "set": bson.M{"$gt": after.Set}})
+		}
+		pageFilter["$and"] = []bson.M{{"$or": next}}
+	}
+	// เรียงตามฟิลด์ตำแหน่งตัวเลขแล้วตามชุด ซึ่งให้ลำดับเดียวกับ number และใช้ Index ของการ Browse ได้
+	opts := options.Find().
+		SetSort(append(digitIndexKeys(), bson.E{Key: "set", Value: 1})).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, pageFilter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets, total, nil
+}
+
+func (r *LotteryRepository) prefillRedis(ctx context.Context, drawID string, pattern *domain.LotteryPattern, redisKey string) error {
+	// 1. ตรวจสอบปริมาณข้อมูลใน Redis Pool ของ Pattern นี้
+	// หากยังมีข้อมูลเหลือมากกว่า 50 รายการ ให้หยุดการทำงาน (เพื่อประหยัดทรัพยากร)
+	count, err := r.redis.SCard(ctx, redisKey).Result()
+	if err != nil {
+		return err
+	}
+	if count > 50 {
+		return nil
+	}
+
+	// 2. ดึงหมายเลขลอตเตอรี่ที่ว่าง (Available) จาก MongoDB เพื่อนำไปเติมใน Redis Pool
+	// ค้นหาผ่าน Index ตำแหน่งตัวเลข และจำกัดจำนวนไว้ที่ 100 รายการ เพื่อไม่ให้โหลดข้อมูลหนักเกินไป
+	filter := withPatternFilter(pattern, bson.M{
+		"draw_id": drawID,
+		"status":  domain.LotteryStatusAvailable,
+	})
+	// เลือกเฉพาะฟิลด์ "_id" เท่านั้น เพื่อลดปริมาณข้อมูลที่รับส่ง
+	opts := options.Find().SetLimit(100).SetProjection(bson.M{"_id": 1})
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return
+		return err
 	}
 	defer cursor.Close(ctx)
 
 	var docs []struct {
-		Number string `bson:"number"`
+		ID string `bson:"_id"`
 	}
 	if err := cursor.All(ctx, &docs); err != nil {
-		return
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
 	}
 
-	// 3. หากมีเลขที่ดึงได้ ให้นำไปเพิ่มลงใน Redis Set แบบเรียงลำดับ
-	if len(docs) > 0 {
-		members := make([]interface{}, len(docs))
-		for i, doc := range docs {
-			members[i] = doc.Number
-		}
-		// เพิ่มข้อมูลลงใน Redis Set (SAdd)
-		r.redis.SAdd(ctx, redisKey, members...)
-		r.redis.Expire(ctx, redisKey, 1*time.Hour) // @TODO: set env (1 ชั่วโมง)
+	// 3. นำ ID ของตั๋วที่ดึงได้ไปเพิ่มลงใน Redis Set พร้อมตั้งเวลาหมดอายุ
+	members := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		members[i] = doc.ID
 	}
+	pipe := r.redis.TxPipeline()
+	pipe.SAdd(ctx, redisKey, members...)
+	pipe.Expire(ctx, redisKey, 1*time.Hour) // @TODO: set env (1 ชั่วโมง)
+	// บันทึก Pattern ไว้ในทะเบียน เพื่อให้คืนเลขที่ถูกปล่อยกลับเข้า Pool ที่ตรงกันได้
+	pipe.SAdd(ctx, lotteryPoolRegistryKey(drawID), pattern.Key())
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 func (r *LotteryRepository) UpsertMany(ctx context.Context, tickets []domain.LotteryTicket) error {
@@ -180,13 +402,16 @@ func (r *LotteryRepository) UpsertMany(ctx context.Context, tickets []domain.Lot
 
 	for _, t := range tickets {
 		doc := fromLotteryDomain(&t)
+		if doc.Set == 0 {
+			doc.Set = 1
+		}
 		if doc.CreatedAt.IsZero() {
 			doc.CreatedAt = now
 		}
 		doc.UpdatedAt = now
 
 		model := mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"number": t.Number}).
+			SetFilter(bson.M{"draw_id": t.DrawID, "number": t.Number, "set": doc.Set}).
 			SetUpdate(bson.M{"$set": doc}).
 			SetUpsert(true)
 		models = append(models, model)
@@ -200,80 +425,729 @@ func (r *LotteryRepository) UpsertMany(ctx context.Context, tickets []domain.Lot
 	return err
 }
 
-func (r *LotteryRepository) MarkAsSold(ctx context.Context, ticketID string, userID string) error {
+func (r *LotteryRepository) MarkAsSold(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+	now := time.Now()
 	filter := bson.M{
-		"_id":         ticketID,
-		"reserved_by": userID,
-		"status":      domain.LotteryStatusReserved,
+		"_id":            lotteryIDFilter(ticketID),
+		"draw_id":        drawID,
+		"reserved_by":    userID,
+		"status":         domain.LotteryStatusReserved,
+		"reserved_until": bson.M{"$gte": now},
 	}
 	update := bson.M{
 		"$set": bson.M{
 			"status":     domain.LotteryStatusSold,
-			"updated_at": time.Now(),
+			"sold_at":    now,
+			"updated_at": now,
+		},
+		"$unset": bson.M{
+			"reserved_until": "",
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, filter, update)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc lotteryDoc
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if err == nil {
+		r.recordChanges(ctx, ticketChange{
+			eventType: domain.LotteryEventSold,
+			actor:     userID,
+			from:      domain.LotteryStatusReserved,
+			to:        domain.LotteryStatusSold,
+		}, doc)
+		return doc.toLotteryDomain(), nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// ไม่พบเอกสารที่ตรงเงื่อนไข ให้อ่านสถานะปัจจุบันเพื่อระบุสาเหตุที่ซื้อไม่สำเร็จ
+	return nil, r.purchaseFailureReason(ctx, ticketID, userID, drawID, now)
+}
+
+func (r *LotteryRepository) purchaseFailureReason(ctx context.Context, ticketID string, userID string, drawID string, now time.Time) error {
+	doc, err := r.findByID(ctx, ticketID)
 	if err != nil {
 		return err
 	}
 
-	if result.MatchedCount == 0 {
-		return fmt.Errorf("ticket not found or not reserved by user")
+	switch {
+	case doc.DrawID != drawID:
+		// ตั๋วของงวดอื่นไม่ได้อยู่ในงวดที่ขออยู่ จึงถือว่าไม่พบ
+		return domain.ErrTicketNotFound
+	case doc.Status == domain.LotteryStatusSold:
+		return domain.ErrTicketAlreadySold
+	case doc.Status == domain.LotteryStatusReserved && doc.ReservedBy == userID &&
+		doc.ReservedUntil != nil && doc.ReservedUntil.Before(now):
+		return domain.ErrReservationExpired
+	default:
+		return domain.ErrTicketNotReserved
 	}
+}
 
-	return nil
+// lotteryIDFilter converts a hex ticket ID into an ObjectID since seeded documents use generated ObjectIDs
+func lotteryIDFilter(id string) interface{} {
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		return oid
+	}
+	return id
 }
 
-func (r *LotteryRepository) Count(ctx context.Context) (int64, error) {
-	return r.collection.CountDocuments(ctx, bson.M{})
+// ReserveNumber reserves copies copies of one number for userID, taking over expired holds.
+// Either every requested copy is reserved or none is.
+func (r *LotteryRepository) ReserveNumber(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+	now := time.Now()
+	claim := newTicketClaim(userID, now, now.Add(ttl))
+
+	// ค้นหาชุดที่ว่างของเลขนี้แล้ว Claim ด้วย UpdateMany หากมีผู้อื่นแย่งไปก่อนจะลองใหม่ไม่เกิน maxClaimAttempts รอบ
+	for attempt := 0; attempt < maxClaimAttempts && claim.count < copies; attempt++ {
+		ids, err := r.findClaimable(ctx, bson.M{"draw_id": drawID, "number": number}, now, copies-claim.count)
+		if err != nil {
+			r.releaseClaim(ctx, claim)
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		if _, err := r.claimTickets(ctx, bson.M{"_id": bson.M{"$in": ids}}, claim); err != nil {
+			r.releaseClaim(ctx, claim)
+			return nil, err
+		}
+	}
+
+	if claim.count < copies {
+		// ได้ไม่ครบตามจำนวนที่ขอ ให้ปล่อยชุดที่ Claim ไว้แล้วคืน และแจ้งสาเหตุ
+		if err := r.releaseClaim(ctx, claim); err != nil {
+			return nil, err
+		}
+		return nil, r.reserveFailureReason(ctx, drawID, number, userID, now)
+	}
+
+	docs, err := r.readClaimDocs(ctx, claim)
+	if err != nil {
+		r.releaseClaim(ctx, claim)
+		return nil, err
+	}
+	r.removeFromPools(ctx, drawID, docs)
+	r.recordChanges(ctx, reserveChange(userID), docs...)
+
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets, nil
+}
+
+// ReserveSet reserves every copy of a number for userID. Copies the user already holds count
+// towards the set; if any other copy is sold or held by someone else nothing is reserved.
+func (r *LotteryRepository) ReserveSet(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error) {
+	now := time.Now()
+	claim := newTicketClaim(userID, now, now.Add(ttl))
+	filter := bson.M{"draw_id": drawID, "number": number}
+
+	// แต่ละเลขมีไม่กี่ชุด จึง Claim ทุกชุดที่ว่างในคำสั่งเดียว (claimTickets เพิ่มเงื่อนไขลงใน Filter ที่ส่งไป จึงสร้างใหม่)
+	if _, err := r.claimTickets(ctx, bson.M{"draw_id": drawID, "number": number}, claim); err != nil {
+		r.releaseClaim(ctx, claim)
+		return nil, err
+	}
+
+	// ตรวจว่าทุกชุดของเลขนี้อยู่กับผู้ใช้แล้ว หากมีชุดที่ขายไปหรือผู้อื่นถืออยู่ให้ยกเลิกทั้งหมด
+	var docs []lotteryDoc
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "set", Value: 1}}))
+	if err == nil {
+		err = cursor.All(ctx, &docs)
+	}
+	if err != nil {
+		r.releaseClaim(ctx, claim)
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, domain.ErrTicketNotFound
+	}
+
+	for _, doc := range docs {
+		held := doc.Status == domain.LotteryStatusReserved && doc.ReservedBy == userID &&
+			doc.ReservedUntil != nil && !doc.ReservedUntil.Before(now)
+		if !held {
+			if err := r.releaseClaim(ctx, claim); err != nil {
+				return nil, err
+			}
+			return nil, domain.ErrSetUnavailable
+		}
+	}
+
+	r.removeFromPools(ctx, drawID, docs)
+	// บันทึกเฉพาะชุดที่คำขอนี้จองได้ ชุดที่ผู้ใช้ถืออยู่ก่อนแล้วถูกบันทึกไปตอนจองครั้งนั้น
+	claimed := make([]lotteryDoc, 0, len(docs))
+	for _, doc := range docs {
+		if doc.ClaimToken == claim.token {
+			claimed = append(claimed, doc)
+		}
+	}
+	r.recordChanges(ctx, reserveChange(userID), claimed...)
+
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets, nil
 }
 
-func (r *LotteryRepository) SeedTickets(ctx context.Context, total int) error {
-	count, err := r.Count(ctx)
+// reserveFailureReason reads the copies of a number to explain why none could be reserved
+func (r *LotteryRepository) reserveFailureReason(ctx context.Context, drawID string, number string, userID string, now time.Time) error {
+	opts := options.Find().SetProjection(bson.M{"status": 1, "reserved_by": 1, "reserved_until": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"draw_id": drawID, "number": number}, opts)
 	if err != nil {
 		return err
 	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return domain.ErrTicketNotFound
+	}
+
+	sold, claimable, heldByOthers := 0, 0, 0
+	for _, doc := range docs {
+		switch {
+		case doc.Status == domain.LotteryStatusSold:
+			sold++
+		case doc.Status == domain.LotteryStatusAvailable ||
+			(doc.ReservedUntil != nil && doc.ReservedUntil.Before(now)):
+			claimable++
+		case doc.ReservedBy != userID:
+			heldByOthers++
+		}
+	}
+
+	switch {
+	case sold == len(docs):
+		return domain.ErrTicketAlreadySold
+	case claimable == 0 && heldByOthers == len(docs)-sold:
+		return domain.ErrTicketReserved
+	default:
+		// มีชุดว่างอยู่บ้าง หรือผู้ใช้ถือชุดที่เหลือไว้เองแล้ว แต่ไม่พอตามจำนวนที่ขอ
+		return domain.ErrNotEnoughCopies
+	}
+}
+
+// ExtendReservations pushes back the expiry of every active hold owned by userID that
+// has been extended fewer than maxExtensions times.
+func (r *LotteryRepository) ExtendReservations(ctx context.Context, userID string, extension time.Duration, maxExtensions int) (int64, error) {
+	now := time.Now()
+	filter := bson.M{
+		"reserved_by":    userID,
+		"status":         domain.LotteryStatusReserved,
+		"reserved_until": bson.M{"$gte": now},
+		"$or": []bson.M{
+			{"hold_extensions": bson.M{"$exists": false}},
+			{"hold_extensions": bson.M{"$lt": maxExtensions}},
+		},
+	}
+	// ใช้ Update Pipeline เพื่อบวกเวลาจาก reserved_until เดิมของแต่ละใบในคำสั่งเดียว
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"reserved_until":  bson.M{"$add": bson.A{"$reserved_until", extension.Milliseconds()}},
+			"hold_extensions": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$hold_extensions", 0}}, 1}},
+			"updated_at":      now,
+		}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// HoldUntil keeps the user's active holds on ticketIDs in the draw until at least until, so they
+// outlive an order's payment window. Either every ticket is held or none is extended: when one has
+// been lost, the others keep their previous expiry and the error says why the lost one failed.
+func (r *LotteryRepository) HoldUntil(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error) {
+	ids := make([]interface{}, len(ticketIDs))
+	for i, id := range ticketIDs {
+		ids[i] = lotteryIDFilter(id)
+	}
+	now := time.Now()
+	filter := bson.M{
+		"_id":            bson.M{"$in": ids},
+		"draw_id":        drawID,
+		"reserved_by":    userID,
+		"status":         domain.LotteryStatusReserved,
+		"reserved_until": bson.M{"$gte": now},
+	}
 
-	if count >= int64(total) {
-		fmt.Printf("Data already exists (%d tickets). Skipping seed.\n", count)
+	// อ่านเวลาหมดอายุเดิมไว้ก่อน เพื่อคืนค่าได้หากยืดเวลาได้ไม่ครบทุกใบ
+	held, err := r.findDocs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(held) != len(ticketIDs) {
+		return nil, r.holdFailureReason(ctx, drawID, ticketIDs, held, userID, now)
+	}
+
+	// ใช้ $max เพื่อไม่ให้เวลาหมดอายุที่ยาวกว่าอยู่แล้วสั้นลง
+	update := bson.M{
+		"$max": bson.M{"reserved_until": until},
+		"$set": bson.M{"updated_at": now},
+	}
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		// UpdateMany อาจยืดเวลาไปแล้วบางใบ
+		r.RestoreHolds(ctx, docsToTickets(held), userID)
+		return nil, err
+	}
+	if result.MatchedCount != int64(len(ticketIDs)) {
+		// บางใบหมดเวลาระหว่างอ่านกับยืดเวลา คืนเวลาเดิมให้ใบที่ถูกยืดไปแล้ว
+		r.RestoreHolds(ctx, docsToTickets(held), userID)
+		return nil, r.holdFailureReason(ctx, drawID, ticketIDs, nil, userID, now)
+	}
+
+	docs, err := r.findDocs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return docsToTickets(docs), nil
+}
+
+// holdFailureReason explains why the first of ticketIDs missing from held is no longer held by the user
+func (r *LotteryRepository) holdFailureReason(ctx context.Context, drawID string, ticketIDs []string, held []lotteryDoc, userID string, now time.Time) error {
+	found := make(map[string]bool, len(held))
+	for _, doc := range held {
+		found[doc.ID] = true
+	}
+	for _, id := range ticketIDs {
+		if found[id] {
+			continue
+		}
+		if err := r.purchaseFailureReason(ctx, id, userID, drawID, now); !errors.Is(err, domain.ErrTicketNotReserved) {
+			return err
+		}
+		// ใบนี้ยังถืออยู่ หรือหลุดไปโดยไม่มีสาเหตุเฉพาะ ดูใบถัดไปก่อน
+	}
+	return domain.ErrTicketNotReserved
+}
+
+// RestoreHolds puts back the expiry the user's holds on tickets had before they were extended,
+// taking it from each ticket's ReservedUntil. Holds the user has lost since are left alone.
+func (r *LotteryRepository) RestoreHolds(ctx context.Context, tickets []domain.LotteryTicket, userID string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), poolRestoreTimeout)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(tickets))
+	for _, ticket := range tickets {
+		if ticket.ReservedUntil == nil {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{
+				"_id":         lotteryIDFilter(ticket.ID),
+				"reserved_by": userID,
+				"status":      domain.LotteryStatusReserved,
+			}).
+			SetUpdate(bson.M{"$set": bson.M{"reserved_until": *ticket.ReservedUntil}}))
+	}
+	if len(models) == 0 {
 		return nil
 	}
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func docsToTickets(docs []lotteryDoc) []domain.LotteryTicket {
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets
+}
+
+func (r *LotteryRepository) FindReservationsByUser(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+	filter := bson.M{
+		"reserved_by":    userID,
+		"status":         domain.LotteryStatusReserved,
+		"reserved_until": bson.M{"$gte": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "reserved_until", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("Seeding %d lottery tickets...\n", total)
-	startTime := time.Now()
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets, nil
+}
 
-	batchSize := 10000
-	for i := 0; i < total; i += batchSize {
-		tickets := make([]domain.LotteryTicket, 0, batchSize)
-		for j := 0; j < batchSize; j++ {
-			num := i + j
-			if num >= total {
-				break
+// FindSoldByUser returns the tickets the user bought for a draw
+func (r *LotteryRepository) FindSoldByUser(ctx context.Context, userID string, drawID string) ([]domain.LotteryTicket, error) {
+	filter := bson.M{
+		"draw_id":     drawID,
+		"reserved_by": userID,
+		"status":      domain.LotteryStatusSold,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: 1}, {Key: "set", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	tickets := make([]domain.LotteryTicket, len(docs))
+	for i := range docs {
+		tickets[i] = *docs[i].toLotteryDomain()
+	}
+	return tickets, nil
+}
+
+func (r *LotteryRepository) ReleaseReservation(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+	filter := bson.M{
+		"_id":         lotteryIDFilter(ticketID),
+		"reserved_by": userID,
+		"status":      domain.LotteryStatusReserved,
+	}
+
+	doc, err := r.release(ctx, filter)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if _, err := r.findByID(ctx, ticketID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrTicketNotReserved
+	}
+
+	r.returnToPools(ctx, []lotteryDoc{*doc})
+	r.recordChanges(ctx, releaseChange(userID), *doc)
+	return doc.toLotteryDomain(), nil
+}
+
+// ReleaseAllReservations puts every hold of userID back on sale in one UpdateMany and reads the
+// released tickets back in one Find, however many the user holds.
+func (r *LotteryRepository) ReleaseAllReservations(ctx context.Context, userID string) (int64, error) {
+	now := time.Now()
+	filter := bson.M{
+		"reserved_by": userID,
+		"status":      domain.LotteryStatusReserved,
+	}
+
+	// ติด Token ให้ใบที่ถูกปล่อยเหมือนตอน Claim เพื่ออ่านกลับเฉพาะใบที่คำขอนี้ปล่อยจริง
+	// ใบที่ถูกจองใหม่ระหว่างทางจะได้ Token ของผู้จองใหม่แทน จึงไม่ถูกคืนเข้า Pool
+	token := uuid.New().String()
+	update := releaseUpdate(now)
+	update["$set"].(bson.M)["claim_token"] = token
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err == nil && result.ModifiedCount == 0 {
+		return 0, nil
+	}
+
+	// UpdateMany ที่ล้มเหลวอาจปล่อยไปแล้วบางใบ จึงอ่านกลับด้วย Token เสมอ
+	released, findErr := r.findDocs(ctx, bson.M{"claim_token": token, "status": domain.LotteryStatusAvailable})
+	r.returnToPools(ctx, released)
+	r.recordChanges(ctx, releaseChange(userID), released...)
+	if err != nil {
+		return int64(len(released)), err
+	}
+	if findErr != nil {
+		return result.ModifiedCount, findErr
+	}
+	return result.ModifiedCount, nil
+}
+
+// ReleaseExpiredReservations moves reservations whose hold has expired back to available
+// and returns their numbers, plus any pool numbers stuck in flight, to the cached pattern pools.
+func (r *LotteryRepository) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	const batchSize = 1000
+	var total int64
+
+	// คืนเลขที่ค้างอยู่ใน In-flight จากคำขอที่ตายกลางทางกลับเข้า Pool
+	if _, err := r.recoverInflight(ctx); err != nil {
+		return 0, err
+	}
+
+	for {
+		now := time.Now()
+		filter := bson.M{
+			"status":         domain.LotteryStatusReserved,
+			"reserved_until": bson.M{"$lt": now},
+		}
+		opts := options.Find().SetLimit(batchSize).SetProjection(bson.M{"number": 1, "set": 1, "draw_id": 1})
+		cursor, err := r.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return total, err
+		}
+
+		var docs []lotteryDoc
+		if err := cursor.All(ctx, &docs); err != nil {
+			return total, err
+		}
+		if len(docs) == 0 {
+			return total, nil
+		}
+
+		ids := make([]interface{}, len(docs))
+		for i, doc := range docs {
+			ids[i] = lotteryIDFilter(doc.ID)
+		}
+
+		// ใส่เงื่อนไขหมดอายุซ้ำ เพื่อไม่ให้ปล่อยเลขที่ถูกจองใหม่ระหว่างการค้นหาและการอัปเดต
+		filter["_id"] = bson.M{"$in": ids}
+		result, err := r.collection.UpdateMany(ctx, filter, releaseUpdate(now))
+		if err != nil {
+			return total, err
+		}
+		total += result.ModifiedCount
+
+		// เลขที่อาจถูกจองใหม่ระหว่างทางจะถูกตรวจสถานะใน MongoDB อีกครั้งตอน SearchAndReserve จึงคืนเข้า Pool ได้อย่างปลอดภัย
+		r.returnToPools(ctx, docs)
+
+		expired := docs
+		if result.ModifiedCount < int64(len(docs)) {
+			// บางใบถูกจองใหม่ก่อนจะถูกปล่อย อ่านกลับเฉพาะใบที่ถูกปล่อยในรอบนี้เพื่อบันทึกประวัติให้ถูกต้อง
+			expired, err = r.findReleasedAt(ctx, ids, now)
+			if err != nil {
+				return total, err
 			}
-			ticketNumber := fmt.Sprintf("%06d", num)
-			tickets = append(tickets, domain.LotteryTicket{
-				Number:    ticketNumber,
-				Status:    domain.LotteryStatusAvailable,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			})
 		}
+		r.recordChanges(ctx, ticketChange{
+			eventType: domain.LotteryEventExpired,
+			actor:     domain.LotteryActorSystem,
+			from:      domain.LotteryStatusReserved,
+			to:        domain.LotteryStatusAvailable,
+		}, expired...)
+
+		if len(docs) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// findReleasedAt returns the tickets among ids that releaseUpdate(now) put back on sale
+func (r *LotteryRepository) findReleasedAt(ctx context.Context, ids []interface{}, now time.Time) ([]lotteryDoc, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$in": ids},
+		"status":     domain.LotteryStatusAvailable,
+		"updated_at": now,
+	}
+	opts := options.Find().SetProjection(bson.M{"number": 1, "set": 1, "draw_id": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func reserveChange(userID string) ticketChange {
+	// การจองที่หมดเวลาแล้วถือว่าว่าง (ดู LotteryTicket.IsAvailable) จึงบันทึกว่าจองจากสถานะว่างเสมอ
+	return ticketChange{
+		eventType: domain.LotteryEventReserved,
+		actor:     userID,
+		from:      domain.LotteryStatusAvailable,
+		to:        domain.LotteryStatusReserved,
+	}
+}
+
+func releaseChange(userID string) ticketChange {
+	return ticketChange{
+		eventType: domain.LotteryEventReleased,
+		actor:     userID,
+		from:      domain.LotteryStatusReserved,
+		to:        domain.LotteryStatusAvailable,
+	}
+}
+
+// releaseUpdate puts a reserved ticket back on sale
+func releaseUpdate(now time.Time) bson.M {
+	return bson.M{
+		"$set": bson.M{
+			"status":     domain.LotteryStatusAvailable,
+			"updated_at": now,
+		},
+		"$unset": bson.M{
+			"reserved_by":     "",
+			"reserved_until":  "",
+			"hold_extensions": "",
+		},
+	}
+}
+
+func (r *LotteryRepository) release(ctx context.Context, filter bson.M) (*lotteryDoc, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc lotteryDoc
+	if err := r.collection.FindOneAndUpdate(ctx, filter, releaseUpdate(time.Now()), opts).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *LotteryRepository) findDocs(ctx context.Context, filter bson.M) ([]lotteryDoc, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lotteryDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *LotteryRepository) findByID(ctx context.Context, ticketID string) (*lotteryDoc, error) {
+	var doc lotteryDoc
+	err := r.collection.FindOne(ctx, bson.M{"_id": lotteryIDFilter(ticketID)}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrTicketNotFound
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// returnToPools adds released tickets back into every cached pattern pool of their draw round
+// they match, so other users can pick them up without waiting for a Mongo fallback search.
+func (r *LotteryRepository) returnToPools(ctx context.Context, docs []lotteryDoc) {
+	byDraw := make(map[string][]lotteryDoc)
+	for _, doc := range docs {
+		byDraw[doc.DrawID] = append(byDraw[doc.DrawID], doc)
+	}
 
-		err := r.UpsertMany(ctx, tickets)
+	for drawID, released := range byDraw {
+		registryKey := lotteryPoolRegistryKey(drawID)
+		patterns, err := r.redis.SMembers(ctx, registryKey).Result()
 		if err != nil {
-			return fmt.Errorf("failed to seed batch: %w", err)
+			continue
+		}
+
+		for _, key := range patterns {
+			redisKey := lotteryPoolKey(drawID, key)
+
+			// Pool ที่หมดอายุไปแล้วจะถูกเติมใหม่โดย prefillRedis จึงลบออกจากทะเบียนแทนการสร้าง Pool ใหม่ที่ไม่มี TTL
+			exists, err := r.redis.Exists(ctx, redisKey).Result()
+			if err != nil {
+				continue
+			}
+			if exists == 0 {
+				r.redis.SRem(ctx, registryKey, key)
+				continue
+			}
+
+			pattern, err := domain.ParseLotteryPattern(key)
+			if err != nil {
+				r.redis.SRem(ctx, registryKey, key)
+				continue
+			}
+
+			members := make([]interface{}, 0, len(released))
+			for _, doc := range released {
+				if pattern.Matches(doc.Number) {
+					members = append(members, doc.ID)
+				}
+			}
+			if len(members) > 0 {
+				r.redis.SAdd(ctx, redisKey, members...)
+			}
 		}
+	}
+}
+
+// removeFromPools drops tickets that were reserved directly from every cached pool of their draw round.
+func (r *LotteryRepository) removeFromPools(ctx context.Context, drawID string, docs []lotteryDoc) {
+	if len(docs) == 0 {
+		return
+	}
+	patterns, err := r.redis.SMembers(ctx, lotteryPoolRegistryKey(drawID)).Result()
+	if err != nil {
+		return
+	}
 
-		if (i+batchSize)%50000 == 0 || i+batchSize >= total {
-			current := i + batchSize
-			if current > total {
-				current = total
+	for _, key := range patterns {
+		pattern, err := domain.ParseLotteryPattern(key)
+		if err != nil {
+			continue
+		}
+		members := make([]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			if pattern.Matches(doc.Number) {
+				members = append(members, doc.ID)
 			}
-			fmt.Printf("Seeded %d/%d tickets...\n", current, total)
 		}
+		if len(members) > 0 {
+			r.redis.SRem(ctx, lotteryPoolKey(drawID, key), members...)
+		}
+	}
+}
+
+func (r *LotteryRepository) Count(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+// AssignUnscopedTickets moves tickets created before inventories were scoped to a draw round
+// into the given round. They become the first set of their number.
+func (r *LotteryRepository) AssignUnscopedTickets(ctx context.Context, drawID string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"draw_id": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"draw_id": drawID, "set": 1}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// MigrateLegacyIndexes drops the unique number index of a collection created before draw rounds.
+// It does nothing once the index is gone, and reports whether it dropped it.
+func (r *LotteryRepository) MigrateLegacyIndexes(ctx context.Context) (bool, error) {
+	names, err := r.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list lottery indexes: %w", err)
+	}
+	for _, spec := range names {
+		if spec.Name != legacyNumberIndex {
+			continue
+		}
+		if _, err := r.collection.Indexes().DropOne(ctx, legacyNumberIndex); err != nil {
+			return false, fmt.Errorf("failed to drop lottery index %s: %w", legacyNumberIndex, err)
+		}
+		return true, nil
 	}
+	return false, nil
+}
 
-	fmt.Printf("Successfully seeded %d lottery tickets in %v\n", total, time.Since(startTime))
-	return nil
+// MigrateTicketSets marks tickets stocked before a round could hold several copies of a number
+// as the first set of their number.
+func (r *LotteryRepository) MigrateTicketSets(ctx context.Context) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"set": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"set": 1}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate lottery ticket sets: %w", err)
+	}
+	return result.ModifiedCount, nil
 }
@@ -0,0 +1,187 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// seedLogInterval is how many tickets are written between progress log lines
+const seedLogInterval = 50000
+
+// seedProgressDoc records how far a seed plan got. Next is the first number not yet written.
+type seedProgressDoc struct {
+	ID          string     `bson:"_id"`
+	DrawID      string     `bson:"draw_id"`
+	From        int        `bson:"from"`
+	To          int        `bson:"to"`
+	Sets        int        `bson:"sets"`
+	Next        int        `bson:"next"`
+	StartedAt   time.Time  `bson:"started_at"`
+	UpdatedAt   time.Time  `bson:"updated_at"`
+	CompletedAt *time.Time `bson:"completed_at,omitempty"`
+}
+
+// seedProgressID identifies a plan, so the same range of the same round resumes its own progress
+func seedProgressID(plan domain.TicketSeedPlan) string {
+	return fmt.Sprintf("%s:%d-%d:%d", plan.DrawID, plan.From, plan.To, plan.Sets)
+}
+
+// SeedTickets stocks a draw round with plan.Sets copies of every number in the plan's range.
+// Progress is recorded after every batch, so a seed that was stopped resumes from its last
+// batch instead of starting over. Existing tickets are never overwritten.
+func (r *LotteryRepository) SeedTickets(ctx context.Context, plan domain.TicketSeedPlan) error {
+	if err := plan.Validate(); err != nil {
+		return err
+	}
+
+	id := seedProgressID(plan)
+	if plan.Restart {
+		if _, err := r.seeds.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			return err
+		}
+	}
+
+	progress, err := r.loadSeedProgress(ctx, plan)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{
+		"draw_id": plan.DrawID,
+		"from":    plan.From,
+		"to":      plan.To,
+		"sets":    plan.Sets,
+	}
+	if progress.CompletedAt != nil {
+		logger.Info("Lottery tickets already seeded, skipping", fields)
+		return nil
+	}
+	if progress.Next > plan.From {
+		fields["next"] = progress.Next
+		logger.Info("Resuming lottery ticket seed", fields)
+	} else {
+		logger.Info("Seeding lottery tickets", fields)
+	}
+
+	startTime := time.Now()
+	copies := plan.Copies()
+	step := plan.NumbersPerBatch()
+	for next := progress.Next; next < plan.To; {
+		end := next + step
+		if end > plan.To {
+			end = plan.To
+		}
+
+		now := time.Now()
+		models := make([]mongo.WriteModel, 0, (end-next)*plan.Sets)
+		for n := next; n < end; n++ {
+			// เขียนทุกชุดของเลขเดียวกันในชุดคำสั่งเดียวกัน เพื่อให้ Progress นับเป็นเลขได้
+			for set := 1; set <= plan.Sets; set++ {
+				doc := fromLotteryDomain(&domain.LotteryTicket{
+					Number:    fmt.Sprintf("%06d", n),
+					Set:       set,
+					Status:    domain.LotteryStatusAvailable,
+					DrawID:    plan.DrawID,
+					CreatedAt: now,
+					UpdatedAt: now,
+				})
+				// ใช้ $setOnInsert เพื่อไม่ทับสถานะของตั๋วที่ถูกจองหรือขายไปแล้ว
+				models = append(models, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"draw_id": plan.DrawID, "number": doc.Number, "set": doc.Set}).
+					SetUpdate(bson.M{"$setOnInsert": doc}).
+					SetUpsert(true))
+			}
+		}
+
+		if _, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to seed batch from %06d: %w", next, err)
+		}
+		// บันทึกหลังเขียนสำเร็จเท่านั้น หากหยุดกลางทางจะเริ่มใหม่จากชุดที่ยังไม่สำเร็จ
+		if _, err := r.seeds.UpdateByID(ctx, id, bson.M{"$set": bson.M{"next": end, "updated_at": now}}); err != nil {
+			return fmt.Errorf("failed to record seed progress: %w", err)
+		}
+
+		seeded := (end - plan.From) * plan.Sets
+		if seeded/seedLogInterval > (next-plan.From)*plan.Sets/seedLogInterval || end == plan.To {
+			logger.Info("Seeded lottery tickets", map[string]interface{}{
+				"draw_id": plan.DrawID,
+				"seeded":  seeded,
+				"total":   copies,
+			})
+		}
+		next = end
+	}
+
+	completedAt := time.Now()
+	if _, err := r.seeds.UpdateByID(ctx, id, bson.M{"$set": bson.M{"completed_at": completedAt, "updated_at": completedAt}}); err != nil {
+		return fmt.Errorf("failed to record seed progress: %w", err)
+	}
+
+	logger.Info("Successfully seeded lottery tickets", map[string]interface{}{
+		"draw_id":  plan.DrawID,
+		"total":    copies,
+		"duration": time.Since(startTime).String(),
+	})
+	return nil
+}
+
+// loadSeedProgress returns the recorded progress of a plan, starting a new record if there is none
+func (r *LotteryRepository) loadSeedProgress(ctx context.Context, plan domain.TicketSeedPlan) (*seedProgressDoc, error) {
+	id := seedProgressID(plan)
+	var progress seedProgressDoc
+	err := r.seeds.FindOne(ctx, bson.M{"_id": id}).Decode(&progress)
+	if err == nil {
+		return &progress, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	now := time.Now()
+	progress = seedProgressDoc{
+		ID:        id,
+		DrawID:    plan.DrawID,
+		From:      plan.From,
+		To:        plan.To,
+		Sets:      plan.Sets,
+		Next:      plan.From,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	// งวดที่ถูก Seed ก่อนมีการบันทึก Progress ถือว่าเสร็จแล้วหากมีตั๋วครบทุกใบในช่วง
+	count, err := r.collection.CountDocuments(ctx, seedRangeFilter(plan))
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(plan.Copies()) {
+		progress.Next = plan.To
+		progress.CompletedAt = &now
+	}
+
+	if _, err := r.seeds.InsertOne(ctx, &progress); err != nil {
+		// อีก Process เริ่ม Plan เดียวกันไปก่อน ใช้ Progress ของ Process นั้นแทน
+		if mongo.IsDuplicateKeyError(err) {
+			return r.loadSeedProgress(ctx, plan)
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// seedRangeFilter matches the tickets of a plan's draw round within its number range
+func seedRangeFilter(plan domain.TicketSeedPlan) bson.M {
+	number := bson.M{"$gte": fmt.Sprintf("%06d", plan.From)}
+	// เลขเก็บเป็นข้อความหกหลัก ขอบบนที่เกิน 999999 จึงไม่ต้องใส่
+	if plan.To < domain.LotteryNumberSpace {
+		number["$lt"] = fmt.Sprintf("%06d", plan.To)
+	}
+	return bson.M{"draw_id": plan.DrawID, "number": number}
+}
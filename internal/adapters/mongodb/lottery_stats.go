@@ -0,0 +1,219 @@
+package mongodb
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// maxTrackedPatterns bounds the patterns tracked per round; the ones searched least recently
+	// are dropped first, so a new pattern is never evicted in favour of a stale one
+	maxTrackedPatterns = 1000
+	// lotteryStatsTTL drops the counters of a round once it has not been searched for this long
+	lotteryStatsTTL = 30 * 24 * time.Hour
+)
+
+// Search statistics are kept per draw round in Redis:
+//
+//	lottery_stats:<drawId>:patterns  sorted set of normalized patterns scored by searches
+//	lottery_stats:<drawId>:seen      sorted set of the same patterns scored by last search (ms)
+//	lottery_stats:<drawId>:pool      hash counting searches the pattern pools served (hits, misses)
+func lotteryStatsKey(drawID, name string) string {
+	return "lottery_stats:" + drawID + ":" + name
+}
+
+// recordSearchScript counts a search and evicts the least recently searched patterns over the limit.
+//
+// KEYS[1] patterns key, KEYS[2] seen key, KEYS[3] pool key,
+// ARGV[1] pattern, ARGV[2] now (ms), ARGV[3] max patterns, ARGV[4] pool field, ARGV[5] ttl (s)
+var recordSearchScript = redis.NewScript(`
+redis.call('ZINCRBY', KEYS[1], 1, ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+local excess = redis.call('ZCARD', KEYS[2]) - tonumber(ARGV[3])
+if excess > 0 then
+	local stale = redis.call('ZRANGE', KEYS[2], 0, excess - 1)
+	redis.call('ZREM', KEYS[1], unpack(stale))
+	redis.call('ZREM', KEYS[2], unpack(stale))
+end
+redis.call('HINCRBY', KEYS[3], ARGV[4], 1)
+for _, key in ipairs(KEYS) do
+	redis.call('EXPIRE', key, ARGV[5])
+end
+return 1
+`)
+
+// recordSearch counts a search of patternKey in the draw round and whether the pattern pool
+// served it. Statistics are best effort, so failures are ignored.
+func (r *LotteryRepository) recordSearch(ctx context.Context, drawID string, patternKey string, poolHit bool) {
+	field := "misses"
+	if poolHit {
+		field = "hits"
+	}
+
+	keys := []string{
+		lotteryStatsKey(drawID, "patterns"),
+		lotteryStatsKey(drawID, "seen"),
+		lotteryStatsKey(drawID, "pool"),
+	}
+	recordSearchScript.Run(ctx, r.redis, keys, patternKey, time.Now().UnixMilli(), maxTrackedPatterns, field,
+		int64(lotteryStatsTTL/time.Second))
+}
+
+// Stats counts the tickets of a draw round (or of every round when drawID is empty) by status
+// and sales per hour since salesSince. For a single round it also reads the round's hottest
+// patterns and pool counters from Redis.
+func (r *LotteryRepository) Stats(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error) {
+	now := time.Now()
+	match := bson.M{}
+	if drawID != "" {
+		match["draw_id"] = drawID
+	}
+
+	stats := &domain.LotteryStats{
+		DrawID:      drawID,
+		ByStatus:    make(map[domain.LotteryStatus]int64),
+		SalesSince:  salesSince,
+		GeneratedAt: now,
+	}
+	if err := r.countByStatus(ctx, match, now, stats); err != nil {
+		return nil, err
+	}
+
+	sales, err := r.salesPerHour(ctx, match, salesSince)
+	if err != nil {
+		return nil, err
+	}
+	stats.SalesPerHour = sales
+
+	// สถิติการค้นหาเก็บแยกตามงวด จึงไม่มีเมื่อขอสถิติรวมทุกงวด
+	if drawID == "" {
+		return stats, nil
+	}
+	// ตัวเลขจาก Redis เป็นข้อมูลประกอบ หาก Redis มีปัญหายังคืนตัวเลขจาก MongoDB ได้
+	if hotPatterns > 0 {
+		patterns, err := r.redis.ZRevRangeWithScores(ctx, lotteryStatsKey(drawID, "patterns"), 0, int64(hotPatterns)-1).Result()
+		if err == nil {
+			for _, z := range patterns {
+				if key, ok := z.Member.(string); ok {
+					stats.HotPatterns = append(stats.HotPatterns, domain.PatternSearches{Pattern: key, Searches: int64(z.Score)})
+				}
+			}
+		}
+	}
+	pool, err := r.redis.HGetAll(ctx, lotteryStatsKey(drawID, "pool")).Result()
+	if err == nil {
+		stats.Pool.Hits = parseCount(pool["hits"])
+		stats.Pool.Misses = parseCount(pool["misses"])
+	}
+
+	return stats, nil
+}
+
+// countByStatus fills the status and reservation counts in one aggregation
+func (r *LotteryRepository) countByStatus(ctx context.Context, match bson.M, now time.Time, stats *domain.LotteryStats) error {
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$facet": bson.M{
+			"by_status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			// แยกการจองที่ยังมีผลกับที่หมดอายุแล้วแต่ยังไม่ถูกปล่อยคืน
+			"reservations": bson.A{
+				bson.M{"$match": bson.M{"status": domain.LotteryStatusReserved}},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$lt": bson.A{"$reserved_until", now}},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		ByStatus []struct {
+			Status domain.LotteryStatus `bson:"_id"`
+			Count  int64                `bson:"count"`
+		} `bson:"by_status"`
+		Reservations []struct {
+			Expired bool  `bson:"_id"`
+			Count   int64 `bson:"count"`
+		} `bson:"reservations"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return nil
+	}
+
+	for _, s := range result[0].ByStatus {
+		stats.ByStatus[s.Status] = s.Count
+		stats.Total += s.Count
+	}
+	for _, res := range result[0].Reservations {
+		if res.Expired {
+			stats.ExpiredReservations = res.Count
+		} else {
+			stats.ActiveReservations = res.Count
+		}
+	}
+	return nil
+}
+
+// salesPerHour counts tickets sold since since, grouped by the hour of sale
+func (r *LotteryRepository) salesPerHour(ctx context.Context, match bson.M, since time.Time) ([]domain.HourlySales, error) {
+	filter := bson.M{
+		"status":  domain.LotteryStatusSold,
+		"sold_at": bson.M{"$gte": since},
+	}
+	for k, v := range match {
+		filter[k] = v
+	}
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateTrunc": bson.M{"date": "$sold_at", "unit": "hour"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Hour  time.Time `bson:"_id"`
+		Count int64     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	sales := make([]domain.HourlySales, len(rows))
+	for i, row := range rows {
+		sales[i] = domain.HourlySales{Hour: row.Hour.UTC(), Count: row.Count}
+	}
+	return sales, nil
+}
+
+// parseCount reads a Redis counter, treating a missing or malformed value as zero
+func parseCount(raw string) int64 {
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
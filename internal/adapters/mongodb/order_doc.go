@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+type orderDoc struct {
+	ID             string             `bson:"_id"`
+	UserID         string             `bson:"user_id"`
+	DrawID         string             `bson:"draw_id"`
+	Items          []orderItemDoc     `bson:"items"`
+	Total          int64              `bson:"total"`
+	RefundedAmount int64              `bson:"refunded_amount,omitempty"`
+	Currency       string             `bson:"currency"`
+	Status         domain.OrderStatus `bson:"status"`
+	PaymentID      string             `bson:"payment_id,omitempty"`
+	CheckoutURL    string             `bson:"checkout_url,omitempty"`
+	FailureReason  string             `bson:"failure_reason,omitempty"`
+	ExpiresAt      time.Time          `bson:"expires_at"`
+	PaidAt         *time.Time         `bson:"paid_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+type orderItemDoc struct {
+	TicketID string `bson:"ticket_id"`
+	Number   string `bson:"number"`
+	Set      int    `bson:"set"`
+	Price    int64  `bson:"price"`
+	Refunded bool   `bson:"refunded,omitempty"`
+}
+
+func fromOrderDomain(o *domain.Order) *orderDoc {
+	if o == nil {
+		return nil
+	}
+	items := make([]orderItemDoc, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = orderItemDoc{
+			TicketID: item.TicketID,
+			Number:   item.Number,
+			Set:      item.Set,
+			Price:    item.Price,
+			Refunded: item.Refunded,
+		}
+	}
+	return &orderDoc{
+		ID:             o.ID,
+		UserID:         o.UserID,
+		DrawID:         o.DrawID,
+		Items:          items,
+		Total:          o.Total,
+		RefundedAmount: o.RefundedAmount,
+		Currency:       o.Currency,
+		Status:         o.Status,
+		PaymentID:      o.PaymentID,
+		CheckoutURL:    o.CheckoutURL,
+		FailureReason:  o.FailureReason,
+		ExpiresAt:      o.ExpiresAt,
+		PaidAt:         o.PaidAt,
+		CreatedAt:      o.CreatedAt,
+		UpdatedAt:      o.UpdatedAt,
+	}
+}
+
+func (d *orderDoc) toOrderDomain() *domain.Order {
+	if d == nil {
+		return nil
+	}
+	items := make([]domain.OrderItem, len(d.Items))
+	for i, item := range d.Items {
+		items[i] = domain.OrderItem{
+			TicketID: item.TicketID,
+			Number:   item.Number,
+			Set:      item.Set,
+			Price:    item.Price,
+			Refunded: item.Refunded,
+		}
+	}
+	return &domain.Order{
+		ID:             d.ID,
+		UserID:         d.UserID,
+		DrawID:         d.DrawID,
+		Items:          items,
+		Total:          d.Total,
+		RefundedAmount: d.RefundedAmount,
+		Currency:       d.Currency,
+		Status:         d.Status,
+		PaymentID:      d.PaymentID,
+		CheckoutURL:    d.CheckoutURL,
+		FailureReason:  d.FailureReason,
+		ExpiresAt:      d.ExpiresAt,
+		PaidAt:         d.PaidAt,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
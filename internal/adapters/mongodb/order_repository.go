@@ -0,0 +1,155 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrderRepository(db *mongo.Database) *OrderRepository {
+	collection := db.Collection("orders")
+
+	indexModels := []mongo.IndexModel{
+		{
+			// Callback จาก Payment Gateway อ้างถึงคำสั่งซื้อด้วย Payment ID
+			Keys:    bson.D{{Key: "payment_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			// ตั๋วหนึ่งใบอยู่ได้ในคำสั่งซื้อที่รอชำระเพียงรายการเดียว กันการจ่ายเงินซ้ำสำหรับตั๋วใบเดียวกัน
+			Keys: bson.D{{Key: "items.ticket_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": domain.OrderStatusPending,
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}},
+		},
+	}
+	collection.Indexes().CreateMany(context.Background(), indexModels)
+
+	return &OrderRepository{
+		collection: collection,
+	}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error {
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+
+	_, err := r.collection.InsertOne(ctx, fromOrderDomain(order))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrTicketInOrder
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) FindByID(ctx context.Context, id string) (*domain.Order, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *OrderRepository) FindByPaymentID(ctx context.Context, paymentID string) (*domain.Order, error) {
+	return r.findOne(ctx, bson.M{"payment_id": paymentID})
+}
+
+// FindByUser returns the user's most recent orders first
+func (r *OrderRepository) FindByUser(ctx context.Context, userID string, limit int) ([]*domain.Order, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	return r.find(ctx, bson.M{"user_id": userID}, opts)
+}
+
+// FindExpiredPending returns pending orders whose payment window closed before now
+func (r *OrderRepository) FindExpiredPending(ctx context.Context, now time.Time, limit int) ([]*domain.Order, error) {
+	filter := bson.M{
+		"status":     domain.OrderStatusPending,
+		"expires_at": bson.M{"$lt": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(int64(limit))
+	return r.find(ctx, filter, opts)
+}
+
+// Update stores the order only while it is still in status from, so two callbacks for the same
+// payment cannot both move the order on.
+func (r *OrderRepository) Update(ctx context.Context, order *domain.Order, from domain.OrderStatus) error {
+	doc := fromOrderDomain(order)
+	filter := bson.M{
+		"_id":    order.ID,
+		"status": from,
+	}
+	set := bson.M{
+		"items":           doc.Items,
+		"refunded_amount": doc.RefundedAmount,
+		"status":          doc.Status,
+		"checkout_url":    doc.CheckoutURL,
+		"failure_reason":  doc.FailureReason,
+		"paid_at":         doc.PaidAt,
+		"updated_at":      doc.UpdatedAt,
+	}
+	// Sparse index ข้ามเฉพาะเอกสารที่ไม่มี Field จึงไม่บันทึก Payment ID ที่ว่าง
+	if doc.PaymentID != "" {
+		set["payment_id"] = doc.PaymentID
+	}
+	update := bson.M{"$set": set}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.FindByID(ctx, order.ID); err != nil {
+			return err
+		}
+		return domain.ErrOrderStatusConflict
+	}
+	return nil
+}
+
+func (r *OrderRepository) findOne(ctx context.Context, filter bson.M) (*domain.Order, error) {
+	var doc orderDoc
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	return doc.toOrderDomain(), nil
+}
+
+func (r *OrderRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.Order, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []orderDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	orders := make([]*domain.Order, len(docs))
+	for i := range docs {
+		orders[i] = docs[i].toOrderDomain()
+	}
+	return orders, nil
+}
@@ -7,11 +7,13 @@ import (
 )
 
 type userDoc struct {
-	ID        string    `bson:"_id,omitempty"`
-	Name      string    `bson:"name"`
-	Email     string    `bson:"email"`
-	Password  string    `bson:"password"`
-	CreatedAt time.Time `bson:"created_at"`
+	ID            string    `bson:"_id,omitempty"`
+	Name          string    `bson:"name"`
+	Email         string    `bson:"email"`
+	Password      string    `bson:"password"`
+	Roles         []string  `bson:"roles,omitempty"`
+	EmailVerified bool      `bson:"email_verified"`
+	CreatedAt     time.Time `bson:"created_at"`
 }
 
 func fromDomain(u *domain.User) *userDoc {
@@ -19,11 +21,13 @@ func fromDomain(u *domain.User) *userDoc {
 		return nil
 	}
 	return &userDoc{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Password:      u.Password,
+		Roles:         fromDomainRoles(u.Roles),
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
 	}
 }
 
@@ -32,10 +36,34 @@ func (d *userDoc) toDomain() *domain.User {
 		return nil
 	}
 	return &domain.User{
-		ID:        d.ID,
-		Name:      d.Name,
-		Email:     d.Email,
-		Password:  d.Password,
-		CreatedAt: d.CreatedAt,
+		ID:            d.ID,
+		Name:          d.Name,
+		Email:         d.Email,
+		Password:      d.Password,
+		Roles:         toDomainRoles(d.Roles),
+		EmailVerified: d.EmailVerified,
+		CreatedAt:     d.CreatedAt,
 	}
 }
+
+func fromDomainRoles(roles []domain.Role) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return names
+}
+
+// toDomainRoles skips unknown role names. Accounts created before roles existed are plain users.
+func toDomainRoles(names []string) []domain.Role {
+	roles := make([]domain.Role, 0, len(names))
+	for _, name := range names {
+		if role, ok := domain.ParseRole(name); ok {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return domain.DefaultRoles()
+	}
+	return roles
+}
@@ -34,6 +34,9 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	if user.ID == "" {
 		user.ID = uuid.New().String()
 	}
+	if len(user.Roles) == 0 {
+		user.Roles = domain.DefaultRoles()
+	}
 	user.CreatedAt = time.Now()
 
 	doc := fromDomain(user)
@@ -97,8 +100,9 @@ func (r *UserRepository) FindAll(ctx context.Context) ([]*domain.User, error) {
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	update := bson.M{
 		"$set": bson.M{
-			"name":  user.Name,
-			"email": user.Email,
+			"name":           user.Name,
+			"email":          user.Email,
+			"email_verified": user.EmailVerified,
 		},
 	}
 
@@ -117,6 +121,80 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
+// UpdateRoles replaces the roles of a user
+func (r *UserRepository) UpdateRoles(ctx context.Context, id string, roles []domain.Role) error {
+	update := bson.M{
+		"$set": bson.M{
+			"roles": fromDomainRoles(roles),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the email of a user verified, as long as it has not changed since
+// the verification link was sent
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id string, email string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"email_verified": true,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "email": email}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrInvalidVerifyToken
+	}
+
+	return nil
+}
+
+// MigrateEmailVerified marks accounts created before email verification existed as verified, so
+// they keep access when verification is required. New accounts always store email_verified.
+func (r *UserRepository) MigrateEmailVerified(ctx context.Context) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *UserRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"password": passwordHash,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/pkg/logger"
+)
+
+// LogNotifier writes notifications to the application log instead of delivering them.
+// It is meant for local development, where the reset link can be copied from the log.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, notification domain.Notification) error {
+	logger.Info("Notification", map[string]interface{}{
+		"kind":    string(notification.Kind),
+		"to":      notification.To,
+		"subject": notification.Subject,
+		"body":    notification.Body,
+	})
+	return nil
+}
+
+// FileNotifier appends every notification to a file as one JSON object per line
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+type fileNotification struct {
+	Kind    string `json:"kind"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	SentAt  string `json:"sentAt"`
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Send(ctx context.Context, notification domain.Notification) error {
+	line, err := json.Marshal(&fileNotification{
+		Kind:    string(notification.Kind),
+		To:      notification.To,
+		Subject: notification.Subject,
+		Body:    notification.Body,
+		SentAt:  time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/google/uuid"
+)
+
+// FakeGateway is an in-memory payment gateway for development and tests. Nothing is charged:
+// a payment is completed by posting a signed event to the webhook endpoint, the way a real
+// gateway calls back after the user pays. The signature is the hex HMAC-SHA256 of the body
+// keyed with the webhook secret.
+type FakeGateway struct {
+	secret      []byte
+	checkoutURL string
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	amount   int64
+	refunded int64
+}
+
+// fakeEvent is the callback body: {"paymentId": "...", "status": "succeeded|failed", "reason": "..."}
+type fakeEvent struct {
+	PaymentID string `json:"paymentId"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func NewFakeGateway(secret string, checkoutURL string) *FakeGateway {
+	return &FakeGateway{
+		secret:      []byte(secret),
+		checkoutURL: checkoutURL,
+		intents:     make(map[string]*fakeIntent),
+	}
+}
+
+func (g *FakeGateway) CreateIntent(ctx context.Context, order *domain.Order) (*domain.PaymentIntent, error) {
+	if order.Total <= 0 {
+		return nil, fmt.Errorf("%w: order total must be positive", domain.ErrRequestInvalid)
+	}
+
+	id := "pi_" + uuid.New().String()
+	g.mu.Lock()
+	g.intents[id] = &fakeIntent{amount: order.Total}
+	g.mu.Unlock()
+
+	return &domain.PaymentIntent{
+		ID:          id,
+		OrderID:     order.ID,
+		Amount:      order.Total,
+		Currency:    order.Currency,
+		CheckoutURL: g.checkoutURL + id,
+	}, nil
+}
+
+// Refund pays back part or all of an intent. Intents are kept in memory only, so refunds of
+// intents created before a restart are accepted as is.
+func (g *FakeGateway) Refund(ctx context.Context, paymentID string, amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("refund amount must be positive")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	intent, ok := g.intents[paymentID]
+	if !ok {
+		return nil
+	}
+	if intent.refunded+amount > intent.amount {
+		return fmt.Errorf("refund of %d exceeds the remaining %d of payment %s", amount, intent.amount-intent.refunded, paymentID)
+	}
+	intent.refunded += amount
+	return nil
+}
+
+// ParseEvent verifies the callback signature and decodes the event
+func (g *FakeGateway) ParseEvent(payload []byte, signature string) (*domain.PaymentEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, g.mac(payload)) {
+		return nil, domain.ErrInvalidWebhook
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrRequestInvalid, err)
+	}
+	status := domain.PaymentEventStatus(event.Status)
+	if event.PaymentID == "" || (status != domain.PaymentSucceeded && status != domain.PaymentFailed) {
+		return nil, fmt.Errorf("%w: callback needs a paymentId and a succeeded or failed status", domain.ErrRequestInvalid)
+	}
+
+	return &domain.PaymentEvent{
+		PaymentID: event.PaymentID,
+		Status:    status,
+		Reason:    event.Reason,
+	}, nil
+}
+
+// Sign returns the signature the webhook endpoint expects for payload
+func (g *FakeGateway) Sign(payload []byte) string {
+	return hex.EncodeToString(g.mac(payload))
+}
+
+func (g *FakeGateway) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, g.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
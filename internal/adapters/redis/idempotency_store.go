@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// beginScript returns the record stored under a key, or claims the key when there is none.
+//
+// KEYS[1] idempotency key, ARGV[1] pending record, ARGV[2] lock ttl (ms)
+var beginScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[1])
+if existing then
+	return existing
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+// IdempotencyStore keeps one JSON record per Idempotency-Key. A claimed key holds a pending
+// record that expires after the lock TTL, so a request that dies mid-way does not block
+// retries for the whole replay window.
+type IdempotencyStore struct {
+	client *redis.Client
+}
+
+func NewIdempotencyStore(client *redis.Client) *IdempotencyStore {
+	return &IdempotencyStore{
+		client: client,
+	}
+}
+
+func (s *IdempotencyStore) Begin(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error) {
+	pending, err := json.Marshal(&domain.IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	raw, err := beginScript.Run(ctx, s.client, []string{idempotencyKey(key)}, pending, lockTTL.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var existing domain.IdempotentResponse
+	if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	return &existing, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, response *domain.IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	return s.client.Set(ctx, idempotencyKey(key), data, ttl).Err()
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, idempotencyKey(key)).Err()
+}
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
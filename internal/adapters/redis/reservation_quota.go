@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// placeholderGrace keeps an acquired slot alive a little longer than the hold itself,
+// so a request that dies before Commit cannot block the user for longer than that.
+const placeholderGrace = 1 * time.Minute
+
+// acquireScript atomically drops expired slots, checks the user's cap and reserves
+// placeholder slots for the tickets about to be reserved.
+//
+// KEYS[1] quota key, ARGV[1] now (ms), ARGV[2] max, ARGV[3] requested,
+// ARGV[4] placeholder expiry (ms), ARGV[5] grant id
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local available = tonumber(ARGV[2]) - redis.call('ZCARD', KEYS[1])
+if available <= 0 then
+	return 0
+end
+local granted = math.min(available, tonumber(ARGV[3]))
+for i = 1, granted do
+	redis.call('ZADD', KEYS[1], ARGV[4], ARGV[5] .. ':' .. i)
+end
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+redis.call('PEXPIREAT', KEYS[1], last[2])
+return granted
+`)
+
+// commitScript swaps placeholder slots for the reserved tickets in one step.
+//
+// KEYS[1] quota key, ARGV[1] grant id, ARGV[2] granted, then pairs of (expiry ms, ticket id)
+var commitScript = redis.NewScript(`
+for i = 1, tonumber(ARGV[2]) do
+	redis.call('ZREM', KEYS[1], ARGV[1] .. ':' .. i)
+end
+for i = 3, #ARGV, 2 do
+	redis.call('ZADD', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if #last > 0 then
+	redis.call('PEXPIREAT', KEYS[1], last[2])
+end
+return 1
+`)
+
+// ReservationQuota tracks the tickets each user holds in a Redis sorted set scored by
+// hold expiry, so the per-user cap stays correct under parallel requests.
+type ReservationQuota struct {
+	client *redis.Client
+}
+
+func NewReservationQuota(client *redis.Client) *ReservationQuota {
+	return &ReservationQuota{
+		client: client,
+	}
+}
+
+func (q *ReservationQuota) Acquire(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+	if requested <= 0 {
+		return "", 0, nil
+	}
+
+	now := time.Now()
+	grantID := uuid.New().String()
+	expiry := now.Add(hold + placeholderGrace)
+
+	granted, err := acquireScript.Run(ctx, q.client, []string{quotaKey(userID)},
+		now.UnixMilli(), max, requested, expiry.UnixMilli(), grantID).Int()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to acquire reservation quota: %w", err)
+	}
+
+	return grantID, granted, nil
+}
+
+func (q *ReservationQuota) Commit(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error {
+	args := make([]interface{}, 0, 2+len(tickets)*2)
+	args = append(args, grantID, granted)
+	for _, t := range tickets {
+		if t.ReservedUntil == nil {
+			continue
+		}
+		args = append(args, t.ReservedUntil.UnixMilli(), t.ID)
+	}
+
+	if err := commitScript.Run(ctx, q.client, []string{quotaKey(userID)}, args...).Err(); err != nil {
+		return fmt.Errorf("failed to commit reservation quota: %w", err)
+	}
+	return nil
+}
+
+func (q *ReservationQuota) Release(ctx context.Context, userID string, ticketIDs ...string) error {
+	if len(ticketIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(ticketIDs))
+	for i, id := range ticketIDs {
+		members[i] = id
+	}
+	return q.client.ZRem(ctx, quotaKey(userID), members...).Err()
+}
+
+func quotaKey(userID string) string {
+	return fmt.Sprintf("lottery_quota:%s", userID)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -40,7 +42,138 @@ func (s *SessionManager) GetSession(ctx context.Context, key string) (string, er
 	return result, nil
 }
 
+func (s *SessionManager) TakeSession(ctx context.Context, key string) (string, error) {
+	fullKey := fmt.Sprintf("session:%s", key)
+	result, err := s.client.GetDel(ctx, fullKey).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
 func (s *SessionManager) DeleteSession(ctx context.Context, key string) error {
 	fullKey := fmt.Sprintf("session:%s", key)
 	return s.client.Del(ctx, fullKey).Err()
 }
+
+// userSessionsKey is a sorted set of a user's session IDs scored by when each one expires
+func userSessionsKey(userID string) string {
+	return fmt.Sprintf("session:user:%s", userID)
+}
+
+// sessionInfoKey is a hash with the device, IP, user agent and times of one login session
+func sessionInfoKey(sessionID string) string {
+	return fmt.Sprintf("session:info:%s", sessionID)
+}
+
+// indexScript adds a session to its user's index and records the client it was last used from.
+// The creation time is kept when the session is indexed again after a refresh.
+//
+// KEYS[1] user sessions key, KEYS[2] session info key, ARGV[1] session id, ARGV[2] expiry (s),
+// ARGV[3] device, ARGV[4] ip, ARGV[5] user agent, ARGV[6] created at (ms), ARGV[7] last seen (ms)
+var indexScript = redis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+-- ดัชนีอยู่นานเท่ากับ Session ที่หมดอายุช้าที่สุด
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+redis.call('EXPIREAT', KEYS[1], last[2])
+redis.call('HSET', KEYS[2], 'device', ARGV[3], 'ip', ARGV[4], 'userAgent', ARGV[5], 'lastSeenAt', ARGV[7])
+redis.call('HSETNX', KEYS[2], 'createdAt', ARGV[6])
+redis.call('EXPIREAT', KEYS[2], ARGV[2])
+return 1
+`)
+
+// touchScript moves the last seen time of a session forward, unless the session is gone.
+//
+// KEYS[1] session info key, ARGV[1] last seen (ms)
+var touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if tonumber(redis.call('HGET', KEYS[1], 'lastSeenAt') or '0') < tonumber(ARGV[1]) then
+	redis.call('HSET', KEYS[1], 'lastSeenAt', ARGV[1])
+end
+return 1
+`)
+
+// IndexUserSession records that a session belongs to a user until it expires, along with
+// the client it was last used from
+func (s *SessionManager) IndexUserSession(ctx context.Context, userID string, session *domain.LoginSession) error {
+	return indexScript.Run(ctx, s.client, []string{userSessionsKey(userID), sessionInfoKey(session.ID)},
+		session.ID, session.ExpiresAt.Unix(), session.Device, session.IP, session.UserAgent,
+		session.CreatedAt.UnixMilli(), session.LastSeenAt.UnixMilli()).Err()
+}
+
+// TouchUserSession records that a session was used at seenAt
+func (s *SessionManager) TouchUserSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	return touchScript.Run(ctx, s.client, []string{sessionInfoKey(sessionID)}, seenAt.UnixMilli()).Err()
+}
+
+// UserSessionIDs lists the sessions of a user that have not expired
+func (s *SessionManager) UserSessionIDs(ctx context.Context, userID string) ([]string, error) {
+	key := userSessionsKey(userID)
+	now := fmt.Sprintf("%d", time.Now().Unix())
+	if err := s.client.ZRemRangeByScore(ctx, key, "-inf", now).Err(); err != nil {
+		return nil, err
+	}
+	return s.client.ZRange(ctx, key, 0, -1).Result()
+}
+
+// UserSessions lists the sessions of a user that have not expired, oldest expiry first
+func (s *SessionManager) UserSessions(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+	key := userSessionsKey(userID)
+	now := fmt.Sprintf("%d", time.Now().Unix())
+	if err := s.client.ZRemRangeByScore(ctx, key, "-inf", now).Err(); err != nil {
+		return nil, err
+	}
+	entries, err := s.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	infos := make([]*redis.MapStringStringCmd, len(entries))
+	for i, entry := range entries {
+		infos[i] = pipe.HGetAll(ctx, sessionInfoKey(entry.Member.(string)))
+	}
+	if len(entries) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]domain.LoginSession, len(entries))
+	for i, entry := range entries {
+		// Session ที่สร้างก่อนมีข้อมูลอุปกรณ์จะมีแค่ ID และเวลาหมดอายุ
+		info := infos[i].Val()
+		sessions[i] = domain.LoginSession{
+			ID:         entry.Member.(string),
+			Device:     info["device"],
+			IP:         info["ip"],
+			UserAgent:  info["userAgent"],
+			CreatedAt:  parseMillis(info["createdAt"]),
+			LastSeenAt: parseMillis(info["lastSeenAt"]),
+			ExpiresAt:  time.Unix(int64(entry.Score), 0).UTC(),
+		}
+	}
+	return sessions, nil
+}
+
+// UnindexUserSession forgets that a session belongs to a user
+func (s *SessionManager) UnindexUserSession(ctx context.Context, userID, sessionID string) error {
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(ctx, userSessionsKey(userID), sessionID)
+	pipe.Del(ctx, sessionInfoKey(sessionID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func parseMillis(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
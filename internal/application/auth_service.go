@@ -2,14 +2,21 @@ package application
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/backend-challenge/user-api/internal/adapters/jwt"
 	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/logger"
 	"github.com/backend-challenge/user-api/pkg/validator"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,28 +24,55 @@ type AuthService struct {
 	userRepo       ports.UserRepository
 	sessionManager ports.SessionManager
 	tokenService   ports.TokenService
+	notifier       ports.Notifier
+	policy         domain.AccountPolicy
 }
 
 type accessTokenSessionDTO struct {
-	UserID       string `json:"userId"`
-	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	UserID       string        `json:"userId"`
+	AccessToken  string        `json:"accessToken"`
+	RefreshToken string        `json:"refreshToken"`
+	Roles        []domain.Role `json:"roles,omitempty"`
+	SessionID    string        `json:"sessionId,omitempty"`
 }
 
 type refreshTokenSessionDTO struct {
 	RefreshToken string `json:"refreshToken"`
 	UserID       string `json:"userId"`
+	SessionID    string `json:"sessionId,omitempty"`
+}
+
+type passwordResetSessionDTO struct {
+	UserID string `json:"userId"`
+}
+
+// emailVerificationSessionDTO remembers which email a verification link was sent to, so the
+// link cannot verify an email the user has changed to since
+type emailVerificationSessionDTO struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// loginSessionDTO tracks the tokens currently issued to one login, so the login can be revoked
+type loginSessionDTO struct {
+	UserID         string `json:"userId"`
+	AccessTokenID  string `json:"accessTokenId"`
+	RefreshTokenID string `json:"refreshTokenId"`
 }
 
 func NewAuthService(
 	userRepo ports.UserRepository,
 	sessionManager ports.SessionManager,
 	tokenService ports.TokenService,
+	notifier ports.Notifier,
+	policy domain.AccountPolicy,
 ) *AuthService {
 	return &AuthService{
 		userRepo:       userRepo,
 		sessionManager: sessionManager,
 		tokenService:   tokenService,
+		notifier:       notifier,
+		policy:         policy,
 	}
 }
 
@@ -64,16 +98,84 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 		Name:     name,
 		Email:    email,
 		Password: string(hashedPassword),
+		Roles:    domain.DefaultRoles(),
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
+	// บัญชีถูกสร้างแล้ว หากส่งอีเมลไม่สำเร็จผู้ใช้ขอส่งใหม่ได้ภายหลัง
+	if err := s.SendVerification(ctx, user); err != nil {
+		logger.Error("Failed to send email verification", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": user.ID,
+		})
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, string, error) {
+// VerifyEmail marks the email of an account verified with a token from its verification link.
+// The token can be used once.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	if !validator.ValidateRequired(token) {
+		return domain.ErrInvalidVerifyToken
+	}
+
+	sessionJSON, err := s.sessionManager.TakeSession(ctx, emailVerificationKey(token))
+	if err != nil {
+		return err
+	}
+	if sessionJSON == "" {
+		return domain.ErrInvalidVerifyToken
+	}
+
+	var verification emailVerificationSessionDTO
+	if err := json.Unmarshal([]byte(sessionJSON), &verification); err != nil {
+		return fmt.Errorf("failed to unmarshal email verification session: %w", err)
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, verification.UserID, verification.Email)
+}
+
+// ResendVerification sends a new verification link to a signed-in user whose email is not
+// verified yet. Links sent before stay usable until they expire.
+func (s *AuthService) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return domain.ErrEmailVerified
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// SendVerification issues a single-use verification token for the user's current email and
+// sends it through the notifier
+func (s *AuthService) SendVerification(ctx context.Context, user *domain.User) error {
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &emailVerificationSessionDTO{UserID: user.ID, Email: user.Email}
+	if err := s.sessionManager.StoreSession(ctx, emailVerificationKey(token), verification, s.policy.EmailVerificationTTL); err != nil {
+		return fmt.Errorf("failed to store email verification token: %w", err)
+	}
+
+	return s.notifier.Send(ctx, domain.Notification{
+		Kind:    domain.NotificationEmailVerification,
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Use this link to verify your email. It expires in %s.\n%s%s",
+			s.policy.EmailVerificationTTL, s.policy.EmailVerificationURL, token),
+	})
+}
+
+func (s *AuthService) Login(ctx context.Context, email, password string, client domain.ClientInfo) (string, string, error) {
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		if err == domain.ErrUserNotFound {
@@ -99,6 +201,16 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	if err := s.storeTokens(ctx, user, uuid.New().String(), client, accessToken, refreshToken); err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// storeTokens records an access and refresh token pair of a login session in Redis.
+// The session ID stays the same across refreshes, so the whole login can be revoked.
+func (s *AuthService) storeTokens(ctx context.Context, user *domain.User, sessionID string, client domain.ClientInfo, accessToken, refreshToken string) error {
 	// Extract token IDs to store in Redis
 	accessClaims, _ := s.tokenService.ValidateToken(accessToken)
 	refreshClaims, _ := s.tokenService.ValidateToken(refreshToken)
@@ -107,22 +219,47 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 		UserID:       user.ID,
 		AccessToken:  accessToken,
 		RefreshToken: refreshClaims.Subject,
+		Roles:        user.Roles,
+		SessionID:    sessionID,
 	}
 	accessKey := fmt.Sprintf("access:%s", accessClaims.Subject)
 	if err := s.sessionManager.StoreSession(ctx, accessKey, accessSession, s.getAccessTokenTTL()); err != nil {
-		return "", "", fmt.Errorf("failed to store access session: %w", err)
+		return fmt.Errorf("failed to store access session: %w", err)
 	}
 
 	refreshSession := &refreshTokenSessionDTO{
 		RefreshToken: refreshToken,
 		UserID:       user.ID,
+		SessionID:    sessionID,
 	}
 	refreshKey := fmt.Sprintf("refresh:%s", refreshClaims.Subject)
 	if err := s.sessionManager.StoreSession(ctx, refreshKey, refreshSession, s.getRefreshTokenTTL()); err != nil {
-		return "", "", fmt.Errorf("failed to store refresh session: %w", err)
+		return fmt.Errorf("failed to store refresh session: %w", err)
 	}
 
-	return accessToken, refreshToken, nil
+	loginSession := &loginSessionDTO{
+		UserID:         user.ID,
+		AccessTokenID:  accessClaims.Subject,
+		RefreshTokenID: refreshClaims.Subject,
+	}
+	if err := s.sessionManager.StoreSession(ctx, loginKey(sessionID), loginSession, s.getRefreshTokenTTL()); err != nil {
+		return fmt.Errorf("failed to store login session: %w", err)
+	}
+	now := time.Now()
+	session := &domain.LoginSession{
+		ID:         sessionID,
+		Device:     client.DeviceName(),
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.getRefreshTokenTTL()),
+	}
+	if err := s.sessionManager.IndexUserSession(ctx, user.ID, session); err != nil {
+		return fmt.Errorf("failed to index login session: %w", err)
+	}
+
+	return nil
 }
 
 func (s *AuthService) Logout(ctx context.Context, token string) error {
@@ -141,6 +278,10 @@ func (s *AuthService) Logout(ctx context.Context, token string) error {
 				refreshKey := fmt.Sprintf("refresh:%s", accessSession.RefreshToken)
 				s.sessionManager.DeleteSession(ctx, refreshKey)
 			}
+			if accessSession.SessionID != "" {
+				s.sessionManager.DeleteSession(ctx, loginKey(accessSession.SessionID))
+				s.sessionManager.UnindexUserSession(ctx, accessSession.UserID, accessSession.SessionID)
+			}
 		}
 	}
 
@@ -179,10 +320,21 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (*domain.
 		return nil, "", domain.ErrInvalidToken
 	}
 
+	// Session ที่สร้างก่อนมี Role ถือเป็นผู้ใช้ทั่วไป
+	claims.Roles = session.Roles
+	if len(claims.Roles) == 0 {
+		claims.Roles = domain.DefaultRoles()
+	}
+	claims.SessionID = session.SessionID
+	if session.SessionID != "" {
+		// เวลาใช้งานล่าสุดเป็นข้อมูลประกอบ ไม่ควรทำให้ Request ล้มเหลว
+		s.sessionManager.TouchUserSession(ctx, session.SessionID, time.Now())
+	}
+
 	return claims, session.UserID, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client domain.ClientInfo) (string, string, error) {
 	claims, err := s.tokenService.ValidateToken(refreshToken)
 	if err != nil {
 		return "", "", domain.ErrInvalidToken
@@ -225,27 +377,240 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (st
 	// Cleanup old sessions
 	s.sessionManager.DeleteSession(ctx, refreshKey)
 
-	newAccessClaims, _ := s.tokenService.ValidateToken(newAccessToken)
-	newRefreshClaims, _ := s.tokenService.ValidateToken(latestRefreshToken)
-
-	newAccessSession := &accessTokenSessionDTO{
-		UserID:       user.ID,
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshClaims.Subject,
+	// Refresh Token ที่ออกก่อนมี Session ID จะได้ Session ใหม่
+	sessionID := refreshSession.SessionID
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	} else if err := s.deleteLoginAccessToken(ctx, sessionID); err != nil {
+		return "", "", err
 	}
-	newAccessKey := fmt.Sprintf("access:%s", newAccessClaims.Subject)
-	s.sessionManager.StoreSession(ctx, newAccessKey, newAccessSession, s.getAccessTokenTTL())
 
-	newRefreshSession := &refreshTokenSessionDTO{
-		RefreshToken: latestRefreshToken,
-		UserID:       user.ID,
+	if err := s.storeTokens(ctx, user, sessionID, client, newAccessToken, latestRefreshToken); err != nil {
+		return "", "", err
 	}
-	newRefreshKey := fmt.Sprintf("refresh:%s", newRefreshClaims.Subject)
-	s.sessionManager.StoreSession(ctx, newRefreshKey, newRefreshSession, s.getRefreshTokenTTL())
 
 	return newAccessToken, latestRefreshToken, nil
 }
 
+// ChangePassword replaces the password of a signed-in user after checking the current one.
+// Every other session of the user is revoked; the session making the change stays signed in.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return domain.ErrInvalidCredentials
+	}
+
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+
+	_, err = s.RevokeAllSessions(ctx, userID, sessionID)
+	return err
+}
+
+// ForgotPassword sends a single-use password reset link to the account with the given email.
+// It succeeds whether or not the account exists, so it cannot be used to discover accounts.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	if !validator.ValidateEmail(email) {
+		return fmt.Errorf("%w: invalid email format", domain.ErrRequestInvalid)
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+
+	// เก็บเฉพาะ Hash ของ Token เพื่อไม่ให้ผู้ที่อ่าน Redis ได้นำไปใช้ตั้งรหัสผ่านใหม่
+	resetSession := &passwordResetSessionDTO{UserID: user.ID}
+	if err := s.sessionManager.StoreSession(ctx, passwordResetKey(token), resetSession, s.policy.PasswordResetTTL); err != nil {
+		return fmt.Errorf("failed to store password reset token: %w", err)
+	}
+
+	return s.notifier.Send(ctx, domain.Notification{
+		Kind:    domain.NotificationPasswordReset,
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this link to choose a new password. It expires in %s and can be used once.\n%s%s",
+			s.policy.PasswordResetTTL, s.policy.PasswordResetURL, token),
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The token is consumed
+// whether or not the new password is accepted, and every session of the user is revoked.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if !validator.ValidateRequired(token) {
+		return domain.ErrInvalidResetToken
+	}
+
+	sessionJSON, err := s.sessionManager.TakeSession(ctx, passwordResetKey(token))
+	if err != nil {
+		return err
+	}
+	if sessionJSON == "" {
+		return domain.ErrInvalidResetToken
+	}
+
+	var resetSession passwordResetSessionDTO
+	if err := json.Unmarshal([]byte(sessionJSON), &resetSession); err != nil {
+		return fmt.Errorf("failed to unmarshal password reset session: %w", err)
+	}
+
+	if err := s.setPassword(ctx, resetSession.UserID, newPassword); err != nil {
+		return err
+	}
+
+	_, err = s.RevokeAllSessions(ctx, resetSession.UserID, "")
+	return err
+}
+
+// setPassword validates, hashes and stores a new password
+func (s *AuthService) setPassword(ctx context.Context, userID, password string) error {
+	if !validator.ValidatePassword(password) {
+		return fmt.Errorf("%w: password must be at least 8 characters and include uppercase, lowercase, number, and special character", domain.ErrRequestInvalid)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword))
+}
+
+// ListSessions returns the sessions a user is signed in with, most recently used first.
+// The session with currentSessionID is marked as current.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.LoginSession, error) {
+	sessions, err := s.sessionManager.UserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one of their sessions
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	sessionIDs, err := s.sessionManager.UserSessionIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sessionIDs {
+		if id == sessionID {
+			return s.revokeSession(ctx, userID, sessionID)
+		}
+	}
+	return domain.ErrSessionNotFound
+}
+
+// RevokeAllSessions revokes every session of a user except keepSessionID, which may be empty.
+// It returns how many sessions were revoked.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID, keepSessionID string) (int, error) {
+	sessionIDs, err := s.sessionManager.UserSessionIDs(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, sessionID := range sessionIDs {
+		if sessionID == keepSessionID {
+			continue
+		}
+		if err := s.revokeSession(ctx, userID, sessionID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// revokeSession deletes the tokens currently issued to a login session and forgets the session
+func (s *AuthService) revokeSession(ctx context.Context, userID, sessionID string) error {
+	sessionJSON, err := s.sessionManager.GetSession(ctx, loginKey(sessionID))
+	if err != nil {
+		return err
+	}
+	if sessionJSON != "" {
+		var loginSession loginSessionDTO
+		if err := json.Unmarshal([]byte(sessionJSON), &loginSession); err != nil {
+			return fmt.Errorf("failed to unmarshal login session: %w", err)
+		}
+		if err := s.sessionManager.DeleteSession(ctx, fmt.Sprintf("access:%s", loginSession.AccessTokenID)); err != nil {
+			return err
+		}
+		if err := s.sessionManager.DeleteSession(ctx, fmt.Sprintf("refresh:%s", loginSession.RefreshTokenID)); err != nil {
+			return err
+		}
+		if err := s.sessionManager.DeleteSession(ctx, loginKey(sessionID)); err != nil {
+			return err
+		}
+	}
+
+	return s.sessionManager.UnindexUserSession(ctx, userID, sessionID)
+}
+
+// deleteLoginAccessToken revokes the access token a login session was last issued, so only
+// the tokens recorded for the session can be used and revoking the session revokes them all
+func (s *AuthService) deleteLoginAccessToken(ctx context.Context, sessionID string) error {
+	sessionJSON, err := s.sessionManager.GetSession(ctx, loginKey(sessionID))
+	if err != nil || sessionJSON == "" {
+		return err
+	}
+	var loginSession loginSessionDTO
+	if err := json.Unmarshal([]byte(sessionJSON), &loginSession); err != nil {
+		return fmt.Errorf("failed to unmarshal login session: %w", err)
+	}
+	if loginSession.AccessTokenID == "" {
+		return nil
+	}
+	return s.sessionManager.DeleteSession(ctx, fmt.Sprintf("access:%s", loginSession.AccessTokenID))
+}
+
+// newRandomToken returns a random URL-safe token
+func newRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func passwordResetKey(token string) string {
+	return fmt.Sprintf("password_reset:%s", hashToken(token))
+}
+
+func emailVerificationKey(token string) string {
+	return fmt.Sprintf("email_verification:%s", hashToken(token))
+}
+
+// hashToken is how single-use tokens are keyed in Redis, so the stored keys cannot be used as tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func loginKey(sessionID string) string {
+	return fmt.Sprintf("login:%s", sessionID)
+}
+
 func (s *AuthService) getAccessTokenTTL() time.Duration {
 	if ts, ok := s.tokenService.(*jwt.TokenService); ok {
 		return ts.GetAccessTokenDuration()
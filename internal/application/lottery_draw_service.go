@@ -0,0 +1,264 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/validator"
+)
+
+type LotteryDrawService struct {
+	draws    ports.LotteryDrawRepository
+	tickets  ports.LotteryRepository
+	template domain.DrawTemplate
+}
+
+// NewLotteryDrawService creates the draw service. template is used for new rounds when
+// there is no earlier round to copy settings from.
+func NewLotteryDrawService(draws ports.LotteryDrawRepository, tickets ports.LotteryRepository, template domain.DrawTemplate) *LotteryDrawService {
+	return &LotteryDrawService{
+		draws:    draws,
+		tickets:  tickets,
+		template: template,
+	}
+}
+
+const (
+	defaultDrawListLimit = 24
+	maxDrawListLimit     = 100
+)
+
+// CreateDraw schedules a round on drawDate. A nil template copies the latest round's settings.
+// The round's inventory is stocked by cmd/seed (SeedDraw), or by StockInventories when auto stocking is on.
+func (s *LotteryDrawService) CreateDraw(ctx context.Context, drawDate time.Time, template *domain.DrawTemplate) (*domain.LotteryDraw, error) {
+	tmpl, err := s.resolveTemplate(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	draw := domain.NewLotteryDraw(drawDate, tmpl)
+	if !draw.DrawDate.After(time.Now()) {
+		return nil, fmt.Errorf("%w: draw date must be in the future", domain.ErrRequestInvalid)
+	}
+
+	if err := s.draws.Create(ctx, draw); err != nil {
+		return nil, err
+	}
+
+	return draw, nil
+}
+
+// CreateNextDraw schedules the regular draw following the latest round, copying its settings
+// unless a template is given.
+func (s *LotteryDrawService) CreateNextDraw(ctx context.Context, template *domain.DrawTemplate) (*domain.LotteryDraw, error) {
+	after := time.Now()
+	latest, err := s.draws.FindLatest(ctx)
+	if err != nil && !errors.Is(err, domain.ErrDrawNotFound) {
+		return nil, err
+	}
+	if latest != nil && latest.DrawDate.After(after) {
+		after = latest.DrawDate
+	}
+
+	return s.CreateDraw(ctx, domain.NextDrawDate(after), template)
+}
+
+// EnsureNextDraw schedules the next regular draw once no scheduled round is still selling,
+// so a new round is ready as soon as the previous one closes its sales.
+func (s *LotteryDrawService) EnsureNextDraw(ctx context.Context) (*domain.LotteryDraw, error) {
+	now := time.Now()
+	draw, err := s.draws.FindNext(ctx, now)
+	if err == nil {
+		return draw, nil
+	}
+	if !errors.Is(err, domain.ErrDrawNotFound) {
+		return nil, err
+	}
+
+	draw, err = s.CreateNextDraw(ctx, nil)
+	if err != nil {
+		// อีก Instance อาจสร้างงวดเดียวกันไปก่อนแล้ว
+		if errors.Is(err, domain.ErrDrawExists) {
+			return s.draws.FindNext(ctx, now)
+		}
+		return nil, err
+	}
+
+	return draw, nil
+}
+
+// StockInventories creates the ticket inventory of every round that has none yet and
+// returns how many rounds were stocked. Tickets created before inventories were scoped
+// to a round are moved into the first round stocked.
+func (s *LotteryDrawService) StockInventories(ctx context.Context) (int, error) {
+	draws, err := s.draws.FindUnstocked(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	stocked := 0
+	for _, draw := range draws {
+		if _, err := s.tickets.AssignUnscopedTickets(ctx, draw.ID); err != nil {
+			return stocked, err
+		}
+		if err := s.tickets.SeedTickets(ctx, domain.NewTicketSeedPlan(draw)); err != nil {
+			return stocked, err
+		}
+		if err := s.draws.MarkStocked(ctx, draw.ID, time.Now()); err != nil {
+			return stocked, err
+		}
+		stocked++
+	}
+
+	return stocked, nil
+}
+
+// SeedDraw stocks a range of a round's inventory on demand. An empty draw ID seeds the next
+// round, scheduling it if needed; an empty range covers the round's whole inventory. The copies
+// per number always follow the round. A seed covering the whole inventory marks the round
+// stocked, so StockInventories leaves it alone. Like StockInventories, tickets created before
+// inventories were scoped to a round are moved into the first round seeded.
+func (s *LotteryDrawService) SeedDraw(ctx context.Context, plan domain.TicketSeedPlan) (*domain.LotteryDraw, error) {
+	var draw *domain.LotteryDraw
+	var err error
+	if plan.DrawID == "" {
+		draw, err = s.EnsureNextDraw(ctx)
+	} else {
+		draw, err = s.GetDraw(ctx, plan.DrawID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plan.DrawID = draw.ID
+	plan.Sets = draw.SetsPerNumber
+	if plan.To == 0 {
+		plan.To = draw.TicketCount
+	}
+	if plan.BatchSize == 0 {
+		plan.BatchSize = domain.DefaultSeedBatchSize
+	}
+	if plan.To > draw.TicketCount {
+		return nil, fmt.Errorf("%w: round %s only stocks numbers below %06d", domain.ErrRequestInvalid, draw.ID, draw.TicketCount)
+	}
+
+	// เลขเดิมที่ยังไม่มี draw_id ต้องเข้างวดก่อนเติมเลข ไม่เช่นนั้นจะไม่อยู่ในงวดใดเลย
+	if _, err := s.tickets.AssignUnscopedTickets(ctx, draw.ID); err != nil {
+		return nil, err
+	}
+	if err := s.tickets.SeedTickets(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Covers(draw) && draw.StockedAt == nil {
+		now := time.Now()
+		if err := s.draws.MarkStocked(ctx, draw.ID, now); err != nil {
+			return nil, err
+		}
+		draw.StockedAt = &now
+	}
+
+	return draw, nil
+}
+
+// UpdateSalesWindow opens or closes sales of a round by moving its sales window.
+func (s *LotteryDrawService) UpdateSalesWindow(ctx context.Context, id string, openAt, closeAt time.Time) (*domain.LotteryDraw, error) {
+	draw, err := s.GetDraw(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := draw.SetSalesWindow(openAt, closeAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.draws.UpdateSalesWindow(ctx, draw); err != nil {
+		return nil, err
+	}
+
+	return draw, nil
+}
+
+func (s *LotteryDrawService) resolveTemplate(ctx context.Context, template *domain.DrawTemplate) (domain.DrawTemplate, error) {
+	if template != nil {
+		return *template, template.Validate()
+	}
+
+	latest, err := s.draws.FindLatest(ctx)
+	if err != nil {
+		if errors.Is(err, domain.ErrDrawNotFound) {
+			return s.template, nil
+		}
+		return domain.DrawTemplate{}, err
+	}
+	return latest.Template(), nil
+}
+
+func (s *LotteryDrawService) GetDraw(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+	if !validator.ValidateRequired(id) {
+		return nil, fmt.Errorf("%w: draw id is required", domain.ErrRequestInvalid)
+	}
+	return s.draws.FindByID(ctx, id)
+}
+
+func (s *LotteryDrawService) ListDraws(ctx context.Context, limit int) ([]*domain.LotteryDraw, error) {
+	if limit <= 0 {
+		limit = defaultDrawListLimit
+	}
+	if limit > maxDrawListLimit {
+		limit = maxDrawListLimit
+	}
+	return s.draws.List(ctx, limit)
+}
+
+func (s *LotteryDrawService) RecordResults(ctx context.Context, id string, results map[domain.PrizeTier][]string) (*domain.LotteryDraw, error) {
+	draw, err := s.GetDraw(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Before(draw.DrawDate) {
+		return nil, fmt.Errorf("%w: results cannot be recorded before the draw date", domain.ErrRequestInvalid)
+	}
+	if err := draw.RecordResults(results, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.draws.SaveResults(ctx, draw); err != nil {
+		return nil, err
+	}
+
+	return draw, nil
+}
+
+// CheckTickets checks every ticket the user bought for the draw against its results.
+func (s *LotteryDrawService) CheckTickets(ctx context.Context, id string, userID string) (*domain.DrawCheckResult, error) {
+	draw, err := s.GetDraw(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if draw.Status != domain.DrawStatusDrawn {
+		return nil, domain.ErrDrawNotDrawn
+	}
+
+	tickets, err := s.tickets.FindSoldByUser(ctx, userID, draw.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.DrawCheckResult{
+		Draw:    draw,
+		Tickets: make([]domain.TicketCheck, len(tickets)),
+	}
+	for i, t := range tickets {
+		check := domain.TicketCheck{Ticket: t, Prizes: draw.PrizesFor(t.Number)}
+		result.Tickets[i] = check
+		result.TotalWinnings += check.Winnings()
+	}
+
+	return result, nil
+}
@@ -2,36 +2,463 @@ package application
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	"github.com/backend-challenge/user-api/pkg/validator"
+	"golang.org/x/sync/singleflight"
 )
 
 type LotteryService struct {
-	repo ports.LotteryRepository
+	repo   ports.LotteryRepository
+	draws  ports.LotteryDrawRepository
+	quota  ports.ReservationQuota
+	policy domain.ReservationPolicy
+
+	statsMu     sync.Mutex
+	statsCache  map[string]*domain.LotteryStats
+	statsFlight singleflight.Group
 }
 
-func NewLotteryService(repo ports.LotteryRepository) *LotteryService {
+func NewLotteryService(repo ports.LotteryRepository, draws ports.LotteryDrawRepository, quota ports.ReservationQuota, policy domain.ReservationPolicy) *LotteryService {
 	return &LotteryService{
-		repo: repo,
+		repo:       repo,
+		draws:      draws,
+		quota:      quota,
+		policy:     policy,
+		statsCache: make(map[string]*domain.LotteryStats),
+	}
+}
+
+const (
+	// searchReserveLimit caps how many tickets one search reserves, however high the per-user cap is
+	searchReserveLimit = 10
+
+	defaultBrowseLimit = 20
+	maxBrowseLimit     = 100
+
+	// statsCacheTTL keeps repeated dashboard refreshes from re-running the aggregations
+	statsCacheTTL    = 30 * time.Second
+	statsSalesWindow = 24 * time.Hour
+	statsHotPatterns = 10
+
+	maxHistoryEntries = 500
+)
+
+func (s *LotteryService) SearchLottery(ctx context.Context, rawPattern string, userID string) ([]domain.LotteryTicket, error) {
+	pattern, err := domain.ParseLotteryPattern(rawPattern)
+	if err != nil {
+		return nil, err
+	}
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
+	}
+
+	// จองสิทธิ์ใน Quota ก่อนแบบ Atomic เพื่อไม่ให้คำขอที่ยิงพร้อมกันของผู้ใช้คนเดียวจองเกินเพดาน
+	// ขอแค่จำนวนต่อการค้นหา Quota จะให้ไม่เกินที่เหลือของเพดาน
+	grantID, granted, err := s.quota.Acquire(ctx, userID, searchReserveLimit, s.policy.MaxTicketsPerUser, s.policy.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if granted == 0 {
+		return nil, domain.ErrQuotaExceeded
+	}
+
+	tickets, err := s.repo.SearchAndReserve(ctx, draw, pattern, userID, granted, s.policy.TTL)
+	commitQuota(ctx, s.quota, userID, grantID, granted, tickets)
+	if err != nil {
+		return nil, err
+	}
+
+	return tickets, nil
+}
+
+// WatchLottery streams the reserve, release and sell events of tickets matching pattern in the
+// round on sale, without reserving anything. The channel is closed when ctx is cancelled or the
+// server shuts down.
+func (s *LotteryService) WatchLottery(ctx context.Context, rawPattern string) (<-chan domain.LotteryEvent, error) {
+	pattern, err := domain.ParseLotteryPattern(rawPattern)
+	if err != nil {
+		return nil, err
+	}
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
 	}
+
+	events, err := s.repo.Watch(ctx, draw.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(chan domain.LotteryEvent)
+	go func() {
+		defer close(matched)
+		// Channel ของ Repository ถูกปิดเมื่อ ctx ถูกยกเลิก จึงวนจนกว่าจะหมด
+		for event := range events {
+			if !pattern.Matches(event.Number) {
+				continue
+			}
+			select {
+			case matched <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return matched, nil
 }
 
-func (s *LotteryService) SearchLottery(ctx context.Context, pattern string, userID string) ([]domain.LotteryTicket, error) {
-	if len(pattern) != 6 {
-		return nil, fmt.Errorf("%w: pattern must be 6 characters", domain.ErrRequestInvalid)
+// BrowseLottery lists tickets matching pattern with their current status without reserving anything.
+func (s *LotteryService) BrowseLottery(ctx context.Context, rawPattern string, cursor string, limit int) (*domain.LotteryPage, error) {
+	pattern, err := domain.ParseLotteryPattern(rawPattern)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultBrowseLimit
+	}
+	if limit > maxBrowseLimit {
+		limit = maxBrowseLimit
+	}
+
+	after, err := decodeLotteryCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, total, err := s.repo.BrowseTickets(ctx, draw.ID, pattern, after, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, char := range pattern {
-		if (char < '0' || char > '9') && char != '*' {
-			return nil, fmt.Errorf("%w: pattern can only contain digits and *", domain.ErrRequestInvalid)
+	// การจองที่หมดอายุแล้วถือว่าว่าง และไม่เปิดเผยผู้จองให้ผู้ใช้อื่นเห็น
+	now := time.Now()
+	for i := range tickets {
+		if tickets[i].IsAvailable(now) {
+			tickets[i].Status = domain.LotteryStatusAvailable
+			tickets[i].ReservedUntil = nil
 		}
+		tickets[i].ReservedBy = ""
 	}
 
-	return s.repo.SearchAndReserve(ctx, pattern, userID, 10) // limit 10
+	page := &domain.LotteryPage{
+		Tickets: tickets,
+		Total:   total,
+	}
+	if len(tickets) == limit {
+		last := tickets[len(tickets)-1]
+		page.NextCursor = encodeLotteryCursor(domain.LotteryCursor{Number: last.Number, Set: last.Set})
+	}
+
+	return page, nil
+}
+
+// encodeLotteryCursor encodes the last copy of a page as "<number>:<set>"
+func encodeLotteryCursor(c domain.LotteryCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Number + ":" + strconv.Itoa(c.Set)))
+}
+
+// decodeLotteryCursor also accepts cursors holding only a number, issued before tickets had sets
+func decodeLotteryCursor(cursor string) (domain.LotteryCursor, error) {
+	if cursor == "" {
+		return domain.LotteryCursor{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return domain.LotteryCursor{}, fmt.Errorf("%w: invalid cursor", domain.ErrRequestInvalid)
+	}
+
+	number, rawSet, hasSet := strings.Cut(string(decoded), ":")
+	c := domain.LotteryCursor{Number: number}
+	if hasSet {
+		if c.Set, err = strconv.Atoi(rawSet); err != nil {
+			return domain.LotteryCursor{}, fmt.Errorf("%w: invalid cursor", domain.ErrRequestInvalid)
+		}
+	}
+	return c, nil
+}
+
+func (s *LotteryService) GetCart(ctx context.Context, userID string) (*domain.Cart, error) {
+	tickets, err := s.repo.FindReservationsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &domain.Cart{
+		UserID:     userID,
+		Tickets:    tickets,
+		MaxTickets: s.policy.MaxTicketsPerUser,
+	}
+	for i := range tickets {
+		t := &tickets[i]
+		if t.ReservedUntil != nil && (cart.ExpiresAt == nil || t.ReservedUntil.Before(*cart.ExpiresAt)) {
+			cart.ExpiresAt = t.ReservedUntil
+		}
+		if t.HoldExtensions < s.policy.MaxExtensions {
+			cart.CanExtend = true
+		}
+	}
+
+	return cart, nil
+}
+
+// AddToCart reserves copies copies of number for the user. Copies the user already holds count
+// towards the request, so adding the same number again does not reserve it twice.
+func (s *LotteryService) AddToCart(ctx context.Context, number string, copies int, userID string) ([]domain.LotteryTicket, error) {
+	if err := validateLotteryNumber(number); err != nil {
+		return nil, err
+	}
+	if copies <= 0 {
+		copies = 1
+	}
+
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
+	}
+	if copies > draw.SetsPerNumber {
+		return nil, fmt.Errorf("%w: copies must be between 1 and %d", domain.ErrRequestInvalid, draw.SetsPerNumber)
+	}
+
+	held, err := heldCopies(ctx, s.repo, draw.ID, number, userID)
+	if err != nil {
+		return nil, err
+	}
+	need := copies - len(held)
+	if need <= 0 {
+		return held, nil
+	}
+
+	grantID, granted, err := s.quota.Acquire(ctx, userID, need, s.policy.MaxTicketsPerUser, s.policy.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if granted < need {
+		// จองได้ทั้งหมดหรือไม่จองเลย จึงคืนสิทธิ์ที่ได้มาไม่ครบ
+		commitQuota(ctx, s.quota, userID, grantID, granted, nil)
+		return nil, domain.ErrQuotaExceeded
+	}
+
+	tickets, err := s.repo.ReserveNumber(ctx, draw.ID, number, userID, need, s.policy.TTL)
+	if err != nil {
+		commitQuota(ctx, s.quota, userID, grantID, granted, nil)
+		return nil, err
+	}
+	commitQuota(ctx, s.quota, userID, grantID, granted, tickets)
+
+	return append(held, tickets...), nil
+}
+
+// heldCopies returns the copies of number in the draw the user currently holds
+func heldCopies(ctx context.Context, repo ports.LotteryRepository, drawID string, number string, userID string) ([]domain.LotteryTicket, error) {
+	reservations, err := repo.FindReservationsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make([]domain.LotteryTicket, 0)
+	for _, t := range reservations {
+		if t.DrawID == drawID && t.Number == number {
+			held = append(held, t)
+		}
+	}
+	return held, nil
+}
+
+// ExtendHold extends every ticket in the user's cart once, up to policy.MaxExtensions times.
+func (s *LotteryService) ExtendHold(ctx context.Context, userID string) (*domain.Cart, error) {
+	if s.policy.MaxExtensions <= 0 || s.policy.ExtensionDuration <= 0 {
+		return nil, domain.ErrHoldNotExtendable
+	}
+
+	extended, err := s.repo.ExtendReservations(ctx, userID, s.policy.ExtensionDuration, s.policy.MaxExtensions)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.GetCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if extended == 0 {
+		if len(cart.Tickets) == 0 {
+			return nil, domain.ErrTicketNotReserved
+		}
+		return nil, domain.ErrHoldNotExtendable
+	}
+
+	// อัปเดตเวลาหมดอายุใน Quota ให้ตรงกับการจองที่ถูกขยาย
+	commitQuota(ctx, s.quota, userID, "", 0, cart.Tickets)
+
+	return cart, nil
+}
+
+func (s *LotteryService) ListReservations(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+	return s.repo.FindReservationsByUser(ctx, userID)
+}
+
+func (s *LotteryService) ReleaseReservation(ctx context.Context, ticketID string, userID string) error {
+	if !validator.ValidateRequired(ticketID) {
+		return fmt.Errorf("%w: ticket id is required", domain.ErrRequestInvalid)
+	}
+
+	ticket, err := s.repo.ReleaseReservation(ctx, ticketID, userID)
+	if err != nil {
+		return err
+	}
+	releaseQuota(ctx, s.quota, userID, ticket.ID)
+
+	return nil
+}
+
+func (s *LotteryService) ReleaseAllReservations(ctx context.Context, userID string) (int64, error) {
+	held, err := s.repo.FindReservationsByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	released, err := s.repo.ReleaseAllReservations(ctx, userID)
+
+	heldIDs := make([]string, len(held))
+	for i, t := range held {
+		heldIDs[i] = t.ID
+	}
+	releaseQuota(ctx, s.quota, userID, heldIDs...)
+
+	return released, err
+}
+
+// GetTicketHistory returns the audit trail of a ticket, oldest first. Other users are not
+// named: their entries are returned without an actor.
+func (s *LotteryService) GetTicketHistory(ctx context.Context, ticketID string, userID string) ([]domain.LotteryHistoryEntry, error) {
+	if !validator.ValidateRequired(ticketID) {
+		return nil, fmt.Errorf("%w: ticket id is required", domain.ErrRequestInvalid)
+	}
+
+	entries, err := s.repo.FindHistory(ctx, ticketID, maxHistoryEntries)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Actor != userID && entries[i].Actor != domain.LotteryActorSystem {
+			entries[i].Actor = ""
+		}
+	}
+	return entries, nil
+}
+
+func (s *LotteryService) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	return s.repo.ReleaseExpiredReservations(ctx)
+}
+
+// openDraw returns the round currently on sale
+func openDraw(ctx context.Context, draws ports.LotteryDrawRepository) (*domain.LotteryDraw, error) {
+	draw, err := draws.FindOnSale(ctx, time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrDrawNotFound) {
+			return nil, domain.ErrSalesClosed
+		}
+		return nil, err
+	}
+	return draw, nil
+}
+
+// commitQuota records reserved tickets against the user's quota and frees unused slots.
+// Failures are logged only: stale slots expire on their own shortly after the hold.
+func commitQuota(ctx context.Context, quota ports.ReservationQuota, userID, grantID string, granted int, tickets []domain.LotteryTicket) {
+	if err := quota.Commit(ctx, userID, grantID, granted, tickets); err != nil {
+		logger.Error("Failed to commit reservation quota", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+func releaseQuota(ctx context.Context, quota ports.ReservationQuota, userID string, ticketIDs ...string) {
+	if err := quota.Release(ctx, userID, ticketIDs...); err != nil {
+		logger.Error("Failed to release reservation quota", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+func validateLotteryNumber(number string) error {
+	if len(number) != domain.LotteryNumberLength {
+		return fmt.Errorf("%w: number must be 6 digits", domain.ErrRequestInvalid)
+	}
+	for _, char := range number {
+		if char < '0' || char > '9' {
+			return fmt.Errorf("%w: number can only contain digits", domain.ErrRequestInvalid)
+		}
+	}
+	return nil
 }
 
 func (s *LotteryService) GetLotteryCount(ctx context.Context) (int64, error) {
 	return s.repo.Count(ctx)
 }
+
+// GetLotteryStats returns inventory statistics of a draw round, or of every round when drawID
+// is empty, with sales per hour over the last day. Results are cached for statsCacheTTL.
+func (s *LotteryService) GetLotteryStats(ctx context.Context, drawID string) (*domain.LotteryStats, error) {
+	if cached := s.cachedStats(drawID); cached != nil {
+		return cached, nil
+	}
+
+	// คำขอของรอบเดียวกันที่มาพร้อมกันตอน Cache หมดอายุรอผลของ Aggregation ครั้งเดียว
+	// ส่วนรอบอื่นคำนวณได้พร้อมกันโดยไม่ต้องรอ
+	result, err, _ := s.statsFlight.Do(drawID, func() (interface{}, error) {
+		if cached := s.cachedStats(drawID); cached != nil {
+			return cached, nil
+		}
+
+		if drawID != "" {
+			if _, err := s.draws.FindByID(ctx, drawID); err != nil {
+				return nil, err
+			}
+		}
+
+		now := time.Now()
+		stats, err := s.repo.Stats(ctx, drawID, now.Add(-statsSalesWindow).Truncate(time.Hour), statsHotPatterns)
+		if err != nil {
+			return nil, err
+		}
+
+		s.statsMu.Lock()
+		s.statsCache[drawID] = stats
+		s.statsMu.Unlock()
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*domain.LotteryStats), nil
+}
+
+// cachedStats returns the cached statistics of drawID while they are fresh, or nil
+func (s *LotteryService) cachedStats(drawID string) *domain.LotteryStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	if cached, ok := s.statsCache[drawID]; ok && time.Since(cached.GeneratedAt) < statsCacheTTL {
+		return cached
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/logger"
+	"github.com/backend-challenge/user-api/pkg/validator"
+)
+
+const (
+	defaultOrderListLimit = 20
+	maxOrderListLimit     = 100
+	expiredOrderBatchSize = 100
+)
+
+// OrderService turns held tickets into orders and settles them from payment gateway callbacks.
+// Tickets are only sold once their order's payment is confirmed.
+type OrderService struct {
+	orders        ports.OrderRepository
+	repo          ports.LotteryRepository
+	draws         ports.LotteryDrawRepository
+	quota         ports.ReservationQuota
+	gateway       ports.PaymentGateway
+	policy        domain.ReservationPolicy
+	paymentWindow time.Duration
+}
+
+func NewOrderService(orders ports.OrderRepository, repo ports.LotteryRepository, draws ports.LotteryDrawRepository, quota ports.ReservationQuota, gateway ports.PaymentGateway, policy domain.ReservationPolicy, paymentWindow time.Duration) *OrderService {
+	return &OrderService{
+		orders:        orders,
+		repo:          repo,
+		draws:         draws,
+		quota:         quota,
+		gateway:       gateway,
+		policy:        policy,
+		paymentWindow: paymentWindow,
+	}
+}
+
+// Checkout places an order for tickets the user holds in the round on sale and starts its
+// payment. The holds are kept for the whole payment window.
+func (s *OrderService) Checkout(ctx context.Context, ticketIDs []string, userID string) (*domain.Order, error) {
+	if len(ticketIDs) == 0 {
+		return nil, fmt.Errorf("%w: ticket ids are required", domain.ErrRequestInvalid)
+	}
+	for _, id := range ticketIDs {
+		if !validator.ValidateRequired(id) {
+			return nil, fmt.Errorf("%w: ticket id is required", domain.ErrRequestInvalid)
+		}
+	}
+
+	// ซื้อได้เฉพาะตั๋วของงวดที่เปิดขายอยู่
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.placeOrder(ctx, draw, ticketIDs, userID)
+}
+
+// CheckoutSet reserves every copy of number in the round on sale and places an order for the
+// whole set, so either the user pays for all copies or nothing is held for them.
+func (s *OrderService) CheckoutSet(ctx context.Context, number string, userID string) (*domain.Order, error) {
+	if err := validateLotteryNumber(number); err != nil {
+		return nil, err
+	}
+
+	draw, err := openDraw(ctx, s.draws)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := heldCopies(ctx, s.repo, draw.ID, number, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ชุดที่ผู้ใช้ถืออยู่แล้วใช้สิทธิ์ใน Quota ไปแล้ว จึงขอเพิ่มเฉพาะชุดที่เหลือ
+	grantID, granted := "", 0
+	if need := draw.SetsPerNumber - len(held); need > 0 {
+		grantID, granted, err = s.quota.Acquire(ctx, userID, need, s.policy.MaxTicketsPerUser, s.policy.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if granted < need {
+			commitQuota(ctx, s.quota, userID, grantID, granted, nil)
+			return nil, domain.ErrQuotaExceeded
+		}
+	}
+
+	tickets, err := s.repo.ReserveSet(ctx, draw.ID, number, userID, s.policy.TTL)
+	if err != nil {
+		commitQuota(ctx, s.quota, userID, grantID, granted, nil)
+		return nil, err
+	}
+	commitQuota(ctx, s.quota, userID, grantID, granted, tickets)
+
+	ids := make([]string, len(tickets))
+	for i, t := range tickets {
+		ids[i] = t.ID
+	}
+	return s.placeOrder(ctx, draw, ids, userID)
+}
+
+func (s *OrderService) placeOrder(ctx context.Context, draw *domain.LotteryDraw, ticketIDs []string, userID string) (*domain.Order, error) {
+	ticketIDs = uniqueIDs(ticketIDs)
+	now := time.Now()
+
+	// เก็บเวลาหมดอายุเดิมไว้ เพื่อคืนค่าหากสร้างคำสั่งซื้อไม่สำเร็จหลังยืดเวลาไปแล้ว
+	previous, err := s.repo.FindReservationsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ยืดเวลาจองให้ครอบคลุมช่วงรอชำระเงิน ไม่ให้ตั๋วถูกปล่อยคืนก่อน Callback มาถึง
+	// หากมีใบที่หลุดไปแล้ว จะไม่มีใบใดถูกยืดเวลา และได้ Error ที่บอกสาเหตุ เช่น ขายไปแล้วหรือหมดเวลาจอง
+	tickets, err := s.repo.HoldUntil(ctx, draw.ID, ticketIDs, userID, now.Add(s.paymentWindow))
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) != len(ticketIDs) {
+		s.restoreHolds(ctx, previous, ticketIDs, userID)
+		return nil, domain.ErrTicketNotReserved
+	}
+
+	order, err := domain.NewOrder(userID, draw, tickets, now, s.paymentWindow)
+	if err != nil {
+		s.restoreHolds(ctx, previous, ticketIDs, userID)
+		return nil, err
+	}
+	if err := s.orders.Create(ctx, order); err != nil {
+		// เช่น ตั๋วอยู่ในคำสั่งซื้ออื่นแล้ว (ErrTicketInOrder) ไม่ให้ตั๋วถูกถือไว้นานโดยไม่มีคำสั่งซื้อรองรับ
+		s.restoreHolds(ctx, previous, ticketIDs, userID)
+		return nil, err
+	}
+	// Quota ตามเวลาหมดอายุใหม่เมื่อมีคำสั่งซื้อรองรับแล้วเท่านั้น
+	commitQuota(ctx, s.quota, userID, "", 0, tickets)
+
+	intent, err := s.gateway.CreateIntent(ctx, order)
+	if err != nil {
+		// ตั๋วยังอยู่ในตะกร้า ผู้ใช้สั่งซื้อใหม่ได้
+		if markErr := order.MarkFailed("payment could not be started", time.Now()); markErr == nil {
+			s.saveOrder(ctx, order, domain.OrderStatusPending)
+		}
+		return nil, err
+	}
+	if err := order.AttachPayment(intent, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.orders.Update(ctx, order, domain.OrderStatusPending); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// restoreHolds puts the holds on ticketIDs back to their expiry in previous, after they were
+// extended for an order that could not be placed
+func (s *OrderService) restoreHolds(ctx context.Context, previous []domain.LotteryTicket, ticketIDs []string, userID string) {
+	wanted := make(map[string]bool, len(ticketIDs))
+	for _, id := range ticketIDs {
+		wanted[id] = true
+	}
+	holds := make([]domain.LotteryTicket, 0, len(ticketIDs))
+	for _, t := range previous {
+		if wanted[t.ID] {
+			holds = append(holds, t)
+		}
+	}
+	if err := s.repo.RestoreHolds(ctx, holds, userID); err != nil {
+		logger.Error("Failed to restore lottery holds", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, id string, userID string) (*domain.Order, error) {
+	if !validator.ValidateRequired(id) {
+		return nil, fmt.Errorf("%w: order id is required", domain.ErrRequestInvalid)
+	}
+
+	order, err := s.orders.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// ไม่เปิดเผยว่ามีคำสั่งซื้อของผู้ใช้อื่นอยู่
+	if order.UserID != userID {
+		return nil, domain.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, userID string, limit int) ([]*domain.Order, error) {
+	if limit <= 0 {
+		limit = defaultOrderListLimit
+	}
+	if limit > maxOrderListLimit {
+		limit = maxOrderListLimit
+	}
+	return s.orders.FindByUser(ctx, userID, limit)
+}
+
+// HandlePaymentEvent settles the order of a verified gateway callback. A confirmed payment sells
+// the order's tickets; a failed one releases them. Repeated callbacks leave a settled order as is.
+func (s *OrderService) HandlePaymentEvent(ctx context.Context, payload []byte, signature string) (*domain.Order, error) {
+	event, err := s.gateway.ParseEvent(payload, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orders.FindByPaymentID(ctx, event.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case event.Status == domain.PaymentSucceeded && order.Status == domain.OrderStatusPending:
+		return s.confirmPayment(ctx, order)
+	case event.Status == domain.PaymentSucceeded && order.Status == domain.OrderStatusFailed:
+		// เงินเข้ามาหลังจากคำสั่งซื้อหมดเวลาไปแล้ว ตั๋วถูกปล่อยคืนไปแล้วจึงต้องคืนเงินทั้งหมด
+		return s.refund(ctx, order, order.TicketIDs(), domain.OrderStatusFailed)
+	case event.Status == domain.PaymentFailed && order.Status == domain.OrderStatusPending:
+		reason := event.Reason
+		if reason == "" {
+			reason = "payment failed"
+		}
+		return s.failOrder(ctx, order, reason)
+	}
+	return order, nil
+}
+
+// ExpirePendingOrders fails pending orders whose payment window has closed and releases their tickets
+func (s *OrderService) ExpirePendingOrders(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		expired, err := s.orders.FindExpiredPending(ctx, time.Now(), expiredOrderBatchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, order := range expired {
+			if _, err := s.failOrder(ctx, order, "payment window expired"); err != nil {
+				if errors.Is(err, domain.ErrOrderStatusConflict) {
+					// Callback มาถึงพร้อมกันและจัดการคำสั่งซื้อไปแล้ว
+					continue
+				}
+				return total, err
+			}
+			total++
+		}
+		if len(expired) < expiredOrderBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// confirmPayment sells every ticket of a paid order. Tickets whose hold was lost in the meantime
+// cannot be sold, so their price is refunded.
+func (s *OrderService) confirmPayment(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	if err := order.MarkPaid(time.Now()); err != nil {
+		return nil, err
+	}
+
+	var sold, unsold []string
+	for _, id := range order.TicketIDs() {
+		if _, err := s.repo.MarkAsSold(ctx, id, order.UserID, order.DrawID); err != nil {
+			logger.Error("Failed to sell paid lottery ticket", map[string]interface{}{
+				"order_id":  order.ID,
+				"ticket_id": id,
+				"error":     err.Error(),
+			})
+			unsold = append(unsold, id)
+			continue
+		}
+		sold = append(sold, id)
+	}
+	releaseQuota(ctx, s.quota, order.UserID, sold...)
+
+	if len(unsold) == 0 {
+		if err := s.orders.Update(ctx, order, domain.OrderStatusPending); err != nil {
+			return nil, err
+		}
+		return order, nil
+	}
+	return s.refund(ctx, order, unsold, domain.OrderStatusPending)
+}
+
+// refund returns the price of ticketIDs. The order is stored before the gateway is asked to pay
+// back, so a callback processed twice cannot refund twice.
+func (s *OrderService) refund(ctx context.Context, order *domain.Order, ticketIDs []string, from domain.OrderStatus) (*domain.Order, error) {
+	amount, err := order.Refund(ticketIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orders.Update(ctx, order, from); err != nil {
+		return nil, err
+	}
+
+	if amount > 0 {
+		if err := s.gateway.Refund(ctx, order.PaymentID, amount); err != nil {
+			// คำสั่งซื้อถูกบันทึกว่าคืนเงินแล้ว จึงต้องตามคืนเงินจาก Log
+			logger.Error("Failed to refund order payment", map[string]interface{}{
+				"order_id":   order.ID,
+				"payment_id": order.PaymentID,
+				"amount":     amount,
+				"error":      err.Error(),
+			})
+		}
+	}
+	return order, nil
+}
+
+// failOrder gives up on a pending order and puts its tickets back on sale
+func (s *OrderService) failOrder(ctx context.Context, order *domain.Order, reason string) (*domain.Order, error) {
+	if err := order.MarkFailed(reason, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.orders.Update(ctx, order, domain.OrderStatusPending); err != nil {
+		return nil, err
+	}
+
+	released := make([]string, 0, len(order.Items))
+	for _, id := range order.TicketIDs() {
+		ticket, err := s.repo.ReleaseReservation(ctx, id, order.UserID)
+		if err != nil {
+			// ตั๋วที่ผู้ใช้ปล่อยเองหรือหมดเวลาไปแล้วไม่ต้องปล่อยซ้ำ
+			if !errors.Is(err, domain.ErrTicketNotReserved) {
+				logger.Error("Failed to release lottery ticket of failed order", map[string]interface{}{
+					"order_id":  order.ID,
+					"ticket_id": id,
+					"error":     err.Error(),
+				})
+			}
+			continue
+		}
+		released = append(released, ticket.ID)
+	}
+	releaseQuota(ctx, s.quota, order.UserID, released...)
+
+	return order, nil
+}
+
+// saveOrder stores an order whose failure is already being reported to the caller
+func (s *OrderService) saveOrder(ctx context.Context, order *domain.Order, from domain.OrderStatus) {
+	if err := s.orders.Update(ctx, order, from); err != nil {
+		logger.Error("Failed to update order", map[string]interface{}{
+			"order_id": order.ID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
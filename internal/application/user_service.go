@@ -6,17 +6,20 @@ import (
 
 	"github.com/backend-challenge/user-api/internal/domain"
 	"github.com/backend-challenge/user-api/internal/ports"
+	"github.com/backend-challenge/user-api/pkg/logger"
 	"github.com/backend-challenge/user-api/pkg/validator"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	repo ports.UserRepository
+	repo     ports.UserRepository
+	security ports.AccountSecurity
 }
 
-func NewUserService(repo ports.UserRepository) *UserService {
+func NewUserService(repo ports.UserRepository, security ports.AccountSecurity) *UserService {
 	return &UserService{
-		repo: repo,
+		repo:     repo,
+		security: security,
 	}
 }
 
@@ -47,6 +50,7 @@ func (s *UserService) CreateUser(ctx context.Context, name, email, password stri
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	s.sendVerification(ctx, user)
 
 	return user, nil
 }
@@ -73,17 +77,154 @@ func (s *UserService) UpdateUser(ctx context.Context, id, name, email string) (*
 	}
 
 	user.Name = name
-	user.Email = email
+	emailChanged := user.Email != email
+	if emailChanged {
+		user.Email = email
+		user.EmailVerified = false
+	}
 
 	if err := s.repo.Update(ctx, user); err != nil {
 		return nil, err
 	}
+	if emailChanged {
+		s.sendVerification(ctx, user)
+	}
 
 	return user, nil
 }
 
+// PatchUser updates only the fields that are given, leaving nil fields unchanged
+func (s *UserService) PatchUser(ctx context.Context, id string, name, email *string) (*domain.User, error) {
+	if name == nil && email == nil {
+		return nil, fmt.Errorf("%w: name or email is required", domain.ErrRequestInvalid)
+	}
+	if name != nil && !validator.ValidateRequired(*name) {
+		return nil, fmt.Errorf("%w: name is required", domain.ErrRequestInvalid)
+	}
+	if email != nil && !validator.ValidateEmail(*email) {
+		return nil, fmt.Errorf("%w: invalid email format", domain.ErrRequestInvalid)
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		user.Name = *name
+	}
+	emailChanged := email != nil && user.Email != *email
+	if emailChanged {
+		user.Email = *email
+		user.EmailVerified = false
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	if emailChanged {
+		s.sendVerification(ctx, user)
+	}
+
+	return user, nil
+}
+
+// sendVerification sends a verification link for the new email of a user. The user is saved
+// already, so a failed send is only logged and the user can ask for a new link.
+func (s *UserService) sendVerification(ctx context.Context, user *domain.User) {
+	if err := s.security.SendVerification(ctx, user); err != nil {
+		logger.Error("Failed to send email verification", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": user.ID,
+		})
+	}
+}
+
+// UpdateUserRoles replaces the roles of a user. Every account keeps the user role.
+// Sessions carry the roles they were issued with, so a change signs the user out everywhere.
+func (s *UserService) UpdateUserRoles(ctx context.Context, id string, names []string) (*domain.User, error) {
+	roles := domain.DefaultRoles()
+	for _, name := range names {
+		role, ok := domain.ParseRole(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown role %q", domain.ErrRequestInvalid, name)
+		}
+		if !domain.HasAnyRole(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sameRoles(user.Roles, roles) {
+		return user, nil
+	}
+	if err := s.changeRoles(ctx, user, roles); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// changeRoles saves the new roles of a user and signs them out everywhere, so no session keeps
+// the roles it was issued with
+func (s *UserService) changeRoles(ctx context.Context, user *domain.User, roles []domain.Role) error {
+	if err := s.repo.UpdateRoles(ctx, user.ID, roles); err != nil {
+		return err
+	}
+	user.Roles = roles
+
+	_, err := s.security.RevokeAllSessions(ctx, user.ID, "")
+	return err
+}
+
+// sameRoles reports whether a and b hold the same roles in any order
+func sameRoles(a, b []domain.Role) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, role := range a {
+		if !domain.HasAnyRole(b, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// GrantAdmin gives the admin role to the existing accounts with the given emails, so a
+// deployment always has an account that can assign roles. Like any role change, a promotion
+// signs the account out everywhere. It returns how many were promoted.
+func (s *UserService) GrantAdmin(ctx context.Context, emails []string) (int, error) {
+	promoted := 0
+	for _, email := range emails {
+		user, err := s.repo.FindByEmail(ctx, email)
+		if err == domain.ErrUserNotFound {
+			continue
+		}
+		if err != nil {
+			return promoted, err
+		}
+		if user.HasRole(domain.RoleAdmin) {
+			continue
+		}
+		if err := s.changeRoles(ctx, user, append(user.Roles, domain.RoleAdmin)); err != nil {
+			return promoted, err
+		}
+		promoted++
+	}
+	return promoted, nil
+}
+
+// DeleteUser deletes an account and revokes every one of its sessions, so its tokens stop working
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	_, err := s.security.RevokeAllSessions(ctx, id, "")
+	return err
 }
 
 func (s *UserService) GetUserCount(ctx context.Context) (int64, error) {
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+)
+
+// AccountPolicy configures how account recovery tokens are issued
+type AccountPolicy struct {
+	// PasswordResetTTL is how long a password reset token can be used
+	PasswordResetTTL time.Duration
+	// PasswordResetURL is the page the reset link points to. The token is appended to it.
+	PasswordResetURL string
+	// EmailVerificationTTL is how long an email verification token can be used
+	EmailVerificationTTL time.Duration
+	// EmailVerificationURL is the verification endpoint the link points to. The token is appended to it.
+	EmailVerificationURL string
+}
+
+func DefaultAccountPolicy() AccountPolicy {
+	return AccountPolicy{
+		PasswordResetTTL:     30 * time.Minute,
+		PasswordResetURL:     "http://localhost:8080/reset-password?token=",
+		EmailVerificationTTL: 24 * time.Hour,
+		EmailVerificationURL: "http://localhost:8080/api/v1/auth/verify?token=",
+	}
+}
+
+// NotificationKind tells what a notification is about, so a notifier can pick a template
+type NotificationKind string
+
+const (
+	NotificationPasswordReset     NotificationKind = "password_reset"
+	NotificationEmailVerification NotificationKind = "email_verification"
+)
+
+// Notification is a message delivered to a user outside the API, such as an email
+type Notification struct {
+	Kind    NotificationKind
+	To      string
+	Subject string
+	Body    string
+}
@@ -23,4 +23,34 @@ var (
 	ErrInvalidToken       = NewAppError("INVALID_TOKEN", "invalid token")
 	ErrTokenBlacklisted   = NewAppError("TOKEN_BLACKLISTED", "token has been blacklisted")
 	ErrRequestInvalid     = NewAppError("INVALID_INPUT", "request invalid")
+	ErrForbidden          = NewAppError("FORBIDDEN", "you are not allowed to perform this action")
+	ErrInvalidResetToken  = NewAppError("INVALID_RESET_TOKEN", "password reset token is invalid or has expired")
+	ErrInvalidVerifyToken = NewAppError("INVALID_VERIFICATION_TOKEN", "email verification token is invalid or has expired")
+	ErrEmailVerified      = NewAppError("EMAIL_ALREADY_VERIFIED", "email has already been verified")
+	ErrEmailNotVerified   = NewAppError("EMAIL_NOT_VERIFIED", "verify your email before reserving lottery tickets")
+	ErrSessionNotFound    = NewAppError("SESSION_NOT_FOUND", "session not found")
+
+	ErrTicketNotFound     = NewAppError("TICKET_NOT_FOUND", "lottery ticket not found")
+	ErrTicketNotReserved  = NewAppError("TICKET_NOT_RESERVED", "lottery ticket is not reserved by user")
+	ErrReservationExpired = NewAppError("RESERVATION_EXPIRED", "lottery reservation has expired")
+	ErrTicketAlreadySold  = NewAppError("TICKET_ALREADY_SOLD", "lottery ticket has already been sold")
+	ErrTicketReserved     = NewAppError("TICKET_RESERVED", "lottery ticket is reserved by another user")
+	ErrQuotaExceeded      = NewAppError("RESERVATION_QUOTA_EXCEEDED", "maximum number of held tickets reached")
+	ErrHoldNotExtendable  = NewAppError("HOLD_NOT_EXTENDABLE", "reservation hold can no longer be extended")
+	ErrNotEnoughCopies    = NewAppError("NOT_ENOUGH_COPIES", "not enough copies of the lottery number are available")
+	ErrSetUnavailable     = NewAppError("SET_UNAVAILABLE", "the whole set of the lottery number is no longer available")
+
+	ErrDrawNotFound     = NewAppError("DRAW_NOT_FOUND", "lottery draw not found")
+	ErrDrawExists       = NewAppError("DRAW_EXISTS", "a lottery draw already exists for this date")
+	ErrDrawAlreadyDrawn = NewAppError("DRAW_ALREADY_DRAWN", "lottery draw results have already been recorded")
+	ErrDrawNotDrawn     = NewAppError("DRAW_NOT_DRAWN", "lottery draw results have not been recorded yet")
+	ErrSalesClosed      = NewAppError("SALES_CLOSED", "lottery sales are closed for this draw")
+
+	ErrOrderNotFound       = NewAppError("ORDER_NOT_FOUND", "order not found")
+	ErrOrderStatusConflict = NewAppError("ORDER_STATUS_CONFLICT", "order status does not allow this change")
+	ErrTicketInOrder       = NewAppError("TICKET_IN_ORDER", "lottery ticket is already in a pending order")
+	ErrInvalidWebhook      = NewAppError("INVALID_WEBHOOK", "payment callback could not be verified")
+
+	ErrIdempotencyInProgress = NewAppError("IDEMPOTENCY_IN_PROGRESS", "a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused  = NewAppError("IDEMPOTENCY_KEY_REUSED", "idempotency key was already used for a different request")
 )
@@ -0,0 +1,13 @@
+package domain
+
+// IdempotentResponse is a response stored under an Idempotency-Key so a retried request
+// gets the original result instead of running again
+type IdempotentResponse struct {
+	// Fingerprint identifies the request the key was first used with
+	Fingerprint string `json:"fingerprint"`
+	// Completed is false while the first request is still running
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
@@ -12,12 +12,67 @@ const (
 	LotteryStatusSold      LotteryStatus = "sold"
 )
 
+// LotteryTicket is one physical copy of a number. A draw round can stock several copies
+// (sets) of every number, each reserved and sold on its own.
 type LotteryTicket struct {
-	ID            string
-	Number        string
-	Status        LotteryStatus
-	ReservedUntil *time.Time
-	ReservedBy    string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID     string
+	Number string
+	// Set is the copy of Number within its draw round, starting at 1
+	Set            int
+	Status         LotteryStatus
+	ReservedUntil  *time.Time
+	ReservedBy     string
+	HoldExtensions int
+	// DrawID is the draw round whose inventory the ticket belongs to
+	DrawID    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsAvailable reports whether the ticket can be reserved at the given time
+func (t *LotteryTicket) IsAvailable(now time.Time) bool {
+	if t.Status == LotteryStatusAvailable {
+		return true
+	}
+	return t.Status == LotteryStatusReserved && t.ReservedUntil != nil && t.ReservedUntil.Before(now)
+}
+
+// LotteryCursor is the last copy on a browse page. A zero Set continues after every copy of Number.
+type LotteryCursor struct {
+	Number string
+	Set    int
+}
+
+// LotteryPage is one page of browse results
+type LotteryPage struct {
+	Tickets    []LotteryTicket
+	Total      int64
+	NextCursor string
+}
+
+// ReservationPolicy holds the configurable rules for holding lottery tickets
+type ReservationPolicy struct {
+	TTL               time.Duration
+	MaxTicketsPerUser int
+	ExtensionDuration time.Duration
+	MaxExtensions     int
+}
+
+func DefaultReservationPolicy() ReservationPolicy {
+	return ReservationPolicy{
+		TTL:               5 * time.Minute,
+		MaxTicketsPerUser: 10,
+		ExtensionDuration: 5 * time.Minute,
+		MaxExtensions:     1,
+	}
+}
+
+// Cart is the set of tickets a user currently holds
+type Cart struct {
+	UserID     string
+	Tickets    []LotteryTicket
+	MaxTickets int
+	ExpiresAt  *time.Time
+	// CanExtend reports whether at least one held ticket can still be extended
+	CanExtend bool
 }
@@ -0,0 +1,321 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type DrawStatus string
+
+const (
+	DrawStatusScheduled DrawStatus = "scheduled"
+	DrawStatusDrawn     DrawStatus = "drawn"
+)
+
+// DrawLocation is the time zone draw dates are announced in (Thailand, UTC+7)
+var DrawLocation = time.FixedZone("ICT", 7*60*60)
+
+// drawHour is the local hour results are announced on a draw date
+const drawHour = 16
+
+type PrizeTier string
+
+const (
+	PrizeFirst         PrizeTier = "first"
+	PrizeFirstAdjacent PrizeTier = "first_adjacent"
+	PrizeSecond        PrizeTier = "second"
+	PrizeThird         PrizeTier = "third"
+	PrizeFourth        PrizeTier = "fourth"
+	PrizeFifth         PrizeTier = "fifth"
+	PrizeFirstThree    PrizeTier = "first_three"
+	PrizeLastThree     PrizeTier = "last_three"
+	PrizeLastTwo       PrizeTier = "last_two"
+)
+
+// PrizeMatch is the part of a ticket number compared against a tier's winning numbers
+type PrizeMatch string
+
+const (
+	PrizeMatchFull   PrizeMatch = "full"
+	PrizeMatchPrefix PrizeMatch = "prefix"
+	PrizeMatchSuffix PrizeMatch = "suffix"
+)
+
+// PrizeTierRule describes how many winning numbers a tier has and what it pays per ticket
+type PrizeTierRule struct {
+	Tier   PrizeTier
+	Match  PrizeMatch
+	Digits int
+	Count  int
+	Amount int64
+}
+
+// PrizeTierRules follow the Thai government lottery. Amounts are in baht per ticket.
+var PrizeTierRules = []PrizeTierRule{
+	{Tier: PrizeFirst, Match: PrizeMatchFull, Digits: 6, Count: 1, Amount: 6000000},
+	{Tier: PrizeFirstAdjacent, Match: PrizeMatchFull, Digits: 6, Count: 2, Amount: 100000},
+	{Tier: PrizeSecond, Match: PrizeMatchFull, Digits: 6, Count: 5, Amount: 200000},
+	{Tier: PrizeThird, Match: PrizeMatchFull, Digits: 6, Count: 10, Amount: 80000},
+	{Tier: PrizeFourth, Match: PrizeMatchFull, Digits: 6, Count: 50, Amount: 40000},
+	{Tier: PrizeFifth, Match: PrizeMatchFull, Digits: 6, Count: 100, Amount: 20000},
+	{Tier: PrizeFirstThree, Match: PrizeMatchPrefix, Digits: 3, Count: 2, Amount: 4000},
+	{Tier: PrizeLastThree, Match: PrizeMatchSuffix, Digits: 3, Count: 2, Amount: 4000},
+	{Tier: PrizeLastTwo, Match: PrizeMatchSuffix, Digits: 2, Count: 1, Amount: 2000},
+}
+
+// LotteryPrize is one tier's winning numbers for a draw
+type LotteryPrize struct {
+	Tier    PrizeTier
+	Numbers []string
+	Amount  int64
+}
+
+// LotteryDraw is a single draw round. Every round has its own ticket inventory, which is
+// sold between SalesOpenAt and SalesCloseAt and checked against the round's results.
+type LotteryDraw struct {
+	ID           string
+	DrawDate     time.Time
+	Status       DrawStatus
+	SalesOpenAt  time.Time
+	SalesCloseAt time.Time
+	TicketCount  int
+	// SetsPerNumber is how many copies of every number the round stocks
+	SetsPerNumber int
+	// TicketPrice is what one copy of a number costs in this round, in baht
+	TicketPrice int64
+	// StockedAt is set once the round's ticket inventory has been created
+	StockedAt *time.Time
+	Prizes    []LotteryPrize
+	DrawnAt   *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DrawTemplate holds the settings copied into each new round
+type DrawTemplate struct {
+	TicketCount   int
+	SetsPerNumber int
+	TicketPrice   int64
+	// SalesOpenLead is how long before the draw sales open
+	SalesOpenLead time.Duration
+	// SalesCloseLead is how long before the draw sales close
+	SalesCloseLead time.Duration
+}
+
+// DefaultDrawTemplate opens sales a little over two weeks before the draw, so the next round
+// is already selling when the previous one closes.
+func DefaultDrawTemplate() DrawTemplate {
+	return DrawTemplate{
+		TicketCount:    1000000,
+		SetsPerNumber:  1,
+		TicketPrice:    DefaultTicketPrice,
+		SalesOpenLead:  17 * 24 * time.Hour,
+		SalesCloseLead: 1 * time.Hour,
+	}
+}
+
+// DefaultTicketPrice is the price of one ticket in baht set by the Thai government lottery
+const DefaultTicketPrice int64 = 80
+
+// maxSetsPerNumber bounds the copies stocked per number, which multiply the inventory size
+const maxSetsPerNumber = 100
+
+// Validate checks the template can produce a sellable round
+func (t DrawTemplate) Validate() error {
+	if t.TicketCount <= 0 || t.TicketCount > 1000000 {
+		return fmt.Errorf("%w: ticket count must be between 1 and 1000000", ErrRequestInvalid)
+	}
+	if t.SetsPerNumber <= 0 || t.SetsPerNumber > maxSetsPerNumber {
+		return fmt.Errorf("%w: sets per number must be between 1 and %d", ErrRequestInvalid, maxSetsPerNumber)
+	}
+	if t.TicketPrice <= 0 {
+		return fmt.Errorf("%w: ticket price must be positive", ErrRequestInvalid)
+	}
+	if t.SalesCloseLead < 0 || t.SalesOpenLead <= t.SalesCloseLead {
+		return fmt.Errorf("%w: sales must open before they close", ErrRequestInvalid)
+	}
+	return nil
+}
+
+// NewLotteryDraw creates a scheduled round announced on the given day
+func NewLotteryDraw(day time.Time, template DrawTemplate) *LotteryDraw {
+	day = day.In(DrawLocation)
+	drawDate := time.Date(day.Year(), day.Month(), day.Day(), drawHour, 0, 0, 0, DrawLocation)
+	return &LotteryDraw{
+		DrawDate:      drawDate,
+		Status:        DrawStatusScheduled,
+		SalesOpenAt:   drawDate.Add(-template.SalesOpenLead),
+		SalesCloseAt:  drawDate.Add(-template.SalesCloseLead),
+		TicketCount:   template.TicketCount,
+		SetsPerNumber: template.SetsPerNumber,
+		TicketPrice:   template.TicketPrice,
+	}
+}
+
+// Template returns the settings of this round, used to create the next one
+func (d *LotteryDraw) Template() DrawTemplate {
+	return DrawTemplate{
+		TicketCount:    d.TicketCount,
+		SetsPerNumber:  d.SetsPerNumber,
+		TicketPrice:    d.TicketPrice,
+		SalesOpenLead:  d.DrawDate.Sub(d.SalesOpenAt),
+		SalesCloseLead: d.DrawDate.Sub(d.SalesCloseAt),
+	}
+}
+
+// IsOnSale reports whether tickets of this round can be reserved and bought at now
+func (d *LotteryDraw) IsOnSale(now time.Time) bool {
+	return d.Status == DrawStatusScheduled &&
+		d.StockedAt != nil &&
+		!now.Before(d.SalesOpenAt) &&
+		now.Before(d.SalesCloseAt)
+}
+
+// SetSalesWindow moves the sales window. Sales must close no later than the draw.
+func (d *LotteryDraw) SetSalesWindow(openAt, closeAt time.Time) error {
+	if d.Status != DrawStatusScheduled {
+		return ErrDrawAlreadyDrawn
+	}
+	if !openAt.Before(closeAt) {
+		return fmt.Errorf("%w: sales must open before they close", ErrRequestInvalid)
+	}
+	if closeAt.After(d.DrawDate) {
+		return fmt.Errorf("%w: sales must close before the draw", ErrRequestInvalid)
+	}
+	d.SalesOpenAt = openAt
+	d.SalesCloseAt = closeAt
+	return nil
+}
+
+// NextDrawDate returns the first regular draw (the 1st or 16th of a month) after t
+func NextDrawDate(t time.Time) time.Time {
+	local := t.In(DrawLocation)
+	for _, month := range []time.Month{local.Month(), local.Month() + 1} {
+		for _, day := range []int{1, 16} {
+			date := time.Date(local.Year(), month, day, drawHour, 0, 0, 0, DrawLocation)
+			if date.After(t) {
+				return date
+			}
+		}
+	}
+	// ไม่ถึงบรรทัดนี้ เพราะวันที่ 1 ของเดือนถัดไปอยู่หลัง t เสมอ
+	return time.Date(local.Year(), local.Month()+2, 1, drawHour, 0, 0, 0, DrawLocation)
+}
+
+// RecordResults validates the winning numbers for every tier and marks the draw as drawn.
+// The first prize adjacent numbers are derived from the first prize when not given.
+func (d *LotteryDraw) RecordResults(results map[PrizeTier][]string, now time.Time) error {
+	if d.Status == DrawStatusDrawn {
+		return ErrDrawAlreadyDrawn
+	}
+
+	known := make(map[PrizeTier]bool, len(PrizeTierRules))
+	prizes := make([]LotteryPrize, 0, len(PrizeTierRules))
+	for _, rule := range PrizeTierRules {
+		known[rule.Tier] = true
+		numbers, ok := results[rule.Tier]
+		if !ok && rule.Tier == PrizeFirstAdjacent && len(results[PrizeFirst]) == 1 {
+			numbers = adjacentNumbers(results[PrizeFirst][0])
+		}
+		if len(numbers) != rule.Count {
+			return fmt.Errorf("%w: %s prize must have %d numbers", ErrRequestInvalid, rule.Tier, rule.Count)
+		}
+		seen := make(map[string]bool, len(numbers))
+		for _, n := range numbers {
+			if len(n) != rule.Digits || !isDigits(n) {
+				return fmt.Errorf("%w: %s prize numbers must be %d digits", ErrRequestInvalid, rule.Tier, rule.Digits)
+			}
+			if seen[n] {
+				return fmt.Errorf("%w: %s prize number %s is repeated", ErrRequestInvalid, rule.Tier, n)
+			}
+			seen[n] = true
+		}
+		prizes = append(prizes, LotteryPrize{
+			Tier:    rule.Tier,
+			Numbers: append([]string(nil), numbers...),
+			Amount:  rule.Amount,
+		})
+	}
+	for tier := range results {
+		if !known[tier] {
+			return fmt.Errorf("%w: unknown prize tier %q", ErrRequestInvalid, tier)
+		}
+	}
+
+	d.Prizes = prizes
+	d.Status = DrawStatusDrawn
+	d.DrawnAt = &now
+	return nil
+}
+
+// PrizesFor returns every prize the number wins in this draw. A ticket can win more than
+// one tier, e.g. the first prize also matches the last two digits.
+func (d *LotteryDraw) PrizesFor(number string) []LotteryPrize {
+	var won []LotteryPrize
+	for _, prize := range d.Prizes {
+		rule, ok := prizeTierRule(prize.Tier)
+		if !ok || len(number) < rule.Digits {
+			continue
+		}
+
+		part := number
+		switch rule.Match {
+		case PrizeMatchPrefix:
+			part = number[:rule.Digits]
+		case PrizeMatchSuffix:
+			part = number[len(number)-rule.Digits:]
+		}
+		for _, winning := range prize.Numbers {
+			if winning == part {
+				won = append(won, LotteryPrize{Tier: prize.Tier, Numbers: []string{winning}, Amount: prize.Amount})
+				break
+			}
+		}
+	}
+	return won
+}
+
+func prizeTierRule(tier PrizeTier) (PrizeTierRule, bool) {
+	for _, rule := range PrizeTierRules {
+		if rule.Tier == tier {
+			return rule, true
+		}
+	}
+	return PrizeTierRule{}, false
+}
+
+// adjacentNumbers returns the numbers either side of n, wrapping at 000000 and 999999
+func adjacentNumbers(n string) []string {
+	value, err := strconv.Atoi(n)
+	if err != nil || len(n) != LotteryNumberLength {
+		return nil
+	}
+	const span = 1000000
+	return []string{
+		fmt.Sprintf("%06d", (value+span-1)%span),
+		fmt.Sprintf("%06d", (value+1)%span),
+	}
+}
+
+// TicketCheck is a sold ticket and the prizes it won in a draw
+type TicketCheck struct {
+	Ticket LotteryTicket
+	Prizes []LotteryPrize
+}
+
+// Winnings returns the total prize amount for the ticket
+func (t TicketCheck) Winnings() int64 {
+	var total int64
+	for _, p := range t.Prizes {
+		total += p.Amount
+	}
+	return total
+}
+
+// DrawCheckResult is a user's tickets for a draw checked against its results
+type DrawCheckResult struct {
+	Draw          *LotteryDraw
+	Tickets       []TicketCheck
+	TotalWinnings int64
+}
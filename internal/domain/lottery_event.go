@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"time"
+)
+
+type LotteryEventType string
+
+const (
+	LotteryEventReserved LotteryEventType = "reserved"
+	LotteryEventReleased LotteryEventType = "released"
+	LotteryEventSold     LotteryEventType = "sold"
+	// LotteryEventExpired is a hold released by the system after it ran out. Watchers see it as released.
+	LotteryEventExpired LotteryEventType = "expired"
+)
+
+// LotteryActorSystem is the actor of changes nobody asked for, such as expired holds
+const LotteryActorSystem = "system"
+
+// LotteryEvent is a change of a ticket's availability. It does not say who holds the ticket.
+type LotteryEvent struct {
+	Type     LotteryEventType
+	TicketID string
+	DrawID   string
+	Number   string
+	Set      int
+	// ReservedUntil is when a reserved ticket goes back on sale unless it is bought
+	ReservedUntil *time.Time
+	At            time.Time
+}
+
+// NewLotteryEvent describes a change of ticket
+func NewLotteryEvent(eventType LotteryEventType, ticket *LotteryTicket, at time.Time) LotteryEvent {
+	event := LotteryEvent{
+		Type:     eventType,
+		TicketID: ticket.ID,
+		DrawID:   ticket.DrawID,
+		Number:   ticket.Number,
+		Set:      ticket.Set,
+		At:       at,
+	}
+	if eventType == LotteryEventReserved {
+		event.ReservedUntil = ticket.ReservedUntil
+	}
+	return event
+}
+
+// LotteryHistoryEntry is one change of a ticket in its append-only audit trail
+type LotteryHistoryEntry struct {
+	ID       string
+	TicketID string
+	DrawID   string
+	Number   string
+	Set      int
+	Type     LotteryEventType
+	// Actor is the user whose request made the change, or LotteryActorSystem
+	Actor      string
+	FromStatus LotteryStatus
+	ToStatus   LotteryStatus
+	// ReservedUntil is the end of the hold a reserved entry started
+	ReservedUntil *time.Time
+	At            time.Time
+}
@@ -0,0 +1,325 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LotteryNumberLength is the number of digits on every lottery ticket
+const LotteryNumberLength = 6
+
+const (
+	maxPatternLength = 64
+	allDigits        = uint16(1<<10 - 1)
+)
+
+// LotteryPattern is a validated search pattern compiled into per-position digit sets.
+//
+// A pattern is one or more comma separated terms:
+//   - positional: 6 tokens, each a digit, "*" for any digit or a set such as "[1-3]" or "[^4]"
+//   - prefix:<tokens>   starts with, e.g. "prefix:12"
+//   - suffix:<tokens>   ends with, e.g. "suffix:23"
+//   - contains:<digits> contains the digits anywhere, e.g. "contains:77"
+//   - exclude:<digits>  none of the digits appear, e.g. "exclude:4"
+//
+// Equivalent patterns ("****23" and "suffix:23") share the same Key.
+type LotteryPattern struct {
+	positions [LotteryNumberLength]uint16
+	contains  []string
+}
+
+// ParseLotteryPattern validates raw and compiles it into a LotteryPattern
+func ParseLotteryPattern(raw string) (*LotteryPattern, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: pattern is required", ErrRequestInvalid)
+	}
+	if len(raw) > maxPatternLength {
+		return nil, fmt.Errorf("%w: pattern must be at most %d characters", ErrRequestInvalid, maxPatternLength)
+	}
+
+	p := &LotteryPattern{}
+	for i := range p.positions {
+		p.positions[i] = allDigits
+	}
+
+	for _, term := range strings.Split(raw, ",") {
+		if err := p.applyTerm(strings.TrimSpace(term)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, mask := range p.positions {
+		if mask == 0 {
+			return nil, fmt.Errorf("%w: pattern can never match a number", ErrRequestInvalid)
+		}
+	}
+	for _, c := range p.contains {
+		if !p.canContain(c) {
+			return nil, fmt.Errorf("%w: pattern can never contain %s", ErrRequestInvalid, c)
+		}
+	}
+
+	sort.Strings(p.contains)
+	return p, nil
+}
+
+func (p *LotteryPattern) applyTerm(term string) error {
+	name, value, hasName := strings.Cut(term, ":")
+	if !hasName {
+		masks, err := parseDigitTokens(term)
+		if err != nil {
+			return err
+		}
+		if len(masks) != LotteryNumberLength {
+			return fmt.Errorf("%w: pattern must be %d characters", ErrRequestInvalid, LotteryNumberLength)
+		}
+		p.restrict(0, masks)
+		return nil
+	}
+
+	switch name {
+	case "prefix", "suffix":
+		masks, err := parseDigitTokens(value)
+		if err != nil {
+			return err
+		}
+		if len(masks) == 0 || len(masks) > LotteryNumberLength {
+			return fmt.Errorf("%w: %s must be 1 to %d characters", ErrRequestInvalid, name, LotteryNumberLength)
+		}
+		offset := 0
+		if name == "suffix" {
+			offset = LotteryNumberLength - len(masks)
+		}
+		p.restrict(offset, masks)
+	case "contains":
+		if !isDigits(value) || len(value) > LotteryNumberLength {
+			return fmt.Errorf("%w: contains must be 1 to %d digits", ErrRequestInvalid, LotteryNumberLength)
+		}
+		for _, c := range p.contains {
+			if c == value {
+				return nil
+			}
+		}
+		p.contains = append(p.contains, value)
+	case "exclude":
+		if !isDigits(value) {
+			return fmt.Errorf("%w: exclude must be digits", ErrRequestInvalid)
+		}
+		var excluded uint16
+		for _, d := range value {
+			excluded |= 1 << (d - '0')
+		}
+		for i := range p.positions {
+			p.positions[i] &^= excluded
+		}
+	default:
+		return fmt.Errorf("%w: unknown pattern term %q", ErrRequestInvalid, name)
+	}
+
+	return nil
+}
+
+func (p *LotteryPattern) restrict(offset int, masks []uint16) {
+	for i, mask := range masks {
+		p.positions[offset+i] &= mask
+	}
+}
+
+// canContain reports whether any window of the positional sets allows digits
+func (p *LotteryPattern) canContain(digits string) bool {
+	for start := 0; start+len(digits) <= LotteryNumberLength; start++ {
+		ok := true
+		for i, d := range digits {
+			if p.positions[start+i]&(1<<(d-'0')) == 0 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns the normalized form of the pattern, used to share cache pools
+// between equivalent patterns. The key is itself a valid pattern.
+func (p *LotteryPattern) Key() string {
+	var b strings.Builder
+	for _, mask := range p.positions {
+		b.WriteString(renderDigitSet(mask, "*"))
+	}
+	for _, c := range p.contains {
+		b.WriteString(",contains:")
+		b.WriteString(c)
+	}
+	return b.String()
+}
+
+// Regex returns an anchored regular expression matching the pattern
+func (p *LotteryPattern) Regex() string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range p.contains {
+		b.WriteString("(?=.*")
+		b.WriteString(c)
+		b.WriteString(")")
+	}
+	for _, mask := range p.positions {
+		b.WriteString(renderDigitSet(mask, "."))
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// AllowedDigits returns the digits allowed at position i, or nil when any digit is allowed
+func (p *LotteryPattern) AllowedDigits(i int) []int {
+	mask := p.positions[i]
+	if mask == allDigits {
+		return nil
+	}
+
+	digits := make([]int, 0, 10)
+	for d := 0; d < 10; d++ {
+		if mask&(1<<d) != 0 {
+			digits = append(digits, d)
+		}
+	}
+	return digits
+}
+
+// HasContains reports whether the pattern has terms that cannot be expressed per position
+func (p *LotteryPattern) HasContains() bool {
+	return len(p.contains) > 0
+}
+
+// Matches reports whether number satisfies the pattern
+func (p *LotteryPattern) Matches(number string) bool {
+	if len(number) != LotteryNumberLength || !isDigits(number) {
+		return false
+	}
+	for i := 0; i < LotteryNumberLength; i++ {
+		if p.positions[i]&(1<<(number[i]-'0')) == 0 {
+			return false
+		}
+	}
+	for _, c := range p.contains {
+		if !strings.Contains(number, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDigitTokens parses a sequence of digits, "*" and "[...]" sets into digit masks
+func parseDigitTokens(s string) ([]uint16, error) {
+	masks := make([]uint16, 0, LotteryNumberLength)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			masks = append(masks, 1<<(c-'0'))
+		case c == '*':
+			masks = append(masks, allDigits)
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed digit set in pattern", ErrRequestInvalid)
+			}
+			mask, err := parseDigitSet(s[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			masks = append(masks, mask)
+			i += end
+		default:
+			return nil, fmt.Errorf("%w: pattern can only contain digits, * and digit sets", ErrRequestInvalid)
+		}
+	}
+	return masks, nil
+}
+
+// parseDigitSet parses the inside of "[...]", e.g. "1-3", "135" or "^4"
+func parseDigitSet(s string) (uint16, error) {
+	negate := strings.HasPrefix(s, "^")
+	if negate {
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty digit set in pattern", ErrRequestInvalid)
+	}
+
+	var mask uint16
+	for i := 0; i < len(s); i++ {
+		from := s[i]
+		if from < '0' || from > '9' {
+			return 0, fmt.Errorf("%w: digit set can only contain digits and ranges", ErrRequestInvalid)
+		}
+		to := from
+		if i+2 < len(s) && s[i+1] == '-' {
+			to = s[i+2]
+			if to < '0' || to > '9' || to < from {
+				return 0, fmt.Errorf("%w: invalid digit range %c-%c", ErrRequestInvalid, from, to)
+			}
+			i += 2
+		}
+		for d := from; d <= to; d++ {
+			mask |= 1 << (d - '0')
+		}
+	}
+
+	if negate {
+		mask = allDigits &^ mask
+	}
+	return mask, nil
+}
+
+// renderDigitSet renders a digit mask canonically, using any for the full set
+func renderDigitSet(mask uint16, any string) string {
+	if mask == allDigits {
+		return any
+	}
+
+	var digits []byte
+	for d := byte(0); d < 10; d++ {
+		if mask&(1<<d) != 0 {
+			digits = append(digits, '0'+d)
+		}
+	}
+	if len(digits) == 1 {
+		return string(digits)
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < len(digits); {
+		j := i
+		for j+1 < len(digits) && digits[j+1] == digits[j]+1 {
+			j++
+		}
+		switch {
+		case j-i >= 2:
+			b.WriteByte(digits[i])
+			b.WriteByte('-')
+			b.WriteByte(digits[j])
+		default:
+			b.Write(digits[i : j+1])
+		}
+		i = j + 1
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
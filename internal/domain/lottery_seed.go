@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	// LotteryNumberSpace is how many six-digit numbers exist, 000000 to 999999
+	LotteryNumberSpace = 1000000
+	// DefaultSeedBatchSize is how many tickets are written per bulk write when seeding
+	DefaultSeedBatchSize = 10000
+	maxSeedBatchSize     = 100000
+)
+
+// TicketSeedPlan stocks Sets copies of every number from From up to, but not including, To
+// in a draw round. Seeding records its progress after every batch, so a plan that was
+// interrupted resumes where it stopped.
+type TicketSeedPlan struct {
+	DrawID    string
+	From      int
+	To        int
+	Sets      int
+	BatchSize int
+	// Restart ignores recorded progress and seeds the whole range again
+	Restart bool
+}
+
+// NewTicketSeedPlan covers the whole inventory of a draw round
+func NewTicketSeedPlan(draw *LotteryDraw) TicketSeedPlan {
+	return TicketSeedPlan{
+		DrawID:    draw.ID,
+		From:      0,
+		To:        draw.TicketCount,
+		Sets:      draw.SetsPerNumber,
+		BatchSize: DefaultSeedBatchSize,
+	}
+}
+
+func (p TicketSeedPlan) Validate() error {
+	if p.DrawID == "" {
+		return fmt.Errorf("%w: draw id is required", ErrRequestInvalid)
+	}
+	if p.From < 0 || p.To > LotteryNumberSpace || p.From >= p.To {
+		return fmt.Errorf("%w: number range must be within 0 and %d and not empty", ErrRequestInvalid, LotteryNumberSpace)
+	}
+	if p.Sets <= 0 || p.Sets > maxSetsPerNumber {
+		return fmt.Errorf("%w: sets per number must be between 1 and %d", ErrRequestInvalid, maxSetsPerNumber)
+	}
+	if p.BatchSize <= 0 || p.BatchSize > maxSeedBatchSize {
+		return fmt.Errorf("%w: batch size must be between 1 and %d", ErrRequestInvalid, maxSeedBatchSize)
+	}
+	return nil
+}
+
+// Copies is how many tickets the plan stocks
+func (p TicketSeedPlan) Copies() int {
+	return (p.To - p.From) * p.Sets
+}
+
+// NumbersPerBatch is how many numbers one batch writes. A batch always holds every set of its
+// numbers, so a number is never left half stocked between batches.
+func (p TicketSeedPlan) NumbersPerBatch() int {
+	if n := p.BatchSize / p.Sets; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// Covers reports whether the plan stocks the whole inventory of a draw round
+func (p TicketSeedPlan) Covers(draw *LotteryDraw) bool {
+	return p.DrawID == draw.ID && p.From == 0 && p.To >= draw.TicketCount && p.Sets == draw.SetsPerNumber
+}
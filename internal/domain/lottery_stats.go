@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+)
+
+// LotteryStats is a snapshot of the lottery inventory for operations
+type LotteryStats struct {
+	// DrawID scopes the counts to one draw round; empty means the whole inventory
+	DrawID   string
+	Total    int64
+	ByStatus map[LotteryStatus]int64
+	// ActiveReservations are held tickets; ExpiredReservations are holds past their expiry that
+	// the reaper has not released yet. Both are counted under LotteryStatusReserved.
+	ActiveReservations  int64
+	ExpiredReservations int64
+	// SalesPerHour counts tickets sold in each hour since SalesSince, oldest first. Hours
+	// without sales are left out.
+	SalesSince   time.Time
+	SalesPerHour []HourlySales
+	HotPatterns  []PatternSearches
+	Pool         PoolStats
+	GeneratedAt  time.Time
+}
+
+type HourlySales struct {
+	Hour  time.Time
+	Count int64
+}
+
+// PatternSearches is how often a normalized search pattern was searched
+type PatternSearches struct {
+	Pattern  string
+	Searches int64
+}
+
+// PoolStats counts searches served by the Redis pattern pools. A search is a hit when the
+// pool had enough tickets and a miss when it had to fall back to MongoDB.
+type PoolStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate is the share of searches served by the pools, or 0 before any search
+func (p PoolStats) HitRate() float64 {
+	if p.Hits+p.Misses == 0 {
+		return 0
+	}
+	return float64(p.Hits) / float64(p.Hits+p.Misses)
+}
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending  OrderStatus = "pending"
+	OrderStatusPaid     OrderStatus = "paid"
+	OrderStatusFailed   OrderStatus = "failed"
+	OrderStatusRefunded OrderStatus = "refunded"
+)
+
+// OrderCurrency is the currency every ticket price and order total is charged in
+const OrderCurrency = "THB"
+
+// OrderItem is one ticket in an order at the price of its round when the order was placed
+type OrderItem struct {
+	TicketID string
+	Number   string
+	Set      int
+	Price    int64
+	// Refunded is set when the ticket could not be sold after payment and its price was returned
+	Refunded bool
+}
+
+// Order is a checkout of reserved tickets. The tickets stay held while the order is pending
+// and are only sold once its payment is confirmed.
+type Order struct {
+	ID             string
+	UserID         string
+	DrawID         string
+	Items          []OrderItem
+	Total          int64
+	RefundedAmount int64
+	Currency       string
+	Status         OrderStatus
+	// PaymentID is the payment gateway's intent for the order
+	PaymentID   string
+	CheckoutURL string
+	// FailureReason explains why a failed order was not paid
+	FailureReason string
+	// ExpiresAt is when a pending order gives up waiting for its payment
+	ExpiresAt time.Time
+	PaidAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NewOrder prices the user's held tickets of a draw round. The order waits paymentWindow for
+// its payment before it expires.
+func NewOrder(userID string, draw *LotteryDraw, tickets []LotteryTicket, now time.Time, paymentWindow time.Duration) (*Order, error) {
+	if len(tickets) == 0 {
+		return nil, fmt.Errorf("%w: an order needs at least one ticket", ErrRequestInvalid)
+	}
+
+	order := &Order{
+		UserID:    userID,
+		DrawID:    draw.ID,
+		Items:     make([]OrderItem, 0, len(tickets)),
+		Currency:  OrderCurrency,
+		Status:    OrderStatusPending,
+		ExpiresAt: now.Add(paymentWindow),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	seen := make(map[string]bool, len(tickets))
+	for _, t := range tickets {
+		if t.DrawID != draw.ID || t.ReservedBy != userID || t.Status != LotteryStatusReserved {
+			return nil, ErrTicketNotReserved
+		}
+		if seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+
+		order.Items = append(order.Items, OrderItem{
+			TicketID: t.ID,
+			Number:   t.Number,
+			Set:      t.Set,
+			Price:    draw.TicketPrice,
+		})
+		order.Total += draw.TicketPrice
+	}
+	return order, nil
+}
+
+// TicketIDs returns the IDs of every ticket in the order
+func (o *Order) TicketIDs() []string {
+	ids := make([]string, len(o.Items))
+	for i, item := range o.Items {
+		ids[i] = item.TicketID
+	}
+	return ids
+}
+
+// AttachPayment records the gateway intent the order is paid through
+func (o *Order) AttachPayment(intent *PaymentIntent, now time.Time) error {
+	if o.Status != OrderStatusPending {
+		return ErrOrderStatusConflict
+	}
+	o.PaymentID = intent.ID
+	o.CheckoutURL = intent.CheckoutURL
+	o.UpdatedAt = now
+	return nil
+}
+
+// MarkPaid confirms the payment of a pending order
+func (o *Order) MarkPaid(now time.Time) error {
+	if o.Status != OrderStatusPending {
+		return ErrOrderStatusConflict
+	}
+	o.Status = OrderStatusPaid
+	o.PaidAt = &now
+	o.UpdatedAt = now
+	return nil
+}
+
+// MarkFailed gives up on the payment of a pending order
+func (o *Order) MarkFailed(reason string, now time.Time) error {
+	if o.Status != OrderStatusPending {
+		return ErrOrderStatusConflict
+	}
+	o.Status = OrderStatusFailed
+	o.FailureReason = reason
+	o.UpdatedAt = now
+	return nil
+}
+
+// Refund returns the price of the given tickets and reports the amount to pay back. A paid
+// order is refunded when some of its tickets could not be sold; a failed order is refunded when
+// its payment arrives after the order gave up. Once every item is refunded the order is refunded.
+func (o *Order) Refund(ticketIDs []string, now time.Time) (int64, error) {
+	if o.Status != OrderStatusPaid && o.Status != OrderStatusFailed {
+		return 0, ErrOrderStatusConflict
+	}
+
+	refund := make(map[string]bool, len(ticketIDs))
+	for _, id := range ticketIDs {
+		refund[id] = true
+	}
+
+	var amount int64
+	remaining := 0
+	for i := range o.Items {
+		item := &o.Items[i]
+		if refund[item.TicketID] && !item.Refunded {
+			item.Refunded = true
+			amount += item.Price
+		}
+		if !item.Refunded {
+			remaining++
+		}
+	}
+
+	o.RefundedAmount += amount
+	if remaining == 0 {
+		o.Status = OrderStatusRefunded
+	}
+	o.UpdatedAt = now
+	return amount, nil
+}
+
+// PaymentIntent is a payment started with the gateway for an order
+type PaymentIntent struct {
+	ID       string
+	OrderID  string
+	Amount   int64
+	Currency string
+	// CheckoutURL is where the user completes the payment
+	CheckoutURL string
+}
+
+type PaymentEventStatus string
+
+const (
+	PaymentSucceeded PaymentEventStatus = "succeeded"
+	PaymentFailed    PaymentEventStatus = "failed"
+)
+
+// PaymentEvent is a verified callback from the payment gateway about an intent
+type PaymentEvent struct {
+	PaymentID string
+	Status    PaymentEventStatus
+	Reason    string
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// ClientInfo describes the client a user signed in or refreshed their tokens from
+type ClientInfo struct {
+	// Device is a name the client gave itself. It is guessed from UserAgent when empty.
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// LoginSession is one login of a user. It keeps its ID across token refreshes until it is
+// revoked or its refresh token expires.
+type LoginSession struct {
+	ID         string
+	Device     string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	// Current marks the session the listing was requested from
+	Current bool
+}
+
+// DeviceName returns the device the client named, or one guessed from its user agent
+func (c ClientInfo) DeviceName() string {
+	if c.Device != "" {
+		return c.Device
+	}
+	return DeviceFromUserAgent(c.UserAgent)
+}
+
+// DeviceFromUserAgent names the browser and operating system in a user agent,
+// e.g. "Chrome on Windows". It returns "Unknown device" when neither is recognized.
+func DeviceFromUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := ""
+	// ลำดับสำคัญ: Edge และ Opera มีคำว่า Chrome, Chrome มีคำว่า Safari
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	case strings.Contains(ua, "postman"):
+		browser = "Postman"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}
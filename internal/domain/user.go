@@ -4,16 +4,66 @@ import (
 	"time"
 )
 
+// Role grants a user access to a group of endpoints
+type Role string
+
+const (
+	// RoleUser is every registered account. It may only manage its own account.
+	RoleUser Role = "user"
+	// RoleAdmin manages any account, its roles and the lottery draws
+	RoleAdmin Role = "admin"
+	// RoleOperator runs the lottery draws and reads accounts, but cannot change them
+	RoleOperator Role = "operator"
+)
+
+// ParseRole returns the role named by s
+func ParseRole(s string) (Role, bool) {
+	switch role := Role(s); role {
+	case RoleUser, RoleAdmin, RoleOperator:
+		return role, true
+	}
+	return "", false
+}
+
 // User represents the user entity
 type User struct {
-	ID        string
-	Name      string
-	Email     string
-	Password  string
-	CreatedAt time.Time
+	ID       string
+	Name     string
+	Email    string
+	Password string
+	Roles    []Role
+	// EmailVerified is set once the user opens the verification link sent to Email
+	EmailVerified bool
+	CreatedAt     time.Time
+}
+
+// HasRole reports whether the user holds any of the given roles
+func (u *User) HasRole(roles ...Role) bool {
+	return HasAnyRole(u.Roles, roles...)
+}
+
+// HasAnyRole reports whether held contains any of the wanted roles
+func HasAnyRole(held []Role, wanted ...Role) bool {
+	for _, h := range held {
+		for _, w := range wanted {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DefaultRoles are the roles of an account created before roles existed or without any
+func DefaultRoles() []Role {
+	return []Role{RoleUser}
 }
 
 type TokenClaims struct {
 	Subject   string
 	ExpiresAt int64
+	// Roles of the token's user when its session was created
+	Roles []Role
+	// SessionID identifies the login the token was issued to. It stays the same across refreshes.
+	SessionID string
 }
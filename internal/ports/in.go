@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/backend-challenge/user-api/internal/domain"
 )
@@ -11,19 +12,64 @@ type UserService interface {
 	GetUserByID(ctx context.Context, id string) (*domain.User, error)
 	ListUsers(ctx context.Context) ([]*domain.User, error)
 	UpdateUser(ctx context.Context, id, name, email string) (*domain.User, error)
+	PatchUser(ctx context.Context, id string, name, email *string) (*domain.User, error)
 	DeleteUser(ctx context.Context, id string) error
+	UpdateUserRoles(ctx context.Context, id string, roles []string) (*domain.User, error)
 	GetUserCount(ctx context.Context) (int64, error)
 }
 
+// AccountSecurity is what the user service needs from AuthService to keep the sessions and the
+// email verification of an account in line with changes made to it
+type AccountSecurity interface {
+	RevokeAllSessions(ctx context.Context, userID, keepSessionID string) (int, error)
+	SendVerification(ctx context.Context, user *domain.User) error
+}
+
 type AuthService interface {
 	Register(ctx context.Context, name, email, password string) (*domain.User, error)
-	Login(ctx context.Context, email, password string) (string, string, error)
+	Login(ctx context.Context, email, password string, client domain.ClientInfo) (string, string, error)
 	Logout(ctx context.Context, token string) error
 	ValidateToken(ctx context.Context, token string) (*domain.TokenClaims, string, error)
-	RefreshToken(ctx context.Context, refreshToken string) (string, string, error)
+	RefreshToken(ctx context.Context, refreshToken string, client domain.ClientInfo) (string, string, error)
+	ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userID string) error
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.LoginSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID, keepSessionID string) (int, error)
 }
 
 type LotteryService interface {
 	SearchLottery(ctx context.Context, pattern string, userID string) ([]domain.LotteryTicket, error)
+	BrowseLottery(ctx context.Context, pattern string, cursor string, limit int) (*domain.LotteryPage, error)
+	WatchLottery(ctx context.Context, pattern string) (<-chan domain.LotteryEvent, error)
+	GetCart(ctx context.Context, userID string) (*domain.Cart, error)
+	AddToCart(ctx context.Context, number string, copies int, userID string) ([]domain.LotteryTicket, error)
+	ExtendHold(ctx context.Context, userID string) (*domain.Cart, error)
+	ListReservations(ctx context.Context, userID string) ([]domain.LotteryTicket, error)
+	ReleaseReservation(ctx context.Context, ticketID string, userID string) error
+	ReleaseAllReservations(ctx context.Context, userID string) (int64, error)
+	GetTicketHistory(ctx context.Context, ticketID string, userID string) ([]domain.LotteryHistoryEntry, error)
 	GetLotteryCount(ctx context.Context) (int64, error)
+	GetLotteryStats(ctx context.Context, drawID string) (*domain.LotteryStats, error)
+}
+
+type LotteryDrawService interface {
+	CreateDraw(ctx context.Context, drawDate time.Time, template *domain.DrawTemplate) (*domain.LotteryDraw, error)
+	CreateNextDraw(ctx context.Context, template *domain.DrawTemplate) (*domain.LotteryDraw, error)
+	UpdateSalesWindow(ctx context.Context, id string, openAt, closeAt time.Time) (*domain.LotteryDraw, error)
+	GetDraw(ctx context.Context, id string) (*domain.LotteryDraw, error)
+	ListDraws(ctx context.Context, limit int) ([]*domain.LotteryDraw, error)
+	RecordResults(ctx context.Context, id string, results map[domain.PrizeTier][]string) (*domain.LotteryDraw, error)
+	CheckTickets(ctx context.Context, id string, userID string) (*domain.DrawCheckResult, error)
+}
+
+type OrderService interface {
+	Checkout(ctx context.Context, ticketIDs []string, userID string) (*domain.Order, error)
+	CheckoutSet(ctx context.Context, number string, userID string) (*domain.Order, error)
+	GetOrder(ctx context.Context, id string, userID string) (*domain.Order, error)
+	ListOrders(ctx context.Context, userID string, limit int) ([]*domain.Order, error)
+	HandlePaymentEvent(ctx context.Context, payload []byte, signature string) (*domain.Order, error)
 }
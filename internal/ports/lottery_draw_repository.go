@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+type LotteryDrawRepository interface {
+	Create(ctx context.Context, draw *domain.LotteryDraw) error
+	FindByID(ctx context.Context, id string) (*domain.LotteryDraw, error)
+	FindNext(ctx context.Context, after time.Time) (*domain.LotteryDraw, error)
+	FindOnSale(ctx context.Context, now time.Time) (*domain.LotteryDraw, error)
+	FindLatest(ctx context.Context) (*domain.LotteryDraw, error)
+	FindUnstocked(ctx context.Context) ([]*domain.LotteryDraw, error)
+	List(ctx context.Context, limit int) ([]*domain.LotteryDraw, error)
+	MarkStocked(ctx context.Context, id string, stockedAt time.Time) error
+	UpdateSalesWindow(ctx context.Context, draw *domain.LotteryDraw) error
+	SaveResults(ctx context.Context, draw *domain.LotteryDraw) error
+}
@@ -2,14 +2,30 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/backend-challenge/user-api/internal/domain"
 )
 
 type LotteryRepository interface {
-	SearchAndReserve(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error)
+	SearchAndReserve(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error)
+	BrowseTickets(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error)
+	ReserveNumber(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error)
+	ReserveSet(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error)
+	HoldUntil(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error)
+	RestoreHolds(ctx context.Context, tickets []domain.LotteryTicket, userID string) error
+	ExtendReservations(ctx context.Context, userID string, extension time.Duration, maxExtensions int) (int64, error)
 	UpsertMany(ctx context.Context, tickets []domain.LotteryTicket) error
-	MarkAsSold(ctx context.Context, ticketID string, userID string) error
+	MarkAsSold(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error)
+	FindSoldByUser(ctx context.Context, userID string, drawID string) ([]domain.LotteryTicket, error)
+	FindReservationsByUser(ctx context.Context, userID string) ([]domain.LotteryTicket, error)
+	ReleaseReservation(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error)
+	ReleaseAllReservations(ctx context.Context, userID string) (int64, error)
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
 	Count(ctx context.Context) (int64, error)
-	SeedTickets(ctx context.Context, total int) error
+	SeedTickets(ctx context.Context, plan domain.TicketSeedPlan) error
+	AssignUnscopedTickets(ctx context.Context, drawID string) (int64, error)
+	Watch(ctx context.Context, drawID string) (<-chan domain.LotteryEvent, error)
+	FindHistory(ctx context.Context, ticketID string, limit int) ([]domain.LotteryHistoryEntry, error)
+	Stats(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error)
 }
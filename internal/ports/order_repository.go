@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+type OrderRepository interface {
+	Create(ctx context.Context, order *domain.Order) error
+	FindByID(ctx context.Context, id string) (*domain.Order, error)
+	FindByPaymentID(ctx context.Context, paymentID string) (*domain.Order, error)
+	FindByUser(ctx context.Context, userID string, limit int) ([]*domain.Order, error)
+	FindExpiredPending(ctx context.Context, now time.Time, limit int) ([]*domain.Order, error)
+	Update(ctx context.Context, order *domain.Order, from domain.OrderStatus) error
+}
@@ -13,6 +13,11 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindAll(ctx context.Context) ([]*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	UpdateRoles(ctx context.Context, id string, roles []domain.Role) error
+	UpdatePassword(ctx context.Context, id string, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id string, email string) error
+	// MigrateEmailVerified marks accounts created before email verification existed as verified
+	MigrateEmailVerified(ctx context.Context) (int64, error)
 	Delete(ctx context.Context, id string) error
 	Count(ctx context.Context) (int64, error)
 }
@@ -20,10 +25,46 @@ type UserRepository interface {
 type SessionManager interface {
 	StoreSession(ctx context.Context, key string, data interface{}, ttl time.Duration) error
 	GetSession(ctx context.Context, key string) (string, error)
+	// TakeSession returns and deletes a session in one step, so a single-use token is used once
+	TakeSession(ctx context.Context, key string) (string, error)
 	DeleteSession(ctx context.Context, key string) error
+	// IndexUserSession records that a session belongs to a user, so all of a user's sessions can be found.
+	// Indexing a session again updates its client and expiry but keeps its creation time.
+	IndexUserSession(ctx context.Context, userID string, session *domain.LoginSession) error
+	TouchUserSession(ctx context.Context, sessionID string, seenAt time.Time) error
+	UserSessionIDs(ctx context.Context, userID string) ([]string, error)
+	UserSessions(ctx context.Context, userID string) ([]domain.LoginSession, error)
+	UnindexUserSession(ctx context.Context, userID, sessionID string) error
 }
 
 type TokenService interface {
 	GenerateToken(userID, email string, duration time.Duration) (string, error)
 	ValidateToken(tokenString string) (*domain.TokenClaims, error)
 }
+
+type ReservationQuota interface {
+	Acquire(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error)
+	Commit(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error
+	Release(ctx context.Context, userID string, ticketIDs ...string) error
+}
+
+// PaymentGateway starts and refunds order payments and verifies the gateway's callbacks
+type PaymentGateway interface {
+	CreateIntent(ctx context.Context, order *domain.Order) (*domain.PaymentIntent, error)
+	Refund(ctx context.Context, paymentID string, amount int64) error
+	ParseEvent(payload []byte, signature string) (*domain.PaymentEvent, error)
+}
+
+// IdempotencyStore remembers the outcome of requests sent with an Idempotency-Key
+type IdempotencyStore interface {
+	// Begin claims key for a new request, holding it for lockTTL. It returns the stored record
+	// when the key is already claimed or completed, or nil when the caller should run the request.
+	Begin(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, response *domain.IdempotentResponse, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// Notifier delivers messages such as password reset links to users
+type Notifier interface {
+	Send(ctx context.Context, notification domain.Notification) error
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -16,6 +17,35 @@ type Config struct {
 	JWTRefreshTokenSec int
 	ServerPort         string
 	LogLevel           string
+	AdminEmails        []string
+
+	LotteryReservationTTLSec int
+	LotteryMaxTicketsPerUser int
+	LotteryHoldExtensionSec  int
+	LotteryMaxHoldExtensions int
+	LotteryPrefillWorkers    int
+	LotteryDrawTicketCount   int
+	LotteryDrawSetsPerNumber int
+	LotterySalesOpenLeadSec  int
+	LotterySalesCloseLeadSec int
+	LotteryTicketPrice       int64
+	LotteryAutoStock         bool
+	// LotteryRequireVerifiedEmail rejects reservations by users who have not verified their email
+	LotteryRequireVerifiedEmail bool
+
+	PaymentWindowSec     int
+	PaymentWebhookSecret string
+	PaymentCheckoutURL   string
+
+	IdempotencyTTLSec int
+
+	PasswordResetTTLSec int
+	PasswordResetURL    string
+	// EmailVerificationTTLSec is how long an email verification link can be used
+	EmailVerificationTTLSec int
+	EmailVerificationURL    string
+	// NotifierFile is where notifications are appended. When empty they are written to the log.
+	NotifierFile string
 }
 
 func Load() (*Config, error) {
@@ -29,6 +59,86 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_REFRESH_TOKEN_SEC: %w", err)
 	}
 
+	reservationTTLSec, err := strconv.Atoi(getEnv("LOTTERY_RESERVATION_TTL_SEC", "300"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_RESERVATION_TTL_SEC: %w", err)
+	}
+
+	maxTicketsPerUser, err := strconv.Atoi(getEnv("LOTTERY_MAX_TICKETS_PER_USER", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_MAX_TICKETS_PER_USER: %w", err)
+	}
+
+	holdExtensionSec, err := strconv.Atoi(getEnv("LOTTERY_HOLD_EXTENSION_SEC", "300"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_HOLD_EXTENSION_SEC: %w", err)
+	}
+
+	maxHoldExtensions, err := strconv.Atoi(getEnv("LOTTERY_MAX_HOLD_EXTENSIONS", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_MAX_HOLD_EXTENSIONS: %w", err)
+	}
+
+	prefillWorkers, err := strconv.Atoi(getEnv("LOTTERY_PREFILL_WORKERS", "4"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_PREFILL_WORKERS: %w", err)
+	}
+
+	drawTicketCount, err := strconv.Atoi(getEnv("LOTTERY_DRAW_TICKET_COUNT", "1000000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_DRAW_TICKET_COUNT: %w", err)
+	}
+
+	drawSetsPerNumber, err := strconv.Atoi(getEnv("LOTTERY_DRAW_SETS_PER_NUMBER", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_DRAW_SETS_PER_NUMBER: %w", err)
+	}
+
+	salesOpenLeadSec, err := strconv.Atoi(getEnv("LOTTERY_SALES_OPEN_LEAD_SEC", "1468800"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_SALES_OPEN_LEAD_SEC: %w", err)
+	}
+
+	salesCloseLeadSec, err := strconv.Atoi(getEnv("LOTTERY_SALES_CLOSE_LEAD_SEC", "3600"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_SALES_CLOSE_LEAD_SEC: %w", err)
+	}
+
+	ticketPrice, err := strconv.ParseInt(getEnv("LOTTERY_TICKET_PRICE", "80"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_TICKET_PRICE: %w", err)
+	}
+
+	autoStock, err := strconv.ParseBool(getEnv("LOTTERY_AUTO_STOCK", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_AUTO_STOCK: %w", err)
+	}
+
+	requireVerifiedEmail, err := strconv.ParseBool(getEnv("LOTTERY_REQUIRE_VERIFIED_EMAIL", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOTTERY_REQUIRE_VERIFIED_EMAIL: %w", err)
+	}
+
+	paymentWindowSec, err := strconv.Atoi(getEnv("PAYMENT_WINDOW_SEC", "900"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PAYMENT_WINDOW_SEC: %w", err)
+	}
+
+	idempotencyTTLSec, err := strconv.Atoi(getEnv("IDEMPOTENCY_TTL_SEC", "86400"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL_SEC: %w", err)
+	}
+
+	passwordResetTTLSec, err := strconv.Atoi(getEnv("PASSWORD_RESET_TTL_SEC", "1800"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_TTL_SEC: %w", err)
+	}
+
+	emailVerificationTTLSec, err := strconv.Atoi(getEnv("EMAIL_VERIFICATION_TTL_SEC", "86400"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_TTL_SEC: %w", err)
+	}
+
 	return &Config{
 		MongoDBURI:         getEnv("MONGODB_URI", "mongodb://localhost:27017/userdb"),
 		RedisHost:          getEnv("REDIS_HOST", "localhost"),
@@ -38,6 +148,34 @@ func Load() (*Config, error) {
 		JWTRefreshTokenSec: jwtRefreshSec,
 		ServerPort:         getEnv("SERVER_PORT", "8080"),
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		AdminEmails:        splitList(getEnv("ADMIN_EMAILS", "")),
+
+		LotteryReservationTTLSec: reservationTTLSec,
+		LotteryMaxTicketsPerUser: maxTicketsPerUser,
+		LotteryHoldExtensionSec:  holdExtensionSec,
+		LotteryMaxHoldExtensions: maxHoldExtensions,
+		LotteryPrefillWorkers:    prefillWorkers,
+		LotteryDrawTicketCount:   drawTicketCount,
+		LotteryDrawSetsPerNumber: drawSetsPerNumber,
+		LotterySalesOpenLeadSec:  salesOpenLeadSec,
+		LotterySalesCloseLeadSec: salesCloseLeadSec,
+		LotteryTicketPrice:       ticketPrice,
+		LotteryAutoStock:         autoStock,
+
+		LotteryRequireVerifiedEmail: requireVerifiedEmail,
+
+		PaymentWindowSec:     paymentWindowSec,
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "change-this-webhook-secret"),
+		PaymentCheckoutURL:   getEnv("PAYMENT_CHECKOUT_URL", "http://localhost:8080/fake-checkout/"),
+
+		IdempotencyTTLSec: idempotencyTTLSec,
+
+		PasswordResetTTLSec: passwordResetTTLSec,
+		PasswordResetURL:    getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password?token="),
+		NotifierFile:        getEnv("NOTIFIER_FILE", ""),
+
+		EmailVerificationTTLSec: emailVerificationTTLSec,
+		EmailVerificationURL:    getEnv("EMAIL_VERIFICATION_URL", "http://localhost:8080/api/v1/auth/verify?token="),
 	}, nil
 }
 
@@ -47,3 +185,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList parses a comma separated list, skipping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -2,19 +2,24 @@ package mocks
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/backend-challenge/user-api/internal/domain"
 )
 
 type MockUserRepository struct {
-	CreateFunc      func(ctx context.Context, user *domain.User) error
-	FindByIDFunc    func(ctx context.Context, id string) (*domain.User, error)
-	FindByEmailFunc func(ctx context.Context, email string) (*domain.User, error)
-	FindAllFunc     func(ctx context.Context) ([]*domain.User, error)
-	UpdateFunc      func(ctx context.Context, user *domain.User) error
-	DeleteFunc      func(ctx context.Context, id string) error
-	CountFunc       func(ctx context.Context) (int64, error)
+	CreateFunc               func(ctx context.Context, user *domain.User) error
+	FindByIDFunc             func(ctx context.Context, id string) (*domain.User, error)
+	FindByEmailFunc          func(ctx context.Context, email string) (*domain.User, error)
+	FindAllFunc              func(ctx context.Context) ([]*domain.User, error)
+	UpdateFunc               func(ctx context.Context, user *domain.User) error
+	UpdateRolesFunc          func(ctx context.Context, id string, roles []domain.Role) error
+	UpdatePasswordFunc       func(ctx context.Context, id string, passwordHash string) error
+	MarkEmailVerifiedFunc    func(ctx context.Context, id string, email string) error
+	MigrateEmailVerifiedFunc func(ctx context.Context) (int64, error)
+	DeleteFunc               func(ctx context.Context, id string) error
+	CountFunc                func(ctx context.Context) (int64, error)
 }
 
 func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) error {
@@ -52,6 +57,34 @@ func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+func (m *MockUserRepository) UpdateRoles(ctx context.Context, id string, roles []domain.Role) error {
+	if m.UpdateRolesFunc != nil {
+		return m.UpdateRolesFunc(ctx, id, roles)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	if m.UpdatePasswordFunc != nil {
+		return m.UpdatePasswordFunc(ctx, id, passwordHash)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id string, email string) error {
+	if m.MarkEmailVerifiedFunc != nil {
+		return m.MarkEmailVerifiedFunc(ctx, id, email)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) MigrateEmailVerified(ctx context.Context) (int64, error) {
+	if m.MigrateEmailVerifiedFunc != nil {
+		return m.MigrateEmailVerifiedFunc(ctx)
+	}
+	return 0, nil
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(ctx, id)
@@ -69,7 +102,14 @@ func (m *MockUserRepository) Count(ctx context.Context) (int64, error) {
 type MockSessionManager struct {
 	StoreSessionFunc  func(ctx context.Context, key string, data interface{}, ttl time.Duration) error
 	GetSessionFunc    func(ctx context.Context, key string) (string, error)
+	TakeSessionFunc   func(ctx context.Context, key string) (string, error)
 	DeleteSessionFunc func(ctx context.Context, key string) error
+
+	IndexUserSessionFunc   func(ctx context.Context, userID string, session *domain.LoginSession) error
+	TouchUserSessionFunc   func(ctx context.Context, sessionID string, seenAt time.Time) error
+	UserSessionIDsFunc     func(ctx context.Context, userID string) ([]string, error)
+	UserSessionsFunc       func(ctx context.Context, userID string) ([]domain.LoginSession, error)
+	UnindexUserSessionFunc func(ctx context.Context, userID, sessionID string) error
 }
 
 func (m *MockSessionManager) StoreSession(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
@@ -86,6 +126,13 @@ func (m *MockSessionManager) GetSession(ctx context.Context, key string) (string
 	return "", nil
 }
 
+func (m *MockSessionManager) TakeSession(ctx context.Context, key string) (string, error) {
+	if m.TakeSessionFunc != nil {
+		return m.TakeSessionFunc(ctx, key)
+	}
+	return "", nil
+}
+
 func (m *MockSessionManager) DeleteSession(ctx context.Context, key string) error {
 	if m.DeleteSessionFunc != nil {
 		return m.DeleteSessionFunc(ctx, key)
@@ -93,6 +140,71 @@ func (m *MockSessionManager) DeleteSession(ctx context.Context, key string) erro
 	return nil
 }
 
+func (m *MockSessionManager) IndexUserSession(ctx context.Context, userID string, session *domain.LoginSession) error {
+	if m.IndexUserSessionFunc != nil {
+		return m.IndexUserSessionFunc(ctx, userID, session)
+	}
+	return nil
+}
+
+func (m *MockSessionManager) TouchUserSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	if m.TouchUserSessionFunc != nil {
+		return m.TouchUserSessionFunc(ctx, sessionID, seenAt)
+	}
+	return nil
+}
+
+func (m *MockSessionManager) UserSessionIDs(ctx context.Context, userID string) ([]string, error) {
+	if m.UserSessionIDsFunc != nil {
+		return m.UserSessionIDsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockSessionManager) UserSessions(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+	if m.UserSessionsFunc != nil {
+		return m.UserSessionsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockSessionManager) UnindexUserSession(ctx context.Context, userID, sessionID string) error {
+	if m.UnindexUserSessionFunc != nil {
+		return m.UnindexUserSessionFunc(ctx, userID, sessionID)
+	}
+	return nil
+}
+
+type MockAccountSecurity struct {
+	RevokeAllSessionsFunc func(ctx context.Context, userID, keepSessionID string) (int, error)
+	SendVerificationFunc  func(ctx context.Context, user *domain.User) error
+}
+
+func (m *MockAccountSecurity) RevokeAllSessions(ctx context.Context, userID, keepSessionID string) (int, error) {
+	if m.RevokeAllSessionsFunc != nil {
+		return m.RevokeAllSessionsFunc(ctx, userID, keepSessionID)
+	}
+	return 0, nil
+}
+
+func (m *MockAccountSecurity) SendVerification(ctx context.Context, user *domain.User) error {
+	if m.SendVerificationFunc != nil {
+		return m.SendVerificationFunc(ctx, user)
+	}
+	return nil
+}
+
+type MockNotifier struct {
+	SendFunc func(ctx context.Context, notification domain.Notification) error
+}
+
+func (m *MockNotifier) Send(ctx context.Context, notification domain.Notification) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, notification)
+	}
+	return nil
+}
+
 type MockTokenService struct {
 	GenerateTokenFunc func(userID, email string, duration time.Duration) (string, error)
 	ValidateTokenFunc func(tokenString string) (*domain.TokenClaims, error)
@@ -115,20 +227,99 @@ func (m *MockTokenService) ValidateToken(tokenString string) (*domain.TokenClaim
 }
 
 type MockLotteryRepository struct {
-	SearchAndReserveFunc func(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error)
-	UpsertManyFunc       func(ctx context.Context, tickets []domain.LotteryTicket) error
-	MarkAsSoldFunc       func(ctx context.Context, ticketID string, userID string) error
-	CountFunc            func(ctx context.Context) (int64, error)
-	SeedTicketsFunc      func(ctx context.Context, total int) error
+	SearchAndReserveFunc           func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error)
+	BrowseTicketsFunc              func(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error)
+	ReserveNumberFunc              func(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error)
+	ReserveSetFunc                 func(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error)
+	HoldUntilFunc                  func(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error)
+	RestoreHoldsFunc               func(ctx context.Context, tickets []domain.LotteryTicket, userID string) error
+	ExtendReservationsFunc         func(ctx context.Context, userID string, extension time.Duration, maxExtensions int) (int64, error)
+	UpsertManyFunc                 func(ctx context.Context, tickets []domain.LotteryTicket) error
+	MarkAsSoldFunc                 func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error)
+	FindSoldByUserFunc             func(ctx context.Context, userID string, drawID string) ([]domain.LotteryTicket, error)
+	FindReservationsByUserFunc     func(ctx context.Context, userID string) ([]domain.LotteryTicket, error)
+	ReleaseReservationFunc         func(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error)
+	ReleaseAllReservationsFunc     func(ctx context.Context, userID string) (int64, error)
+	ReleaseExpiredReservationsFunc func(ctx context.Context) (int64, error)
+	CountFunc                      func(ctx context.Context) (int64, error)
+	SeedTicketsFunc                func(ctx context.Context, plan domain.TicketSeedPlan) error
+	AssignUnscopedTicketsFunc      func(ctx context.Context, drawID string) (int64, error)
+	WatchFunc                      func(ctx context.Context, drawID string) (<-chan domain.LotteryEvent, error)
+	StatsFunc                      func(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error)
+	FindHistoryFunc                func(ctx context.Context, ticketID string, limit int) ([]domain.LotteryHistoryEntry, error)
 }
 
-func (m *MockLotteryRepository) SearchAndReserve(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error) {
+func (m *MockLotteryRepository) SearchAndReserve(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
 	if m.SearchAndReserveFunc != nil {
-		return m.SearchAndReserveFunc(ctx, pattern, userID, limit)
+		return m.SearchAndReserveFunc(ctx, draw, pattern, userID, limit, ttl)
 	}
 	return []domain.LotteryTicket{}, nil
 }
 
+func (m *MockLotteryRepository) BrowseTickets(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error) {
+	if m.BrowseTicketsFunc != nil {
+		return m.BrowseTicketsFunc(ctx, drawID, pattern, after, limit)
+	}
+	return []domain.LotteryTicket{}, 0, nil
+}
+
+func (m *MockLotteryRepository) ReserveNumber(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+	if m.ReserveNumberFunc != nil {
+		return m.ReserveNumberFunc(ctx, drawID, number, userID, copies, ttl)
+	}
+	return reservedCopies(drawID, number, userID, copies, ttl), nil
+}
+
+func (m *MockLotteryRepository) ReserveSet(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error) {
+	if m.ReserveSetFunc != nil {
+		return m.ReserveSetFunc(ctx, drawID, number, userID, ttl)
+	}
+	return reservedCopies(drawID, number, userID, 1, ttl), nil
+}
+
+func reservedCopies(drawID string, number string, userID string, copies int, ttl time.Duration) []domain.LotteryTicket {
+	reservedUntil := time.Now().Add(ttl)
+	tickets := make([]domain.LotteryTicket, copies)
+	for i := range tickets {
+		tickets[i] = domain.LotteryTicket{
+			ID:            fmt.Sprintf("%s-%d", number, i+1),
+			Number:        number,
+			Set:           i + 1,
+			Status:        domain.LotteryStatusReserved,
+			ReservedBy:    userID,
+			ReservedUntil: &reservedUntil,
+			DrawID:        drawID,
+		}
+	}
+	return tickets
+}
+
+func (m *MockLotteryRepository) HoldUntil(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error) {
+	if m.HoldUntilFunc != nil {
+		return m.HoldUntilFunc(ctx, drawID, ticketIDs, userID, until)
+	}
+	tickets := make([]domain.LotteryTicket, len(ticketIDs))
+	for i, id := range ticketIDs {
+		tickets[i] = domain.LotteryTicket{
+			ID:            id,
+			Number:        "123456",
+			Set:           i + 1,
+			Status:        domain.LotteryStatusReserved,
+			ReservedBy:    userID,
+			ReservedUntil: &until,
+			DrawID:        drawID,
+		}
+	}
+	return tickets, nil
+}
+
+func (m *MockLotteryRepository) ExtendReservations(ctx context.Context, userID string, extension time.Duration, maxExtensions int) (int64, error) {
+	if m.ExtendReservationsFunc != nil {
+		return m.ExtendReservationsFunc(ctx, userID, extension, maxExtensions)
+	}
+	return 0, nil
+}
+
 func (m *MockLotteryRepository) UpsertMany(ctx context.Context, tickets []domain.LotteryTicket) error {
 	if m.UpsertManyFunc != nil {
 		return m.UpsertManyFunc(ctx, tickets)
@@ -136,13 +327,55 @@ func (m *MockLotteryRepository) UpsertMany(ctx context.Context, tickets []domain
 	return nil
 }
 
-func (m *MockLotteryRepository) MarkAsSold(ctx context.Context, ticketID string, userID string) error {
+func (m *MockLotteryRepository) MarkAsSold(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
 	if m.MarkAsSoldFunc != nil {
-		return m.MarkAsSoldFunc(ctx, ticketID, userID)
+		return m.MarkAsSoldFunc(ctx, ticketID, userID, drawID)
+	}
+	return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusSold, ReservedBy: userID, DrawID: drawID}, nil
+}
+
+func (m *MockLotteryRepository) FindSoldByUser(ctx context.Context, userID string, drawID string) ([]domain.LotteryTicket, error) {
+	if m.FindSoldByUserFunc != nil {
+		return m.FindSoldByUserFunc(ctx, userID, drawID)
+	}
+	return []domain.LotteryTicket{}, nil
+}
+
+func (m *MockLotteryRepository) RestoreHolds(ctx context.Context, tickets []domain.LotteryTicket, userID string) error {
+	if m.RestoreHoldsFunc != nil {
+		return m.RestoreHoldsFunc(ctx, tickets, userID)
 	}
 	return nil
 }
 
+func (m *MockLotteryRepository) FindReservationsByUser(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+	if m.FindReservationsByUserFunc != nil {
+		return m.FindReservationsByUserFunc(ctx, userID)
+	}
+	return []domain.LotteryTicket{}, nil
+}
+
+func (m *MockLotteryRepository) ReleaseReservation(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+	if m.ReleaseReservationFunc != nil {
+		return m.ReleaseReservationFunc(ctx, ticketID, userID)
+	}
+	return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusAvailable}, nil
+}
+
+func (m *MockLotteryRepository) ReleaseAllReservations(ctx context.Context, userID string) (int64, error) {
+	if m.ReleaseAllReservationsFunc != nil {
+		return m.ReleaseAllReservationsFunc(ctx, userID)
+	}
+	return 0, nil
+}
+
+func (m *MockLotteryRepository) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	if m.ReleaseExpiredReservationsFunc != nil {
+		return m.ReleaseExpiredReservationsFunc(ctx)
+	}
+	return 0, nil
+}
+
 func (m *MockLotteryRepository) Count(ctx context.Context) (int64, error) {
 	if m.CountFunc != nil {
 		return m.CountFunc(ctx)
@@ -150,9 +383,280 @@ func (m *MockLotteryRepository) Count(ctx context.Context) (int64, error) {
 	return 0, nil
 }
 
-func (m *MockLotteryRepository) SeedTickets(ctx context.Context, total int) error {
+func (m *MockLotteryRepository) SeedTickets(ctx context.Context, plan domain.TicketSeedPlan) error {
 	if m.SeedTicketsFunc != nil {
-		return m.SeedTicketsFunc(ctx, total)
+		return m.SeedTicketsFunc(ctx, plan)
+	}
+	return nil
+}
+
+func (m *MockLotteryRepository) AssignUnscopedTickets(ctx context.Context, drawID string) (int64, error) {
+	if m.AssignUnscopedTicketsFunc != nil {
+		return m.AssignUnscopedTicketsFunc(ctx, drawID)
+	}
+	return 0, nil
+}
+
+// Watch publishes no events by default; the channel closes once ctx is cancelled
+func (m *MockLotteryRepository) Watch(ctx context.Context, drawID string) (<-chan domain.LotteryEvent, error) {
+	if m.WatchFunc != nil {
+		return m.WatchFunc(ctx, drawID)
+	}
+	events := make(chan domain.LotteryEvent)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
+func (m *MockLotteryRepository) FindHistory(ctx context.Context, ticketID string, limit int) ([]domain.LotteryHistoryEntry, error) {
+	if m.FindHistoryFunc != nil {
+		return m.FindHistoryFunc(ctx, ticketID, limit)
+	}
+	return []domain.LotteryHistoryEntry{}, nil
+}
+
+func (m *MockLotteryRepository) Stats(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error) {
+	if m.StatsFunc != nil {
+		return m.StatsFunc(ctx, drawID, salesSince, hotPatterns)
+	}
+	return &domain.LotteryStats{
+		DrawID:      drawID,
+		ByStatus:    map[domain.LotteryStatus]int64{},
+		SalesSince:  salesSince,
+		GeneratedAt: time.Now(),
+	}, nil
+}
+
+type MockLotteryDrawRepository struct {
+	CreateFunc            func(ctx context.Context, draw *domain.LotteryDraw) error
+	FindByIDFunc          func(ctx context.Context, id string) (*domain.LotteryDraw, error)
+	FindNextFunc          func(ctx context.Context, after time.Time) (*domain.LotteryDraw, error)
+	FindOnSaleFunc        func(ctx context.Context, now time.Time) (*domain.LotteryDraw, error)
+	FindLatestFunc        func(ctx context.Context) (*domain.LotteryDraw, error)
+	FindUnstockedFunc     func(ctx context.Context) ([]*domain.LotteryDraw, error)
+	ListFunc              func(ctx context.Context, limit int) ([]*domain.LotteryDraw, error)
+	MarkStockedFunc       func(ctx context.Context, id string, stockedAt time.Time) error
+	UpdateSalesWindowFunc func(ctx context.Context, draw *domain.LotteryDraw) error
+	SaveResultsFunc       func(ctx context.Context, draw *domain.LotteryDraw) error
+}
+
+// OnSaleDraw returns a stocked round whose sales window contains now
+func OnSaleDraw(id string) *domain.LotteryDraw {
+	draw := domain.NewLotteryDraw(domain.NextDrawDate(time.Now().Add(2*time.Hour)), domain.DefaultDrawTemplate())
+	draw.ID = id
+	draw.SalesOpenAt = time.Now().Add(-time.Hour)
+	stockedAt := draw.SalesOpenAt
+	draw.StockedAt = &stockedAt
+	return draw
+}
+
+func (m *MockLotteryDrawRepository) Create(ctx context.Context, draw *domain.LotteryDraw) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, draw)
+	}
+	return nil
+}
+
+func (m *MockLotteryDrawRepository) FindByID(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+	if m.FindByIDFunc != nil {
+		return m.FindByIDFunc(ctx, id)
+	}
+	return nil, domain.ErrDrawNotFound
+}
+
+func (m *MockLotteryDrawRepository) FindNext(ctx context.Context, after time.Time) (*domain.LotteryDraw, error) {
+	if m.FindNextFunc != nil {
+		return m.FindNextFunc(ctx, after)
+	}
+	return OnSaleDraw("next-draw"), nil
+}
+
+func (m *MockLotteryDrawRepository) FindOnSale(ctx context.Context, now time.Time) (*domain.LotteryDraw, error) {
+	if m.FindOnSaleFunc != nil {
+		return m.FindOnSaleFunc(ctx, now)
+	}
+	return OnSaleDraw("open-draw"), nil
+}
+
+func (m *MockLotteryDrawRepository) FindLatest(ctx context.Context) (*domain.LotteryDraw, error) {
+	if m.FindLatestFunc != nil {
+		return m.FindLatestFunc(ctx)
+	}
+	return nil, domain.ErrDrawNotFound
+}
+
+func (m *MockLotteryDrawRepository) FindUnstocked(ctx context.Context) ([]*domain.LotteryDraw, error) {
+	if m.FindUnstockedFunc != nil {
+		return m.FindUnstockedFunc(ctx)
+	}
+	return []*domain.LotteryDraw{}, nil
+}
+
+func (m *MockLotteryDrawRepository) MarkStocked(ctx context.Context, id string, stockedAt time.Time) error {
+	if m.MarkStockedFunc != nil {
+		return m.MarkStockedFunc(ctx, id, stockedAt)
+	}
+	return nil
+}
+
+func (m *MockLotteryDrawRepository) UpdateSalesWindow(ctx context.Context, draw *domain.LotteryDraw) error {
+	if m.UpdateSalesWindowFunc != nil {
+		return m.UpdateSalesWindowFunc(ctx, draw)
+	}
+	return nil
+}
+
+func (m *MockLotteryDrawRepository) List(ctx context.Context, limit int) ([]*domain.LotteryDraw, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, limit)
+	}
+	return []*domain.LotteryDraw{}, nil
+}
+
+func (m *MockLotteryDrawRepository) SaveResults(ctx context.Context, draw *domain.LotteryDraw) error {
+	if m.SaveResultsFunc != nil {
+		return m.SaveResultsFunc(ctx, draw)
+	}
+	return nil
+}
+
+type MockReservationQuota struct {
+	AcquireFunc func(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error)
+	CommitFunc  func(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error
+	ReleaseFunc func(ctx context.Context, userID string, ticketIDs ...string) error
+}
+
+func (m *MockReservationQuota) Acquire(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+	if m.AcquireFunc != nil {
+		return m.AcquireFunc(ctx, userID, requested, max, hold)
+	}
+	return "mock-grant", min(requested, max), nil
+}
+
+func (m *MockReservationQuota) Commit(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error {
+	if m.CommitFunc != nil {
+		return m.CommitFunc(ctx, userID, grantID, granted, tickets)
+	}
+	return nil
+}
+
+func (m *MockReservationQuota) Release(ctx context.Context, userID string, ticketIDs ...string) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(ctx, userID, ticketIDs...)
+	}
+	return nil
+}
+
+type MockOrderRepository struct {
+	CreateFunc             func(ctx context.Context, order *domain.Order) error
+	FindByIDFunc           func(ctx context.Context, id string) (*domain.Order, error)
+	FindByPaymentIDFunc    func(ctx context.Context, paymentID string) (*domain.Order, error)
+	FindByUserFunc         func(ctx context.Context, userID string, limit int) ([]*domain.Order, error)
+	FindExpiredPendingFunc func(ctx context.Context, now time.Time, limit int) ([]*domain.Order, error)
+	UpdateFunc             func(ctx context.Context, order *domain.Order, from domain.OrderStatus) error
+}
+
+func (m *MockOrderRepository) Create(ctx context.Context, order *domain.Order) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, order)
+	}
+	order.ID = "order-1"
+	return nil
+}
+
+func (m *MockOrderRepository) FindByID(ctx context.Context, id string) (*domain.Order, error) {
+	if m.FindByIDFunc != nil {
+		return m.FindByIDFunc(ctx, id)
+	}
+	return nil, domain.ErrOrderNotFound
+}
+
+func (m *MockOrderRepository) FindByPaymentID(ctx context.Context, paymentID string) (*domain.Order, error) {
+	if m.FindByPaymentIDFunc != nil {
+		return m.FindByPaymentIDFunc(ctx, paymentID)
+	}
+	return nil, domain.ErrOrderNotFound
+}
+
+func (m *MockOrderRepository) FindByUser(ctx context.Context, userID string, limit int) ([]*domain.Order, error) {
+	if m.FindByUserFunc != nil {
+		return m.FindByUserFunc(ctx, userID, limit)
+	}
+	return []*domain.Order{}, nil
+}
+
+func (m *MockOrderRepository) FindExpiredPending(ctx context.Context, now time.Time, limit int) ([]*domain.Order, error) {
+	if m.FindExpiredPendingFunc != nil {
+		return m.FindExpiredPendingFunc(ctx, now, limit)
+	}
+	return []*domain.Order{}, nil
+}
+
+func (m *MockOrderRepository) Update(ctx context.Context, order *domain.Order, from domain.OrderStatus) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, order, from)
+	}
+	return nil
+}
+
+type MockPaymentGateway struct {
+	CreateIntentFunc func(ctx context.Context, order *domain.Order) (*domain.PaymentIntent, error)
+	RefundFunc       func(ctx context.Context, paymentID string, amount int64) error
+	ParseEventFunc   func(payload []byte, signature string) (*domain.PaymentEvent, error)
+}
+
+func (m *MockPaymentGateway) CreateIntent(ctx context.Context, order *domain.Order) (*domain.PaymentIntent, error) {
+	if m.CreateIntentFunc != nil {
+		return m.CreateIntentFunc(ctx, order)
+	}
+	return &domain.PaymentIntent{
+		ID:          "pay-" + order.ID,
+		OrderID:     order.ID,
+		Amount:      order.Total,
+		Currency:    order.Currency,
+		CheckoutURL: "https://pay.example/pay-" + order.ID,
+	}, nil
+}
+
+func (m *MockPaymentGateway) Refund(ctx context.Context, paymentID string, amount int64) error {
+	if m.RefundFunc != nil {
+		return m.RefundFunc(ctx, paymentID, amount)
+	}
+	return nil
+}
+
+func (m *MockPaymentGateway) ParseEvent(payload []byte, signature string) (*domain.PaymentEvent, error) {
+	if m.ParseEventFunc != nil {
+		return m.ParseEventFunc(payload, signature)
+	}
+	return nil, domain.ErrInvalidWebhook
+}
+
+type MockIdempotencyStore struct {
+	BeginFunc    func(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error)
+	CompleteFunc func(ctx context.Context, key string, response *domain.IdempotentResponse, ttl time.Duration) error
+	ReleaseFunc  func(ctx context.Context, key string) error
+}
+
+func (m *MockIdempotencyStore) Begin(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error) {
+	if m.BeginFunc != nil {
+		return m.BeginFunc(ctx, key, fingerprint, lockTTL)
+	}
+	return nil, nil
+}
+
+func (m *MockIdempotencyStore) Complete(ctx context.Context, key string, response *domain.IdempotentResponse, ttl time.Duration) error {
+	if m.CompleteFunc != nil {
+		return m.CompleteFunc(ctx, key, response, ttl)
+	}
+	return nil
+}
+
+func (m *MockIdempotencyStore) Release(ctx context.Context, key string) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(ctx, key)
 	}
 	return nil
 }
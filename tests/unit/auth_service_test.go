@@ -2,7 +2,9 @@ package unit
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -65,7 +67,7 @@ func TestAuthService_Register(t *testing.T) {
 
 			tt.mockSetup(mockRepo)
 
-			service := application.NewAuthService(mockRepo, mockSession, mockToken)
+			service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 			user, err := service.Register(context.Background(), tt.userName, tt.email, tt.password)
 
 			if tt.expectError {
@@ -115,8 +117,8 @@ func TestAuthService_Login(t *testing.T) {
 			return nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		accessToken, refreshToken, err := service.Login(context.Background(), "john@example.com", "Password123!")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		accessToken, refreshToken, err := service.Login(context.Background(), "john@example.com", "Password123!", domain.ClientInfo{})
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -129,6 +131,45 @@ func TestAuthService_Login(t *testing.T) {
 		}
 	})
 
+	t.Run("records the client of the session", func(t *testing.T) {
+		mockRepo := &mocks.MockUserRepository{}
+		mockSession := &mocks.MockSessionManager{}
+		mockToken := &mocks.MockTokenService{}
+
+		mockRepo.FindByEmailFunc = func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "test-id", Email: email, Password: string(hashedPassword)}, nil
+		}
+		mockToken.GenerateTokenFunc = func(userID, email string, duration time.Duration) (string, error) {
+			return "test-token", nil
+		}
+		mockToken.ValidateTokenFunc = func(tokenString string) (*domain.TokenClaims, error) {
+			return &domain.TokenClaims{Subject: "token-id"}, nil
+		}
+		var indexed *domain.LoginSession
+		mockSession.IndexUserSessionFunc = func(ctx context.Context, userID string, session *domain.LoginSession) error {
+			indexed = session
+			return nil
+		}
+
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.Login(context.Background(), "john@example.com", "Password123!", domain.ClientInfo{
+			IP:        "203.0.113.7",
+			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if indexed == nil || indexed.ID == "" {
+			t.Fatal("expected the login session to be indexed")
+		}
+		if indexed.Device != "Chrome on Windows" || indexed.IP != "203.0.113.7" {
+			t.Errorf("expected Chrome on Windows from 203.0.113.7 but got %s from %s", indexed.Device, indexed.IP)
+		}
+		if indexed.CreatedAt.IsZero() || !indexed.ExpiresAt.After(indexed.CreatedAt) {
+			t.Errorf("unexpected session times %+v", indexed)
+		}
+	})
+
 	t.Run("invalid credentials - wrong password", func(t *testing.T) {
 		mockRepo := &mocks.MockUserRepository{}
 		mockSession := &mocks.MockSessionManager{}
@@ -142,8 +183,8 @@ func TestAuthService_Login(t *testing.T) {
 			}, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.Login(context.Background(), "john@example.com", "wrongpassword")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.Login(context.Background(), "john@example.com", "wrongpassword", domain.ClientInfo{})
 
 		if err != domain.ErrInvalidCredentials {
 			t.Errorf("expected ErrInvalidCredentials but got %v", err)
@@ -159,8 +200,8 @@ func TestAuthService_Login(t *testing.T) {
 			return nil, domain.ErrUserNotFound
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.Login(context.Background(), "nonexistent@example.com", "Password123!")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.Login(context.Background(), "nonexistent@example.com", "Password123!", domain.ClientInfo{})
 
 		if err != domain.ErrInvalidCredentials {
 			t.Errorf("expected ErrInvalidCredentials but got %v", err)
@@ -180,8 +221,8 @@ func TestAuthService_Login(t *testing.T) {
 			return "", errors.New("gen-error")
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.Login(context.Background(), "john@example.com", "Password123!")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.Login(context.Background(), "john@example.com", "Password123!", domain.ClientInfo{})
 
 		if err == nil {
 			t.Error("expected error but got nil")
@@ -209,8 +250,8 @@ func TestAuthService_Login(t *testing.T) {
 			return errors.New("redis-error")
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.Login(context.Background(), "john@example.com", "Password123!")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.Login(context.Background(), "john@example.com", "Password123!", domain.ClientInfo{})
 
 		if err == nil {
 			t.Error("expected error but got nil")
@@ -234,7 +275,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 			return `{"userId":"test-user-id","accessToken":"valid-token","refreshToken":"refresh-token"}`, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		claims, userID, err := service.ValidateToken(context.Background(), "valid-token")
 
 		if err != nil {
@@ -248,6 +289,44 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		}
 	})
 
+	t.Run("roles from the session", func(t *testing.T) {
+		mockRepo := &mocks.MockUserRepository{}
+		mockSession := &mocks.MockSessionManager{}
+		mockToken := &mocks.MockTokenService{}
+
+		mockSession.GetSessionFunc = func(ctx context.Context, key string) (string, error) {
+			return `{"userId":"test-user-id","accessToken":"valid-token","roles":["user","admin"]}`, nil
+		}
+
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		claims, _, err := service.ValidateToken(context.Background(), "valid-token")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !domain.HasAnyRole(claims.Roles, domain.RoleAdmin) {
+			t.Errorf("expected admin role but got %v", claims.Roles)
+		}
+	})
+
+	t.Run("session without roles is a plain user", func(t *testing.T) {
+		mockRepo := &mocks.MockUserRepository{}
+		mockSession := &mocks.MockSessionManager{}
+		mockToken := &mocks.MockTokenService{}
+
+		mockSession.GetSessionFunc = func(ctx context.Context, key string) (string, error) {
+			return `{"userId":"test-user-id","accessToken":"valid-token"}`, nil
+		}
+
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		claims, _, err := service.ValidateToken(context.Background(), "valid-token")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(claims.Roles) != 1 || claims.Roles[0] != domain.RoleUser {
+			t.Errorf("expected roles [user] but got %v", claims.Roles)
+		}
+	})
+
 	t.Run("invalid token", func(t *testing.T) {
 		mockRepo := &mocks.MockUserRepository{}
 		mockSession := &mocks.MockSessionManager{}
@@ -257,7 +336,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 			return nil, domain.ErrInvalidToken
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		_, _, err := service.ValidateToken(context.Background(), "invalid-token")
 
 		if err != domain.ErrInvalidToken {
@@ -277,7 +356,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 			return "", nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		_, _, err := service.ValidateToken(context.Background(), "valid-token")
 
 		if err != domain.ErrInvalidToken {
@@ -303,7 +382,7 @@ func TestAuthService_Logout(t *testing.T) {
 		return nil
 	}
 
-	service := application.NewAuthService(mockRepo, mockSession, mockToken)
+	service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 	err := service.Logout(context.Background(), "valid-token")
 
 	if err != nil {
@@ -333,8 +412,8 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			return "new-token", nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		accessToken, refreshToken, err := service.RefreshToken(context.Background(), "valid-refresh-token")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		accessToken, refreshToken, err := service.RefreshToken(context.Background(), "valid-refresh-token", domain.ClientInfo{})
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -353,8 +432,8 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			return nil, errors.New("invalid")
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.RefreshToken(context.Background(), "invalid-token")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.RefreshToken(context.Background(), "invalid-token", domain.ClientInfo{})
 
 		if err != domain.ErrInvalidToken {
 			t.Errorf("expected ErrInvalidToken but got %v", err)
@@ -374,8 +453,8 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			return `invalid-json`, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.RefreshToken(context.Background(), "valid-token")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.RefreshToken(context.Background(), "valid-token", domain.ClientInfo{})
 
 		if err == nil {
 			t.Error("expected error but got nil")
@@ -403,8 +482,8 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			return "", errors.New("gen-error")
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
-		_, _, err := service.RefreshToken(context.Background(), "token")
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+		_, _, err := service.RefreshToken(context.Background(), "token", domain.ClientInfo{})
 
 		if err == nil || err.Error() != "gen-error" {
 			t.Errorf("expected gen-error but got %v", err)
@@ -426,7 +505,7 @@ func TestAuthService_ValidateToken_EdgeCases(t *testing.T) {
 			return `invalid-json`, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		_, _, err := service.ValidateToken(context.Background(), "token")
 
 		if err == nil {
@@ -447,7 +526,7 @@ func TestAuthService_ValidateToken_EdgeCases(t *testing.T) {
 			return `{"userId":"id","accessToken":"other-token"}`, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		_, _, err := service.ValidateToken(context.Background(), "token")
 
 		if err != domain.ErrInvalidToken {
@@ -468,7 +547,7 @@ func TestAuthService_ValidateToken_EdgeCases(t *testing.T) {
 			return `{"userId":"","accessToken":"token"}`, nil
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		_, _, err := service.ValidateToken(context.Background(), "token")
 
 		if err != domain.ErrInvalidToken {
@@ -495,7 +574,7 @@ func TestAuthService_Logout_EdgeCases(t *testing.T) {
 			return errors.New("redis-delete-error")
 		}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		err := service.Logout(context.Background(), "token")
 
 		if err == nil {
@@ -510,7 +589,7 @@ func TestAuthService_Register_EdgeCases(t *testing.T) {
 		mockSession := &mocks.MockSessionManager{}
 		mockToken := &mocks.MockTokenService{}
 
-		service := application.NewAuthService(mockRepo, mockSession, mockToken)
+		service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
 		// Very long password to trigger bcrypt error
 		longPassword := string(make([]byte, 80))
 		_, err := service.Register(context.Background(), "Name", "email@test.com", longPassword)
@@ -520,3 +599,363 @@ func TestAuthService_Register_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestAuthService_RevokeAllSessions(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	mockSession := &mocks.MockSessionManager{}
+	mockToken := &mocks.MockTokenService{}
+
+	mockSession.UserSessionIDsFunc = func(ctx context.Context, userID string) ([]string, error) {
+		return []string{"session-1", "session-2"}, nil
+	}
+	mockSession.GetSessionFunc = func(ctx context.Context, key string) (string, error) {
+		switch key {
+		case "login:session-1":
+			return `{"userId":"user-id","accessTokenId":"access-1","refreshTokenId":"refresh-1"}`, nil
+		case "login:session-2":
+			return `{"userId":"user-id","accessTokenId":"access-2","refreshTokenId":"refresh-2"}`, nil
+		}
+		return "", nil
+	}
+	deleted := make(map[string]bool)
+	mockSession.DeleteSessionFunc = func(ctx context.Context, key string) error {
+		deleted[key] = true
+		return nil
+	}
+	var unindexed []string
+	mockSession.UnindexUserSessionFunc = func(ctx context.Context, userID, sessionID string) error {
+		unindexed = append(unindexed, sessionID)
+		return nil
+	}
+
+	service := application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+	revoked, err := service.RevokeAllSessions(context.Background(), "user-id", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if revoked != 2 {
+		t.Errorf("expected 2 sessions to be revoked but got %d", revoked)
+	}
+	for _, key := range []string{"access:access-1", "refresh:refresh-1", "login:session-1", "access:access-2", "refresh:refresh-2", "login:session-2"} {
+		if !deleted[key] {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+	if len(unindexed) != 2 {
+		t.Errorf("expected both sessions to be unindexed but got %v", unindexed)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.DefaultCost)
+
+	newService := func(revoked *[]string, saved *string) *application.AuthService {
+		mockRepo := &mocks.MockUserRepository{}
+		mockSession := &mocks.MockSessionManager{}
+		mockToken := &mocks.MockTokenService{}
+
+		mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Password: string(hashedPassword)}, nil
+		}
+		mockRepo.UpdatePasswordFunc = func(ctx context.Context, id string, passwordHash string) error {
+			*saved = passwordHash
+			return nil
+		}
+		mockSession.UserSessionIDsFunc = func(ctx context.Context, userID string) ([]string, error) {
+			return []string{"current-session", "other-session"}, nil
+		}
+		mockSession.UnindexUserSessionFunc = func(ctx context.Context, userID, sessionID string) error {
+			*revoked = append(*revoked, sessionID)
+			return nil
+		}
+		return application.NewAuthService(mockRepo, mockSession, mockToken, &mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+	}
+
+	t.Run("revokes the other sessions", func(t *testing.T) {
+		var revoked []string
+		var saved string
+		service := newService(&revoked, &saved)
+
+		err := service.ChangePassword(context.Background(), "user-id", "current-session", "Password123!", "NewPassword456!")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(saved), []byte("NewPassword456!")) != nil {
+			t.Error("expected the new password to be saved")
+		}
+		if len(revoked) != 1 || revoked[0] != "other-session" {
+			t.Errorf("expected only other-session to be revoked but got %v", revoked)
+		}
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		var revoked []string
+		var saved string
+		service := newService(&revoked, &saved)
+
+		err := service.ChangePassword(context.Background(), "user-id", "current-session", "WrongPassword1!", "NewPassword456!")
+		if err != domain.ErrInvalidCredentials {
+			t.Errorf("expected ErrInvalidCredentials but got %v", err)
+		}
+		if saved != "" || len(revoked) != 0 {
+			t.Error("expected nothing to change")
+		}
+	})
+
+	t.Run("weak new password", func(t *testing.T) {
+		var revoked []string
+		var saved string
+		service := newService(&revoked, &saved)
+
+		err := service.ChangePassword(context.Background(), "user-id", "current-session", "Password123!", "weak")
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+		if saved != "" {
+			t.Error("expected the password not to be saved")
+		}
+	})
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	mockSession := &mocks.MockSessionManager{}
+	mockToken := &mocks.MockTokenService{}
+	mockNotifier := &mocks.MockNotifier{}
+
+	mockRepo.FindByEmailFunc = func(ctx context.Context, email string) (*domain.User, error) {
+		if email == "john@example.com" {
+			return &domain.User{ID: "user-id", Email: email}, nil
+		}
+		return nil, domain.ErrUserNotFound
+	}
+	var saved string
+	mockRepo.UpdatePasswordFunc = func(ctx context.Context, id string, passwordHash string) error {
+		saved = passwordHash
+		return nil
+	}
+
+	// Redis ในหน่วยความจำ พร้อม TakeSession ที่ลบทันทีเหมือน GETDEL
+	sessions := make(map[string]string)
+	mockSession.StoreSessionFunc = func(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+		raw, _ := json.Marshal(data)
+		sessions[key] = string(raw)
+		return nil
+	}
+	mockSession.TakeSessionFunc = func(ctx context.Context, key string) (string, error) {
+		value := sessions[key]
+		delete(sessions, key)
+		return value, nil
+	}
+
+	var sent []domain.Notification
+	mockNotifier.SendFunc = func(ctx context.Context, notification domain.Notification) error {
+		sent = append(sent, notification)
+		return nil
+	}
+
+	policy := domain.DefaultAccountPolicy()
+	service := application.NewAuthService(mockRepo, mockSession, mockToken, mockNotifier, policy)
+
+	t.Run("unknown email sends nothing", func(t *testing.T) {
+		if err := service.ForgotPassword(context.Background(), "nobody@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sent) != 0 {
+			t.Errorf("expected no notification but got %d", len(sent))
+		}
+	})
+
+	t.Run("reset with the sent token once", func(t *testing.T) {
+		if err := service.ForgotPassword(context.Background(), "john@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sent) != 1 || sent[0].To != "john@example.com" || sent[0].Kind != domain.NotificationPasswordReset {
+			t.Fatalf("expected a reset notification to john@example.com but got %+v", sent)
+		}
+		idx := strings.Index(sent[0].Body, policy.PasswordResetURL)
+		if idx < 0 {
+			t.Fatalf("expected the reset link in %q", sent[0].Body)
+		}
+		token := sent[0].Body[idx+len(policy.PasswordResetURL):]
+		for key := range sessions {
+			if strings.Contains(key, token) {
+				t.Errorf("expected the token to be stored hashed but got key %s", key)
+			}
+		}
+
+		if err := service.ResetPassword(context.Background(), token, "NewPassword456!"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(saved), []byte("NewPassword456!")) != nil {
+			t.Error("expected the new password to be saved")
+		}
+
+		if err := service.ResetPassword(context.Background(), token, "OtherPassword789!"); err != domain.ErrInvalidResetToken {
+			t.Errorf("expected a used token to be rejected but got %v", err)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		if err := service.ResetPassword(context.Background(), "not-a-token", "NewPassword456!"); err != domain.ErrInvalidResetToken {
+			t.Errorf("expected ErrInvalidResetToken but got %v", err)
+		}
+	})
+}
+
+func TestAuthService_EmailVerification(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	mockSession := &mocks.MockSessionManager{}
+	mockToken := &mocks.MockTokenService{}
+	mockNotifier := &mocks.MockNotifier{}
+
+	sessions := make(map[string]string)
+	mockSession.StoreSessionFunc = func(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+		raw, _ := json.Marshal(data)
+		sessions[key] = string(raw)
+		return nil
+	}
+	mockSession.TakeSessionFunc = func(ctx context.Context, key string) (string, error) {
+		value := sessions[key]
+		delete(sessions, key)
+		return value, nil
+	}
+	var sent []domain.Notification
+	mockNotifier.SendFunc = func(ctx context.Context, notification domain.Notification) error {
+		sent = append(sent, notification)
+		return nil
+	}
+	mockRepo.CreateFunc = func(ctx context.Context, user *domain.User) error {
+		user.ID = "user-id"
+		return nil
+	}
+	var verifiedID, verifiedEmail string
+	mockRepo.MarkEmailVerifiedFunc = func(ctx context.Context, id string, email string) error {
+		verifiedID, verifiedEmail = id, email
+		return nil
+	}
+
+	policy := domain.DefaultAccountPolicy()
+	service := application.NewAuthService(mockRepo, mockSession, mockToken, mockNotifier, policy)
+
+	t.Run("register sends a single-use verification link", func(t *testing.T) {
+		user, err := service.Register(context.Background(), "John Doe", "john@example.com", "Password123!")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user.EmailVerified {
+			t.Error("expected a new account to be unverified")
+		}
+		if len(sent) != 1 || sent[0].Kind != domain.NotificationEmailVerification || sent[0].To != "john@example.com" {
+			t.Fatalf("expected a verification email to john@example.com but got %+v", sent)
+		}
+
+		idx := strings.Index(sent[0].Body, policy.EmailVerificationURL)
+		if idx < 0 {
+			t.Fatalf("expected the verification link in %q", sent[0].Body)
+		}
+		token := sent[0].Body[idx+len(policy.EmailVerificationURL):]
+
+		if err := service.VerifyEmail(context.Background(), token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if verifiedID != "user-id" || verifiedEmail != "john@example.com" {
+			t.Errorf("expected john@example.com of user-id to be verified but got %s %s", verifiedID, verifiedEmail)
+		}
+		if err := service.VerifyEmail(context.Background(), token); err != domain.ErrInvalidVerifyToken {
+			t.Errorf("expected a used token to be rejected but got %v", err)
+		}
+	})
+
+	t.Run("resend to a verified account", func(t *testing.T) {
+		mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "john@example.com", EmailVerified: true}, nil
+		}
+
+		if err := service.ResendVerification(context.Background(), "user-id"); err != domain.ErrEmailVerified {
+			t.Errorf("expected ErrEmailVerified but got %v", err)
+		}
+	})
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	mockSession := &mocks.MockSessionManager{}
+	now := time.Now()
+	mockSession.UserSessionsFunc = func(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+		return []domain.LoginSession{
+			{ID: "laptop", LastSeenAt: now.Add(-time.Hour)},
+			{ID: "phone", LastSeenAt: now},
+		}, nil
+	}
+	mockSession.UserSessionIDsFunc = func(ctx context.Context, userID string) ([]string, error) {
+		return []string{"laptop", "phone"}, nil
+	}
+	var unindexed []string
+	mockSession.UnindexUserSessionFunc = func(ctx context.Context, userID, sessionID string) error {
+		unindexed = append(unindexed, sessionID)
+		return nil
+	}
+
+	service := application.NewAuthService(&mocks.MockUserRepository{}, mockSession, &mocks.MockTokenService{},
+		&mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+
+	t.Run("list most recently used first", func(t *testing.T) {
+		sessions, err := service.ListSessions(context.Background(), "user-id", "laptop")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].ID != "phone" || sessions[1].ID != "laptop" {
+			t.Fatalf("expected [phone laptop] but got %+v", sessions)
+		}
+		if sessions[0].Current || !sessions[1].Current {
+			t.Errorf("expected only the laptop session to be current but got %+v", sessions)
+		}
+	})
+
+	t.Run("revoke one session", func(t *testing.T) {
+		unindexed = nil
+		if err := service.RevokeSession(context.Background(), "user-id", "phone"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(unindexed) != 1 || unindexed[0] != "phone" {
+			t.Errorf("expected the phone session to be revoked but got %v", unindexed)
+		}
+	})
+
+	t.Run("revoke another user's session", func(t *testing.T) {
+		unindexed = nil
+		if err := service.RevokeSession(context.Background(), "user-id", "someone-else"); err != domain.ErrSessionNotFound {
+			t.Errorf("expected ErrSessionNotFound but got %v", err)
+		}
+		if len(unindexed) != 0 {
+			t.Errorf("expected nothing to be revoked but got %v", unindexed)
+		}
+	})
+
+	t.Run("logout everywhere", func(t *testing.T) {
+		unindexed = nil
+		revoked, err := service.RevokeAllSessions(context.Background(), "user-id", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if revoked != 2 || len(unindexed) != 2 {
+			t.Errorf("expected both sessions to be revoked but got %d %v", revoked, unindexed)
+		}
+	})
+}
+
+func TestDeviceFromUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120.0":                "Edge on macOS",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                                                                  "Firefox on Linux",
+		"curl/8.4.0": "curl",
+		"":           "Unknown device",
+	}
+	for userAgent, want := range tests {
+		if got := domain.DeviceFromUserAgent(userAgent); got != want {
+			t.Errorf("DeviceFromUserAgent(%q) = %q, want %q", userAgent, got, want)
+		}
+	}
+}
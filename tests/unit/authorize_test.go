@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/backend-challenge/user-api/internal/adapters/http/middleware"
+	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/tests/mocks"
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthorizedRouter(policy middleware.Policy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+		var roles []domain.Role
+		for _, role := range strings.Split(c.GetHeader("X-Roles"), ",") {
+			roles = append(roles, domain.Role(role))
+		}
+		c.Set("roles", roles)
+	})
+	router.PUT("/users/:id", middleware.Authorize(policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func sendAuthorized(router *gin.Engine, user string, roles string, target string) int {
+	req := httptest.NewRequest(http.MethodPut, "/users/"+target, nil)
+	req.Header.Set("X-User", user)
+	req.Header.Set("X-Roles", roles)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	t.Run("self or admin", func(t *testing.T) {
+		router := newAuthorizedRouter(middleware.SelfOrRoles("id", domain.RoleAdmin))
+
+		tests := []struct {
+			name   string
+			user   string
+			roles  string
+			target string
+			want   int
+		}{
+			{"own account", "user-1", "user", "user-1", http.StatusOK},
+			{"someone else's account", "user-1", "user", "user-2", http.StatusForbidden},
+			{"operator on someone else's account", "user-1", "user,operator", "user-2", http.StatusForbidden},
+			{"admin on someone else's account", "user-1", "user,admin", "user-2", http.StatusOK},
+			{"no authenticated user", "", "admin", "user-2", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := sendAuthorized(router, tt.user, tt.roles, tt.target); got != tt.want {
+					t.Errorf("expected status %d but got %d", tt.want, got)
+				}
+			})
+		}
+	})
+
+	t.Run("required roles", func(t *testing.T) {
+		router := newAuthorizedRouter(middleware.RequireRoles(domain.RoleAdmin, domain.RoleOperator))
+
+		if got := sendAuthorized(router, "user-1", "user", "user-1"); got != http.StatusForbidden {
+			t.Errorf("expected a plain user to be forbidden but got %d", got)
+		}
+		if got := sendAuthorized(router, "user-1", "user,operator", "user-2"); got != http.StatusOK {
+			t.Errorf("expected an operator to be allowed but got %d", got)
+		}
+	})
+}
+
+func TestRequireVerifiedEmailMiddleware(t *testing.T) {
+	repo := &mocks.MockUserRepository{
+		FindByIDFunc: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, EmailVerified: id == "verified"}, nil
+		},
+	}
+	userService := application.NewUserService(repo, &mocks.MockAccountSecurity{})
+
+	newRouter := func(required bool) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(middleware.ErrorHandler())
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", c.GetHeader("X-User"))
+		})
+		router.POST("/reserve", middleware.RequireVerifiedEmail(userService, required), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+		return router
+	}
+	send := func(router *gin.Engine, user string) int {
+		req := httptest.NewRequest(http.MethodPost, "/reserve", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	required := newRouter(true)
+	if got := send(required, "unverified"); got != http.StatusForbidden {
+		t.Errorf("expected an unverified user to be forbidden but got %d", got)
+	}
+	if got := send(required, "verified"); got != http.StatusCreated {
+		t.Errorf("expected a verified user to pass but got %d", got)
+	}
+	if got := send(newRouter(false), "unverified"); got != http.StatusCreated {
+		t.Errorf("expected everyone to pass when verification is not required but got %d", got)
+	}
+}
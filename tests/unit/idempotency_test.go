@@ -0,0 +1,217 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/http/middleware"
+	"github.com/backend-challenge/user-api/internal/adapters/redis"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/tests/mocks"
+	"github.com/gin-gonic/gin"
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+// memoryIdempotencyStore backs the mock store with a map, the way the Redis store behaves
+func memoryIdempotencyStore() *mocks.MockIdempotencyStore {
+	var mu sync.Mutex
+	records := make(map[string]*domain.IdempotentResponse)
+	return &mocks.MockIdempotencyStore{
+		BeginFunc: func(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if existing, ok := records[key]; ok {
+				return existing, nil
+			}
+			records[key] = &domain.IdempotentResponse{Fingerprint: fingerprint}
+			return nil, nil
+		},
+		CompleteFunc: func(ctx context.Context, key string, response *domain.IdempotentResponse, ttl time.Duration) error {
+			mu.Lock()
+			defer mu.Unlock()
+			records[key] = response
+			return nil
+		},
+		ReleaseFunc: func(ctx context.Context, key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(records, key)
+			return nil
+		},
+	}
+}
+
+func newIdempotentRouter(store *mocks.MockIdempotencyStore, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User"))
+	})
+	router.POST("/reserve", middleware.Idempotency(store, time.Hour), handler)
+	return router
+}
+
+func sendIdempotent(router *gin.Engine, user string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/reserve", strings.NewReader(body))
+	req.Header.Set("X-User", user)
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Run("replays the first response", func(t *testing.T) {
+		calls := 0
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			calls++
+			c.JSON(http.StatusCreated, gin.H{"call": calls})
+		})
+
+		first := sendIdempotent(router, "user-123", "key-1", `{"pattern":"1*****"}`)
+		second := sendIdempotent(router, "user-123", "key-1", `{"pattern":"1*****"}`)
+
+		if calls != 1 {
+			t.Errorf("expected the handler to run once but ran %d times", calls)
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expected the replay to match %d %s but got %d %s", first.Code, first.Body, second.Code, second.Body)
+		}
+		if second.Header().Get(middleware.IdempotentReplayedHeader) != "true" || second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+			t.Errorf("unexpected replay headers %v", second.Header())
+		}
+	})
+
+	t.Run("requests without a key always run", func(t *testing.T) {
+		calls := 0
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			calls++
+			c.Status(http.StatusNoContent)
+		})
+
+		sendIdempotent(router, "user-123", "", "")
+		sendIdempotent(router, "user-123", "", "")
+		if calls != 2 {
+			t.Errorf("expected 2 calls but got %d", calls)
+		}
+	})
+
+	t.Run("keys are scoped to the user", func(t *testing.T) {
+		calls := 0
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			calls++
+			c.Status(http.StatusNoContent)
+		})
+
+		sendIdempotent(router, "user-123", "key-1", "")
+		sendIdempotent(router, "user-456", "key-1", "")
+		if calls != 2 {
+			t.Errorf("expected each user to run once but got %d calls", calls)
+		}
+	})
+
+	t.Run("key reused for a different body", func(t *testing.T) {
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			c.Status(http.StatusCreated)
+		})
+
+		sendIdempotent(router, "user-123", "key-1", `{"pattern":"1*****"}`)
+		w := sendIdempotent(router, "user-123", "key-1", `{"pattern":"2*****"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422 but got %d", w.Code)
+		}
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		store := &mocks.MockIdempotencyStore{
+			BeginFunc: func(ctx context.Context, key string, fingerprint string, lockTTL time.Duration) (*domain.IdempotentResponse, error) {
+				return &domain.IdempotentResponse{Fingerprint: fingerprint}, nil
+			},
+		}
+		router := newIdempotentRouter(store, func(c *gin.Context) {
+			t.Error("handler must not run while the key is claimed")
+		})
+
+		if w := sendIdempotent(router, "user-123", "key-1", ""); w.Code != http.StatusConflict {
+			t.Errorf("expected 409 but got %d", w.Code)
+		}
+	})
+
+	t.Run("errors are not stored so the retry runs again", func(t *testing.T) {
+		calls := 0
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			calls++
+			if calls == 1 {
+				c.Error(domain.ErrQuotaExceeded)
+				return
+			}
+			c.Status(http.StatusCreated)
+		})
+
+		if w := sendIdempotent(router, "user-123", "key-1", ""); w.Code != http.StatusTooManyRequests {
+			t.Errorf("expected 429 but got %d", w.Code)
+		}
+		if w := sendIdempotent(router, "user-123", "key-1", ""); w.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("expected the retry to run but got %d after %d calls", w.Code, calls)
+		}
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		router := newIdempotentRouter(memoryIdempotencyStore(), func(c *gin.Context) {
+			t.Error("handler must not run")
+		})
+
+		if w := sendIdempotent(router, "user-123", strings.Repeat("k", 256), ""); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 but got %d", w.Code)
+		}
+	})
+}
+
+// Note: This test requires a redis server to be running on localhost:6379
+func TestIdempotencyStore(t *testing.T) {
+	client := redisClient.NewClient(&redisClient.Options{
+		Addr: "localhost:6379",
+	})
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skip("Redis is not running on localhost:6379, skipping IdempotencyStore tests")
+		return
+	}
+
+	store := redis.NewIdempotencyStore(client)
+	key := "test-idempotency-key"
+	client.Del(ctx, "idempotency:"+key)
+	defer client.Del(ctx, "idempotency:"+key)
+
+	existing, err := store.Begin(ctx, key, "fp", time.Minute)
+	if err != nil || existing != nil {
+		t.Fatalf("expected the key to be claimed but got %+v, %v", existing, err)
+	}
+
+	existing, err = store.Begin(ctx, key, "fp", time.Minute)
+	if err != nil || existing == nil || existing.Completed {
+		t.Fatalf("expected a pending record but got %+v, %v", existing, err)
+	}
+
+	if err := store.Complete(ctx, key, &domain.IdempotentResponse{Fingerprint: "fp", Completed: true, Status: 201, Body: []byte(`{"ok":true}`)}, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	existing, _ = store.Begin(ctx, key, "fp", time.Minute)
+	if existing == nil || !existing.Completed || existing.Status != 201 || string(existing.Body) != `{"ok":true}` {
+		t.Errorf("expected the completed response but got %+v", existing)
+	}
+
+	store.Release(ctx, key)
+	if existing, _ := store.Begin(ctx, key, "fp", time.Minute); existing != nil {
+		t.Errorf("expected the released key to be claimable but got %+v", existing)
+	}
+}
@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLotteryPatternFilter(t *testing.T) {
+	anyDigit := bson.M{"$in": []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected bson.M
+	}{
+		{
+			name:    "leading wildcards become $in of all digits",
+			pattern: "****23",
+			expected: bson.M{
+				"d0": anyDigit, "d1": anyDigit, "d2": anyDigit, "d3": anyDigit,
+				"d4": 2, "d5": 3,
+			},
+		},
+		{
+			name:     "trailing wildcards are omitted",
+			pattern:  "prefix:12",
+			expected: bson.M{"d0": 1, "d1": 2},
+		},
+		{
+			name:     "digit set",
+			pattern:  "[1-3]*****",
+			expected: bson.M{"d0": bson.M{"$in": []int{1, 2, 3}}},
+		},
+		{
+			name:     "match all",
+			pattern:  "******",
+			expected: bson.M{},
+		},
+		{
+			name:     "contains keeps a regex on number",
+			pattern:  "contains:77",
+			expected: bson.M{"number": bson.M{"$regex": "^(?=.*77)......$"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := domain.ParseLotteryPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := mongodb.LotteryPatternFilter(pattern)
+			gotJSON, _ := bson.MarshalExtJSON(got, false, false)
+			expectedJSON, _ := bson.MarshalExtJSON(tt.expected, false, false)
+			if len(got) != len(tt.expected) || !sameFields(got, tt.expected) {
+				t.Errorf("expected filter %s but got %s", expectedJSON, gotJSON)
+			}
+		})
+	}
+}
+
+func sameFields(a, b bson.M) bool {
+	for k, v := range b {
+		av, ok := a[k]
+		if !ok {
+			return false
+		}
+		aj, _ := bson.MarshalExtJSON(bson.M{"v": av}, false, false)
+		bj, _ := bson.MarshalExtJSON(bson.M{"v": v}, false, false)
+		if string(aj) != string(bj) {
+			return false
+		}
+	}
+	return true
+}
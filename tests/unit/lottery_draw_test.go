@@ -0,0 +1,441 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/tests/mocks"
+)
+
+// drawResults returns a valid set of results with the given first prize
+func drawResults(first string) map[domain.PrizeTier][]string {
+	results := map[domain.PrizeTier][]string{
+		domain.PrizeFirst:      {first},
+		domain.PrizeFirstThree: {"111", "222"},
+		domain.PrizeLastThree:  {"333", "444"},
+		domain.PrizeLastTwo:    {"55"},
+	}
+	next := 500000
+	for _, rule := range domain.PrizeTierRules {
+		if rule.Digits != 6 || rule.Tier == domain.PrizeFirst || rule.Tier == domain.PrizeFirstAdjacent {
+			continue
+		}
+		for i := 0; i < rule.Count; i++ {
+			results[rule.Tier] = append(results[rule.Tier], fmt.Sprintf("%06d", next))
+			next++
+		}
+	}
+	return results
+}
+
+func TestNextDrawDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected string
+	}{
+		{"before the 1st draw", time.Date(2026, 3, 1, 10, 0, 0, 0, domain.DrawLocation), "2026-03-01"},
+		{"after the 1st draw", time.Date(2026, 3, 1, 16, 0, 0, 0, domain.DrawLocation), "2026-03-16"},
+		{"after the 16th draw rolls into next year", time.Date(2026, 12, 20, 0, 0, 0, 0, domain.DrawLocation), "2027-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := domain.NextDrawDate(tt.now)
+			if got.Format("2006-01-02") != tt.expected || got.Hour() != 16 {
+				t.Errorf("expected %s 16:00 but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLotteryDraw_RecordResults(t *testing.T) {
+	t.Run("derives adjacent numbers", func(t *testing.T) {
+		draw := domain.NewLotteryDraw(time.Now(), domain.DefaultDrawTemplate())
+		if err := draw.RecordResults(drawResults("999999"), time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if draw.Status != domain.DrawStatusDrawn || draw.DrawnAt == nil {
+			t.Errorf("expected drawn status but got %s", draw.Status)
+		}
+		won := draw.PrizesFor("000000")
+		if len(won) != 1 || won[0].Tier != domain.PrizeFirstAdjacent {
+			t.Errorf("expected 000000 to win first adjacent but got %+v", won)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		mutate func(map[domain.PrizeTier][]string)
+		err    error
+	}{
+		{"missing tier", func(r map[domain.PrizeTier][]string) { delete(r, domain.PrizeLastTwo) }, domain.ErrRequestInvalid},
+		{"wrong digit count", func(r map[domain.PrizeTier][]string) { r[domain.PrizeLastTwo] = []string{"555"} }, domain.ErrRequestInvalid},
+		{"repeated number", func(r map[domain.PrizeTier][]string) { r[domain.PrizeFirstThree] = []string{"111", "111"} }, domain.ErrRequestInvalid},
+		{"unknown tier", func(r map[domain.PrizeTier][]string) { r["sixth"] = []string{"123456"} }, domain.ErrRequestInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := drawResults("123456")
+			tt.mutate(results)
+			draw := domain.NewLotteryDraw(time.Now(), domain.DefaultDrawTemplate())
+			if err := draw.RecordResults(results, time.Now()); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("already drawn", func(t *testing.T) {
+		draw := domain.NewLotteryDraw(time.Now(), domain.DefaultDrawTemplate())
+		draw.RecordResults(drawResults("123456"), time.Now())
+		if err := draw.RecordResults(drawResults("123456"), time.Now()); !errors.Is(err, domain.ErrDrawAlreadyDrawn) {
+			t.Errorf("expected already drawn error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryDraw_PrizesFor(t *testing.T) {
+	draw := domain.NewLotteryDraw(time.Now(), domain.DefaultDrawTemplate())
+	if err := draw.RecordResults(drawResults("123455"), time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		number string
+		tiers  []domain.PrizeTier
+	}{
+		{"123455", []domain.PrizeTier{domain.PrizeFirst, domain.PrizeLastTwo}},
+		{"123456", []domain.PrizeTier{domain.PrizeFirstAdjacent}},
+		{"111333", []domain.PrizeTier{domain.PrizeFirstThree, domain.PrizeLastThree}},
+		{"500000", []domain.PrizeTier{domain.PrizeSecond}},
+		{"987654", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			won := draw.PrizesFor(tt.number)
+			if len(won) != len(tt.tiers) {
+				t.Fatalf("expected %v but got %+v", tt.tiers, won)
+			}
+			for i, tier := range tt.tiers {
+				if won[i].Tier != tier {
+					t.Errorf("expected tier %s but got %s", tier, won[i].Tier)
+				}
+			}
+		})
+	}
+}
+
+func TestLotteryDrawService_CheckTickets(t *testing.T) {
+	drawn := domain.NewLotteryDraw(time.Now().Add(-24*time.Hour), domain.DefaultDrawTemplate())
+	drawn.ID = "draw-1"
+	drawn.RecordResults(drawResults("123456"), time.Now())
+
+	t.Run("sums winnings across tickets", func(t *testing.T) {
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return drawn, nil
+			},
+		}
+		tickets := &mocks.MockLotteryRepository{
+			FindSoldByUserFunc: func(ctx context.Context, userID string, drawID string) ([]domain.LotteryTicket, error) {
+				if drawID != "draw-1" {
+					t.Errorf("expected tickets for draw-1 but got %s", drawID)
+				}
+				return []domain.LotteryTicket{{Number: "123456"}, {Number: "000055"}, {Number: "987654"}}, nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, tickets, domain.DefaultDrawTemplate())
+
+		result, err := service.CheckTickets(context.Background(), "draw-1", "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result.Tickets) != 3 {
+			t.Errorf("expected 3 checked tickets but got %d", len(result.Tickets))
+		}
+		// รางวัลที่ 1 ไม่ถูกเลขท้าย 2 ตัว (55) ส่วน 000055 ถูกเลขท้าย 2 ตัว
+		if result.TotalWinnings != 6000000+2000 {
+			t.Errorf("expected total winnings 6002000 but got %d", result.TotalWinnings)
+		}
+	})
+
+	t.Run("draw not drawn yet", func(t *testing.T) {
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return domain.NewLotteryDraw(time.Now().Add(24*time.Hour), domain.DefaultDrawTemplate()), nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, &mocks.MockLotteryRepository{}, domain.DefaultDrawTemplate())
+
+		if _, err := service.CheckTickets(context.Background(), "draw-1", "user-123"); !errors.Is(err, domain.ErrDrawNotDrawn) {
+			t.Errorf("expected not drawn error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryDrawService_RecordResults(t *testing.T) {
+	t.Run("before draw date", func(t *testing.T) {
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return domain.NewLotteryDraw(time.Now().Add(48*time.Hour), domain.DefaultDrawTemplate()), nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, &mocks.MockLotteryRepository{}, domain.DefaultDrawTemplate())
+
+		if _, err := service.RecordResults(context.Background(), "draw-1", drawResults("123456")); !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+
+	t.Run("saves results", func(t *testing.T) {
+		saved := false
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return domain.NewLotteryDraw(time.Now().Add(-48*time.Hour), domain.DefaultDrawTemplate()), nil
+			},
+			SaveResultsFunc: func(ctx context.Context, draw *domain.LotteryDraw) error {
+				saved = draw.Status == domain.DrawStatusDrawn
+				return nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, &mocks.MockLotteryRepository{}, domain.DefaultDrawTemplate())
+
+		if _, err := service.RecordResults(context.Background(), "draw-1", drawResults("123456")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !saved {
+			t.Error("expected drawn results to be saved")
+		}
+	})
+}
+
+func TestLotteryDraw_IsOnSale(t *testing.T) {
+	draw := domain.NewLotteryDraw(time.Date(2026, 3, 16, 0, 0, 0, 0, domain.DrawLocation), domain.DefaultDrawTemplate())
+	stocked := draw.SalesOpenAt
+	inWindow := draw.SalesOpenAt.Add(time.Hour)
+
+	if draw.IsOnSale(inWindow) {
+		t.Error("expected a round without inventory not to be on sale")
+	}
+
+	draw.StockedAt = &stocked
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{"before sales open", draw.SalesOpenAt.Add(-time.Second), false},
+		{"when sales open", draw.SalesOpenAt, true},
+		{"when sales close", draw.SalesCloseAt, false},
+		{"on draw date", draw.DrawDate, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := draw.IsOnSale(tt.now); got != tt.expected {
+				t.Errorf("expected on sale %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLotteryDraw_SetSalesWindow(t *testing.T) {
+	draw := domain.NewLotteryDraw(time.Date(2026, 3, 16, 0, 0, 0, 0, domain.DrawLocation), domain.DefaultDrawTemplate())
+
+	if err := draw.SetSalesWindow(draw.DrawDate.Add(-time.Hour), draw.DrawDate.Add(-2*time.Hour)); !errors.Is(err, domain.ErrRequestInvalid) {
+		t.Errorf("expected invalid request for reversed window but got %v", err)
+	}
+	if err := draw.SetSalesWindow(draw.DrawDate.Add(-time.Hour), draw.DrawDate.Add(time.Hour)); !errors.Is(err, domain.ErrRequestInvalid) {
+		t.Errorf("expected invalid request for closing after the draw but got %v", err)
+	}
+
+	openAt, closeAt := draw.DrawDate.Add(-48*time.Hour), draw.DrawDate.Add(-2*time.Hour)
+	if err := draw.SetSalesWindow(openAt, closeAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmpl := draw.Template(); tmpl.SalesOpenLead != 48*time.Hour || tmpl.SalesCloseLead != 2*time.Hour {
+		t.Errorf("expected template to follow the new window but got %+v", tmpl)
+	}
+}
+
+func TestLotteryDrawService_CreateNextDraw(t *testing.T) {
+	t.Run("copies the latest round", func(t *testing.T) {
+		latest := domain.NewLotteryDraw(domain.NextDrawDate(time.Now()), domain.DrawTemplate{
+			TicketCount:    500,
+			SalesOpenLead:  72 * time.Hour,
+			SalesCloseLead: 30 * time.Minute,
+		})
+		draws := &mocks.MockLotteryDrawRepository{
+			FindLatestFunc: func(ctx context.Context) (*domain.LotteryDraw, error) {
+				return latest, nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, &mocks.MockLotteryRepository{}, domain.DefaultDrawTemplate())
+
+		draw, err := service.CreateNextDraw(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !draw.DrawDate.Equal(domain.NextDrawDate(latest.DrawDate)) {
+			t.Errorf("expected draw after %v but got %v", latest.DrawDate, draw.DrawDate)
+		}
+		if draw.Template() != latest.Template() {
+			t.Errorf("expected template %+v but got %+v", latest.Template(), draw.Template())
+		}
+	})
+
+	t.Run("rejects an invalid template", func(t *testing.T) {
+		service := application.NewLotteryDrawService(&mocks.MockLotteryDrawRepository{}, &mocks.MockLotteryRepository{}, domain.DefaultDrawTemplate())
+
+		_, err := service.CreateNextDraw(context.Background(), &domain.DrawTemplate{TicketCount: 0})
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryDrawService_StockInventories(t *testing.T) {
+	draw := domain.NewLotteryDraw(domain.NextDrawDate(time.Now()), domain.DrawTemplate{
+		TicketCount:    250,
+		SalesOpenLead:  24 * time.Hour,
+		SalesCloseLead: time.Hour,
+	})
+	draw.ID = "draw-1"
+
+	var seeded, stocked string
+	draws := &mocks.MockLotteryDrawRepository{
+		FindUnstockedFunc: func(ctx context.Context) ([]*domain.LotteryDraw, error) {
+			return []*domain.LotteryDraw{draw}, nil
+		},
+		MarkStockedFunc: func(ctx context.Context, id string, stockedAt time.Time) error {
+			if seeded == "" {
+				t.Error("expected tickets to be seeded before the round is marked stocked")
+			}
+			stocked = id
+			return nil
+		},
+	}
+	tickets := &mocks.MockLotteryRepository{
+		SeedTicketsFunc: func(ctx context.Context, plan domain.TicketSeedPlan) error {
+			if plan.From != 0 || plan.To != draw.TicketCount {
+				t.Errorf("expected numbers 0-%d but got %d-%d", draw.TicketCount, plan.From, plan.To)
+			}
+			seeded = plan.DrawID
+			return nil
+		},
+	}
+	service := application.NewLotteryDrawService(draws, tickets, domain.DefaultDrawTemplate())
+
+	count, err := service.StockInventories(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 || seeded != draw.ID || stocked != draw.ID {
+		t.Errorf("expected draw-1 to be stocked but got count=%d seeded=%q stocked=%q", count, seeded, stocked)
+	}
+}
+
+func TestLotteryDrawService_SeedDraw(t *testing.T) {
+	draw := domain.NewLotteryDraw(domain.NextDrawDate(time.Now()), domain.DrawTemplate{
+		TicketCount:    250,
+		SetsPerNumber:  2,
+		SalesOpenLead:  24 * time.Hour,
+		SalesCloseLead: time.Hour,
+	})
+	draw.ID = "draw-1"
+
+	newService := func(seeded *domain.TicketSeedPlan, stocked *string) *application.LotteryDrawService {
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				copied := *draw
+				return &copied, nil
+			},
+			MarkStockedFunc: func(ctx context.Context, id string, stockedAt time.Time) error {
+				*stocked = id
+				return nil
+			},
+		}
+		tickets := &mocks.MockLotteryRepository{
+			SeedTicketsFunc: func(ctx context.Context, plan domain.TicketSeedPlan) error {
+				*seeded = plan
+				return nil
+			},
+		}
+		return application.NewLotteryDrawService(draws, tickets, domain.DefaultDrawTemplate())
+	}
+
+	t.Run("whole inventory marks the round stocked", func(t *testing.T) {
+		var seeded domain.TicketSeedPlan
+		var stocked string
+		service := newService(&seeded, &stocked)
+
+		if _, err := service.SeedDraw(context.Background(), domain.TicketSeedPlan{DrawID: draw.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seeded.To != draw.TicketCount || seeded.Sets != draw.SetsPerNumber || seeded.BatchSize != domain.DefaultSeedBatchSize {
+			t.Errorf("expected the round's inventory to be seeded but got %+v", seeded)
+		}
+		if stocked != draw.ID {
+			t.Errorf("expected %s to be marked stocked but got %q", draw.ID, stocked)
+		}
+	})
+
+	t.Run("moves tickets from before rounds into the round first", func(t *testing.T) {
+		var steps []string
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				copied := *draw
+				return &copied, nil
+			},
+		}
+		tickets := &mocks.MockLotteryRepository{
+			AssignUnscopedTicketsFunc: func(ctx context.Context, drawID string) (int64, error) {
+				steps = append(steps, "assign "+drawID)
+				return 3, nil
+			},
+			SeedTicketsFunc: func(ctx context.Context, plan domain.TicketSeedPlan) error {
+				steps = append(steps, "seed "+plan.DrawID)
+				return nil
+			},
+		}
+		service := application.NewLotteryDrawService(draws, tickets, domain.DefaultDrawTemplate())
+
+		if _, err := service.SeedDraw(context.Background(), domain.TicketSeedPlan{DrawID: draw.ID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(steps) != 2 || steps[0] != "assign "+draw.ID || steps[1] != "seed "+draw.ID {
+			t.Errorf("expected unscoped tickets to be assigned to %s before seeding but got %v", draw.ID, steps)
+		}
+	})
+
+	t.Run("partial range leaves the round unstocked", func(t *testing.T) {
+		var seeded domain.TicketSeedPlan
+		var stocked string
+		service := newService(&seeded, &stocked)
+
+		if _, err := service.SeedDraw(context.Background(), domain.TicketSeedPlan{DrawID: draw.ID, From: 100, To: 200, BatchSize: 50}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seeded.From != 100 || seeded.To != 200 || seeded.BatchSize != 50 {
+			t.Errorf("expected numbers 100-200 in batches of 50 but got %+v", seeded)
+		}
+		if stocked != "" {
+			t.Errorf("expected the round to stay unstocked but %q was marked", stocked)
+		}
+	})
+
+	t.Run("range beyond the inventory", func(t *testing.T) {
+		var seeded domain.TicketSeedPlan
+		var stocked string
+		service := newService(&seeded, &stocked)
+
+		_, err := service.SeedDraw(context.Background(), domain.TicketSeedPlan{DrawID: draw.ID, To: 500})
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
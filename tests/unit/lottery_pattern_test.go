@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+func TestParseLotteryPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		key       string
+		regex     string
+		matches   []string
+		noMatches []string
+	}{
+		{
+			name:      "positional wildcard",
+			pattern:   "****23",
+			key:       "****23",
+			regex:     "^....23$",
+			matches:   []string{"000023", "987623"},
+			noMatches: []string{"000032"},
+		},
+		{
+			name:    "suffix shares key with positional",
+			pattern: "suffix:23",
+			key:     "****23",
+			regex:   "^....23$",
+		},
+		{
+			name:      "prefix with digit set",
+			pattern:   "prefix:1[1-3]",
+			key:       "1[1-3]****",
+			regex:     "^1[1-3]....$",
+			matches:   []string{"110000", "139999"},
+			noMatches: []string{"140000", "210000"},
+		},
+		{
+			name:      "contains",
+			pattern:   "contains:77",
+			key:       "******,contains:77",
+			regex:     "^(?=.*77)......$",
+			matches:   []string{"770000", "123778"},
+			noMatches: []string{"707070"},
+		},
+		{
+			name:      "exclude digit",
+			pattern:   "suffix:23,exclude:4",
+			key:       "[0-35-9][0-35-9][0-35-9][0-35-9]23",
+			matches:   []string{"000023"},
+			noMatches: []string{"400023"},
+		},
+		{
+			name:    "negated set is normalized",
+			pattern: "[^4]*****",
+			key:     "[0-35-9]*****",
+		},
+		{
+			name:    "non contiguous set",
+			pattern: "[135]*****",
+			key:     "[135]*****",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := domain.ParseLotteryPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Key() != tt.key {
+				t.Errorf("expected key %q but got %q", tt.key, p.Key())
+			}
+			if tt.regex != "" && p.Regex() != tt.regex {
+				t.Errorf("expected regex %q but got %q", tt.regex, p.Regex())
+			}
+			for _, n := range tt.matches {
+				if !p.Matches(n) {
+					t.Errorf("expected %s to match", n)
+				}
+			}
+			for _, n := range tt.noMatches {
+				if p.Matches(n) {
+					t.Errorf("expected %s not to match", n)
+				}
+			}
+
+			reparsed, err := domain.ParseLotteryPattern(p.Key())
+			if err != nil || reparsed.Key() != p.Key() {
+				t.Errorf("expected key %q to be a valid pattern", p.Key())
+			}
+		})
+	}
+}
+
+func TestParseLotteryPattern_Invalid(t *testing.T) {
+	patterns := []string{
+		"",
+		"123",
+		"123-bc",
+		"1234567",
+		"prefix:1234567",
+		"[1-3*****",
+		"[3-1]*****",
+		"contains:7a",
+		"exclude:",
+		"unknown:1",
+		"exclude:0123456789",
+		"prefix:4,exclude:4",
+		"suffix:11,contains:22222",
+	}
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			_, err := domain.ParseLotteryPattern(pattern)
+			if !errors.Is(err, domain.ErrRequestInvalid) {
+				t.Errorf("expected invalid request error but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,681 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/domain"
+	redisClient "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lotteryRepositoryTestApp names the test's MongoDB client, so fail points only hit its commands
+const lotteryRepositoryTestApp = "lottery-repository-test"
+
+// lotteryFixture is a LotteryRepository on a fresh test database with the clients behind it
+type lotteryFixture struct {
+	repo *mongodb.LotteryRepository
+	db   *mongo.Database
+	rdb  *redisClient.Client
+}
+
+// Note: This test requires MongoDB on localhost:27017 and Redis on localhost:6379.
+// If either is not running the test is skipped.
+func setupLotteryRepository(t *testing.T, draw *domain.LotteryDraw, total int) *mongodb.LotteryRepository {
+	return setupLotteryFixture(t, draw, total).repo
+}
+
+func setupLotteryFixture(t *testing.T, draw *domain.LotteryDraw, total int) *lotteryFixture {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017").SetAppName(lotteryRepositoryTestApp))
+	if err != nil || client.Ping(ctx, nil) != nil {
+		t.Skip("MongoDB is not running on localhost:27017, skipping LotteryRepository tests")
+	}
+	rdb := redisClient.NewClient(&redisClient.Options{Addr: "localhost:6379"})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Skip("Redis is not running on localhost:6379, skipping LotteryRepository tests")
+	}
+
+	rdb.Del(context.Background(), "lottery_pattern:"+draw.ID+":******")
+	db := client.Database("lottery_repository_test")
+	db.Drop(context.Background())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		rdb.Del(context.Background(), "lottery_pattern:"+draw.ID+":******")
+		client.Disconnect(context.Background())
+	})
+
+	repo := mongodb.NewLotteryRepository(db, rdb)
+	if err := repo.SeedTickets(context.Background(), domain.TicketSeedPlan{
+		DrawID:    draw.ID,
+		To:        total,
+		Sets:      draw.SetsPerNumber,
+		BatchSize: domain.DefaultSeedBatchSize,
+	}); err != nil {
+		t.Fatalf("failed to seed tickets: %v", err)
+	}
+	return &lotteryFixture{repo: repo, db: db, rdb: rdb}
+}
+
+// runPrefillWorkers refills the fixture's pattern pools until the test ends
+func (f *lotteryFixture) runPrefillWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.repo.RunPrefillWorkers(ctx, 2)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForPool waits until the Redis pool of the pattern holds tickets
+func (f *lotteryFixture) waitForPool(t *testing.T, drawID, patternKey string) int64 {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if n, _ := f.rdb.SCard(context.Background(), "lottery_pattern:"+drawID+":"+patternKey).Result(); n > 0 {
+			return n
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("pool of %s was not refilled", patternKey)
+	return 0
+}
+
+// failMongoCommand makes the fixture's matching MongoDB commands fail in the given fail point
+// mode until turned off. It needs a server started with enableTestCommands, otherwise the test is skipped.
+func (f *lotteryFixture) failMongoCommand(t *testing.T, mode interface{}, commands ...string) (off func()) {
+	admin := f.db.Client().Database("admin")
+	err := admin.RunCommand(context.Background(), bson.D{
+		{Key: "configureFailPoint", Value: "failCommand"},
+		{Key: "mode", Value: mode},
+		{Key: "data", Value: bson.D{
+			{Key: "failCommands", Value: commands},
+			// BadValue ไม่ใช่ Error ที่ Driver จะลองใหม่เอง
+			{Key: "errorCode", Value: 2},
+			{Key: "appName", Value: lotteryRepositoryTestApp},
+		}},
+	}).Err()
+	if err != nil {
+		t.Skipf("MongoDB fail points are not enabled, skipping: %v", err)
+	}
+
+	off = func() {
+		admin.RunCommand(context.Background(), bson.D{
+			{Key: "configureFailPoint", Value: "failCommand"},
+			{Key: "mode", Value: "off"},
+		})
+	}
+	t.Cleanup(off)
+	return off
+}
+
+func TestLotteryRepository_SearchAndReserveConcurrent(t *testing.T) {
+	const (
+		total   = 100
+		sets    = 2
+		users   = 30
+		perUser = 10
+	)
+	// ผู้ใช้ต้องการ 300 ใบแต่มีเพียง 200 ใบ จึงต้องแย่งกันทั้งใน Pool และใน MongoDB
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-test-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   total,
+		SetsPerNumber: sets,
+		StockedAt:     &now,
+	}
+	f := setupLotteryFixture(t, draw, total)
+	f.runPrefillWorkers(t)
+
+	pattern, _ := domain.ParseLotteryPattern("******")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		ownerOf = make(map[string]string)
+		held    = make(map[string]int)
+	)
+	reserve := func(userID string, limit int) int {
+		tickets, err := f.repo.SearchAndReserve(context.Background(), draw, pattern, userID, limit, time.Minute)
+		if err != nil {
+			t.Errorf("search failed for %s: %v", userID, err)
+			return 0
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, ticket := range tickets {
+			if ticket.ReservedBy != userID {
+				t.Errorf("ticket %s returned to %s but reserved by %s", ticket.Number, userID, ticket.ReservedBy)
+			}
+			// แต่ละชุดของเลขเดียวกันเป็นตั๋วคนละใบ จึงตรวจซ้ำตาม ID
+			if owner, taken := ownerOf[ticket.ID]; taken {
+				t.Errorf("ticket %s set %d given to both %s and %s", ticket.Number, ticket.Set, owner, userID)
+			}
+			ownerOf[ticket.ID] = userID
+			held[userID]++
+		}
+		return len(tickets)
+	}
+
+	// ค้นหาครั้งแรกจาก MongoDB แล้วรอให้ Worker เติม Pool เพื่อให้การค้นหาพร้อมกันผ่าน Pool ด้วย
+	reserve("user-0", 1)
+	f.waitForPool(t, draw.ID, pattern.Key())
+
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				want := perUser - held[userID]
+				mu.Unlock()
+				if want <= 0 || reserve(userID, want) == 0 {
+					return
+				}
+			}
+		}(fmt.Sprintf("user-%d", u))
+	}
+	wg.Wait()
+
+	// ตั๋วที่ผู้ใช้แย่งกันไม่ทันยังต้องจองได้ ไม่ค้างอยู่ใน Pool หรือถูก Claim ทิ้งไว้
+	for reserve("sweeper", total*sets) > 0 {
+	}
+
+	if len(ownerOf) != total*sets {
+		t.Errorf("expected all %d tickets to be reserved exactly once but got %d", total*sets, len(ownerOf))
+	}
+	for userID, n := range held {
+		if userID != "sweeper" && n > perUser {
+			t.Errorf("%s holds %d tickets, more than the %d requested", userID, n, perUser)
+		}
+	}
+}
+
+func TestLotteryRepository_SearchAndReserveReleasesClaimOnError(t *testing.T) {
+	const total = 20
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-release-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   total,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	pattern, _ := domain.ParseLotteryPattern("******")
+
+	assertNothingHeld := func(t *testing.T, f *lotteryFixture, userID string, available int) {
+		held, err := f.repo.FindReservationsByUser(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(held) != 0 {
+			t.Errorf("expected the failed search to hold nothing but %s holds %d tickets", userID, len(held))
+		}
+		tickets, err := f.repo.SearchAndReserve(context.Background(), draw, pattern, "other-user", total, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(tickets) != available {
+			t.Errorf("expected the other %d tickets to be on sale but got %d", available, len(tickets))
+		}
+	}
+
+	t.Run("reading the claimed tickets back fails", func(t *testing.T) {
+		f := setupLotteryFixture(t, draw, total)
+		// Find แรกหาตั๋วที่ว่าง Find ที่สองอ่านตั๋วที่ Claim แล้วกลับมา
+		off := f.failMongoCommand(t, bson.M{"skip": 1}, "find")
+
+		if _, err := f.repo.SearchAndReserve(context.Background(), draw, pattern, "user-1", 5, time.Minute); err == nil {
+			t.Fatal("expected the search to fail")
+		}
+		off()
+
+		assertNothingHeld(t, f, "user-1", total)
+	})
+
+	t.Run("the fallback fails after the pool was claimed", func(t *testing.T) {
+		f := setupLotteryFixture(t, draw, total)
+		f.runPrefillWorkers(t)
+		if _, err := f.repo.SearchAndReserve(context.Background(), draw, pattern, "warm-user", 1, time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pooled := f.waitForPool(t, draw.ID, pattern.Key())
+
+		// ขอมากกว่าที่อยู่ใน Pool เพื่อให้ต้องค้นต่อใน MongoDB หลัง Claim ตั๋วจาก Pool แล้ว
+		off := f.failMongoCommand(t, bson.M{"times": 1}, "find")
+		if _, err := f.repo.SearchAndReserve(context.Background(), draw, pattern, "user-1", int(pooled)+1, time.Minute); err == nil {
+			t.Fatal("expected the search to fail")
+		}
+		off()
+
+		assertNothingHeld(t, f, "user-1", total-1)
+	})
+}
+
+func TestLotteryRepository_Watch(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-watch-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	repo := setupLotteryRepository(t, draw, 10)
+
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	hubDone := make(chan struct{})
+	go func() {
+		defer close(hubDone)
+		repo.RunEventHub(hubCtx)
+	}()
+	// ให้ Hub Subscribe ให้เสร็จก่อนเริ่มเปลี่ยนสถานะตั๋ว
+	time.Sleep(200 * time.Millisecond)
+
+	events, err := repo.Watch(context.Background(), draw.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tickets, err := repo.ReserveNumber(context.Background(), draw.ID, "000003", "watch-user", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if _, err := repo.ReleaseReservation(context.Background(), tickets[0].ID, "watch-user"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	for _, want := range []domain.LotteryEventType{domain.LotteryEventReserved, domain.LotteryEventReleased} {
+		select {
+		case event := <-events:
+			if event.Type != want || event.TicketID != tickets[0].ID || event.Number != "000003" {
+				t.Errorf("expected %s of %s but got %+v", want, tickets[0].ID, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected a %s event", want)
+		}
+	}
+
+	stopHub()
+	<-hubDone
+	if _, ok := <-events; ok {
+		t.Error("expected the stream to close when the hub stops")
+	}
+}
+
+func TestLotteryRepository_Stats(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-stats-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	f := setupLotteryFixture(t, draw, 10)
+	repo := f.repo
+	ctx := context.Background()
+	patternsKey, seenKey, poolKey := "lottery_stats:"+draw.ID+":patterns", "lottery_stats:"+draw.ID+":seen", "lottery_stats:"+draw.ID+":pool"
+	f.rdb.Del(ctx, patternsKey, seenKey, poolKey)
+	t.Cleanup(func() { f.rdb.Del(context.Background(), patternsKey, seenKey, poolKey) })
+
+	// Pattern เดิมที่ค้นหาบ่อยแต่ไม่ได้ค้นหามานานเต็มจำนวนที่เก็บได้แล้ว
+	for i := 0; i < 1000; i++ {
+		member := fmt.Sprintf("stale-%04d", i)
+		f.rdb.ZAdd(ctx, patternsKey, redisClient.Z{Score: 50, Member: member})
+		f.rdb.ZAdd(ctx, seenKey, redisClient.Z{Score: float64(now.Add(-time.Hour).UnixMilli() + int64(i)), Member: member})
+	}
+	pattern, err := domain.ParseLotteryPattern("*****9")
+	if err != nil {
+		t.Fatalf("failed to parse pattern: %v", err)
+	}
+	if _, err := repo.SearchAndReserve(ctx, draw, pattern, "search-user", 1, time.Minute); err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if _, err := f.rdb.ZScore(ctx, patternsKey, pattern.Key()).Result(); err != nil {
+		t.Errorf("expected the new pattern to be tracked over the stale ones but got %v", err)
+	}
+	if _, err := f.rdb.ZScore(ctx, patternsKey, "stale-0000").Result(); err != redisClient.Nil {
+		t.Errorf("expected the least recently searched pattern to be dropped but got %v", err)
+	}
+	if n := f.rdb.ZCard(ctx, patternsKey).Val(); n != 1000 {
+		t.Errorf("expected 1000 tracked patterns but got %d", n)
+	}
+
+	reserved, err := repo.ReserveNumber(ctx, draw.ID, "000001", "stats-user", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if _, err := repo.ReserveNumber(ctx, draw.ID, "000002", "stats-user", 1, time.Minute); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if _, err := repo.MarkAsSold(ctx, reserved[0].ID, "stats-user", draw.ID); err != nil {
+		t.Fatalf("failed to sell: %v", err)
+	}
+
+	stats, err := repo.Stats(ctx, draw.ID, now.Add(-time.Hour), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Total != 10 || stats.ByStatus[domain.LotteryStatusSold] != 1 || stats.ByStatus[domain.LotteryStatusAvailable] != 7 {
+		t.Errorf("unexpected status counts %+v", stats.ByStatus)
+	}
+	if stats.ActiveReservations != 2 || stats.ExpiredReservations != 0 {
+		t.Errorf("expected 2 active reservations but got %d active, %d expired", stats.ActiveReservations, stats.ExpiredReservations)
+	}
+	if len(stats.SalesPerHour) != 1 || stats.SalesPerHour[0].Count != 1 {
+		t.Errorf("expected one sale in the last hour but got %+v", stats.SalesPerHour)
+	}
+	if stats.Pool.Hits+stats.Pool.Misses != 1 {
+		t.Errorf("expected one search of the round but got %+v", stats.Pool)
+	}
+
+	// สถิติการค้นหาแยกตามงวด งวดอื่นและสถิติรวมทุกงวดไม่นับการค้นหานี้
+	for _, other := range []string{"repository-stats-other-draw", ""} {
+		stats, err := repo.Stats(ctx, other, now.Add(-time.Hour), 5)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stats.HotPatterns) != 0 || stats.Pool.Hits+stats.Pool.Misses != 0 {
+			t.Errorf("expected no search statistics for %q but got %+v, %+v", other, stats.HotPatterns, stats.Pool)
+		}
+	}
+}
+
+func TestLotteryRepository_History(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-history-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	repo := setupLotteryRepository(t, draw, 10)
+	ctx := context.Background()
+
+	// A จองแล้วปล่อยให้หมดเวลา จากนั้น B จองและซื้อ
+	held, err := repo.ReserveNumber(ctx, draw.ID, "000004", "user-a", 1, time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := repo.ReleaseExpiredReservations(ctx); err != nil {
+		t.Fatalf("failed to release expired holds: %v", err)
+	}
+	if _, err := repo.ReserveNumber(ctx, draw.ID, "000004", "user-b", 1, time.Minute); err != nil {
+		t.Fatalf("failed to reserve: %v", err)
+	}
+	if _, err := repo.MarkAsSold(ctx, held[0].ID, "user-b", draw.ID); err != nil {
+		t.Fatalf("failed to sell: %v", err)
+	}
+
+	entries, err := repo.FindHistory(ctx, held[0].ID, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		eventType domain.LotteryEventType
+		actor     string
+		to        domain.LotteryStatus
+	}{
+		{domain.LotteryEventReserved, "user-a", domain.LotteryStatusReserved},
+		{domain.LotteryEventExpired, domain.LotteryActorSystem, domain.LotteryStatusAvailable},
+		{domain.LotteryEventReserved, "user-b", domain.LotteryStatusReserved},
+		{domain.LotteryEventSold, "user-b", domain.LotteryStatusSold},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d history entries but got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Type != w.eventType || entries[i].Actor != w.actor || entries[i].ToStatus != w.to {
+			t.Errorf("entry %d: expected %s by %s to %s but got %+v", i, w.eventType, w.actor, w.to, entries[i])
+		}
+	}
+
+	if _, err := repo.FindHistory(ctx, "missing-ticket", 100); !errors.Is(err, domain.ErrTicketNotFound) {
+		t.Errorf("expected ticket not found error but got %v", err)
+	}
+}
+
+func TestLotteryRepository_MigrateLegacyIndexes(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-index-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	f := setupLotteryFixture(t, draw, 10)
+
+	// Index เดิมก่อนมีงวด ทำให้เลขเดียวกันมีได้ใบเดียวทั้งระบบ
+	_, err := f.db.Collection("lotteries").Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("failed to create the legacy index: %v", err)
+	}
+
+	dropped, err := f.repo.MigrateLegacyIndexes(context.Background())
+	if err != nil || !dropped {
+		t.Fatalf("expected the legacy index to be dropped but got %v, %v", dropped, err)
+	}
+	dropped, err = f.repo.MigrateLegacyIndexes(context.Background())
+	if err != nil || dropped {
+		t.Errorf("expected nothing left to drop but got %v, %v", dropped, err)
+	}
+
+	// เลขเดียวกันของงวดอื่นเติมได้หลังลบ Index เดิม
+	if err := f.repo.SeedTickets(context.Background(), domain.TicketSeedPlan{
+		DrawID:    "repository-index-next-draw",
+		To:        10,
+		Sets:      1,
+		BatchSize: domain.DefaultSeedBatchSize,
+	}); err != nil {
+		t.Errorf("failed to stock the next round: %v", err)
+	}
+}
+
+func TestLotteryRepository_HoldUntil(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-hold-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	f := setupLotteryFixture(t, draw, 10)
+	ctx := context.Background()
+
+	reserve := func(number string) domain.LotteryTicket {
+		tickets, err := f.repo.ReserveNumber(ctx, draw.ID, number, "hold-user", 1, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to reserve %s: %v", number, err)
+		}
+		return tickets[0]
+	}
+	reservedUntil := func(id string) time.Time {
+		held, err := f.repo.FindReservationsByUser(ctx, "hold-user")
+		if err != nil {
+			t.Fatalf("failed to list holds: %v", err)
+		}
+		for _, ticket := range held {
+			if ticket.ID == id {
+				return *ticket.ReservedUntil
+			}
+		}
+		t.Fatalf("expected %s to still be held", id)
+		return time.Time{}
+	}
+
+	t.Run("holds every ticket", func(t *testing.T) {
+		a, b := reserve("000001"), reserve("000002")
+		until := time.Now().Add(time.Hour)
+
+		tickets, err := f.repo.HoldUntil(ctx, draw.ID, []string{a.ID, b.ID}, "hold-user", until)
+		if err != nil || len(tickets) != 2 {
+			t.Fatalf("expected both tickets to be held but got %d, %v", len(tickets), err)
+		}
+		if got := reservedUntil(a.ID); got.Before(until.Add(-time.Second)) {
+			t.Errorf("expected the hold to last until %v but got %v", until, got)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		number string
+		lose   func(ticket domain.LotteryTicket)
+		want   error
+	}{
+		{
+			name:   "a sold ticket",
+			number: "000003",
+			lose: func(ticket domain.LotteryTicket) {
+				if _, err := f.repo.MarkAsSold(ctx, ticket.ID, "hold-user", draw.ID); err != nil {
+					t.Fatalf("failed to sell: %v", err)
+				}
+			},
+			want: domain.ErrTicketAlreadySold,
+		},
+		{
+			name:   "an expired hold",
+			number: "000004",
+			lose: func(ticket domain.LotteryTicket) {
+				_, err := f.db.Collection("lotteries").UpdateOne(ctx,
+					bson.M{"number": ticket.Number, "draw_id": draw.ID},
+					bson.M{"$set": bson.M{"reserved_until": time.Now().Add(-time.Second)}})
+				if err != nil {
+					t.Fatalf("failed to expire the hold: %v", err)
+				}
+			},
+			want: domain.ErrReservationExpired,
+		},
+		{
+			name:   "a released ticket",
+			number: "000005",
+			lose: func(ticket domain.LotteryTicket) {
+				if _, err := f.repo.ReleaseReservation(ctx, ticket.ID, "hold-user"); err != nil {
+					t.Fatalf("failed to release: %v", err)
+				}
+			},
+			want: domain.ErrTicketNotReserved,
+		},
+		{
+			name:   "a ticket of another round",
+			number: "000000",
+			lose: func(ticket domain.LotteryTicket) {
+				_, err := f.db.Collection("lotteries").UpdateOne(ctx,
+					bson.M{"number": ticket.Number, "draw_id": draw.ID},
+					bson.M{"$set": bson.M{"draw_id": "repository-hold-other-draw"}})
+				if err != nil {
+					t.Fatalf("failed to move the ticket: %v", err)
+				}
+			},
+			want: domain.ErrTicketNotFound,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := reserve(fmt.Sprintf("00000%d", 6+i))
+			lost := reserve(tt.number)
+			before := reservedUntil(kept.ID)
+			tt.lose(lost)
+
+			_, err := f.repo.HoldUntil(ctx, draw.ID, []string{kept.ID, lost.ID}, "hold-user", time.Now().Add(time.Hour))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, err)
+			}
+			// ใบที่ยังถืออยู่ต้องไม่ถูกยืดเวลา เมื่อสั่งซื้อทั้งชุดไม่สำเร็จ
+			if got := reservedUntil(kept.ID); !got.Equal(before) {
+				t.Errorf("expected the kept hold to stay at %v but got %v", before, got)
+			}
+		})
+	}
+}
+
+func TestLotteryRepository_ReleaseAllReservations(t *testing.T) {
+	now := time.Now()
+	draw := &domain.LotteryDraw{
+		ID:            "repository-release-all-draw",
+		DrawDate:      now.Add(24 * time.Hour),
+		Status:        domain.DrawStatusScheduled,
+		SalesOpenAt:   now.Add(-time.Hour),
+		SalesCloseAt:  now.Add(time.Hour),
+		TicketCount:   10,
+		SetsPerNumber: 1,
+		StockedAt:     &now,
+	}
+	repo := setupLotteryRepository(t, draw, 10)
+	ctx := context.Background()
+
+	var held []domain.LotteryTicket
+	for _, number := range []string{"000001", "000002", "000003"} {
+		tickets, err := repo.ReserveNumber(ctx, draw.ID, number, "release-user", 1, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to reserve %s: %v", number, err)
+		}
+		held = append(held, tickets...)
+	}
+	// ตั๋วที่ขายไปแล้วต้องไม่ถูกปล่อย
+	if _, err := repo.MarkAsSold(ctx, held[2].ID, "release-user", draw.ID); err != nil {
+		t.Fatalf("failed to sell: %v", err)
+	}
+
+	released, err := repo.ReleaseAllReservations(ctx, "release-user")
+	if err != nil || released != 2 {
+		t.Fatalf("expected 2 holds to be released but got %d, %v", released, err)
+	}
+	if left, _ := repo.FindReservationsByUser(ctx, "release-user"); len(left) != 0 {
+		t.Errorf("expected no holds left but got %d", len(left))
+	}
+
+	history, err := repo.FindHistory(ctx, held[0].ID, 10)
+	if err != nil || len(history) == 0 {
+		t.Fatalf("failed to read history: %d entries, %v", len(history), err)
+	}
+	last := history[len(history)-1]
+	if last.Type != domain.LotteryEventReleased || last.Actor != "release-user" || last.Number != "000001" {
+		t.Errorf("expected the release to be recorded but got %+v", last)
+	}
+	if released, err := repo.ReleaseAllReservations(ctx, "release-user"); err != nil || released != 0 {
+		t.Errorf("expected nothing left to release but got %d, %v", released, err)
+	}
+}
@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/domain"
+	redisClient "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	benchTicketCount = 100000
+	benchDrawID      = "bench-draw"
+)
+
+var benchPatterns = []string{"****23", "12****", "**55**", "1****9"}
+
+// Note: These benchmarks require a MongoDB server to be running on localhost:27017.
+// They compare the previous $regex lookup on number with the digit position lookup.
+//
+//	go test ./tests/unit -run ^$ -bench LotterySearch
+func setupLotteryBench(b *testing.B) *mongo.Collection {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil || client.Ping(ctx, nil) != nil {
+		b.Skip("MongoDB is not running on localhost:27017, skipping lottery search benchmarks")
+	}
+
+	db := client.Database("lottery_bench")
+	rdb := redisClient.NewClient(&redisClient.Options{Addr: "localhost:6379"})
+	db.Drop(context.Background())
+	b.Cleanup(func() {
+		db.Drop(context.Background())
+		rdb.Close()
+		client.Disconnect(context.Background())
+	})
+
+	repo := mongodb.NewLotteryRepository(db, rdb)
+	if err := repo.SeedTickets(context.Background(), domain.TicketSeedPlan{
+		DrawID:    benchDrawID,
+		To:        benchTicketCount,
+		Sets:      1,
+		BatchSize: domain.DefaultSeedBatchSize,
+	}); err != nil {
+		b.Fatalf("failed to seed tickets: %v", err)
+	}
+
+	collection := db.Collection("lotteries")
+	// Index used by the previous regex path, scoped to a round like the repository's queries
+	collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "draw_id", Value: 1}, {Key: "status", Value: 1}, {Key: "number", Value: 1}},
+	})
+
+	return collection
+}
+
+func benchmarkLotteryLookup(b *testing.B, collection *mongo.Collection, filter func(*domain.LotteryPattern) bson.M) {
+	ctx := context.Background()
+	for _, raw := range benchPatterns {
+		pattern, err := domain.ParseLotteryPattern(raw)
+		if err != nil {
+			b.Fatalf("invalid pattern %s: %v", raw, err)
+		}
+		f := filter(pattern)
+		f["draw_id"] = benchDrawID
+		f["status"] = domain.LotteryStatusAvailable
+
+		b.Run(raw, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cursor, err := collection.Find(ctx, f, options.Find().SetLimit(100).SetProjection(bson.M{"number": 1}))
+				if err != nil {
+					b.Fatal(err)
+				}
+				var docs []bson.M
+				if err := cursor.All(ctx, &docs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLotterySearch_Regex(b *testing.B) {
+	collection := setupLotteryBench(b)
+	benchmarkLotteryLookup(b, collection, func(p *domain.LotteryPattern) bson.M {
+		return bson.M{"number": bson.M{"$regex": p.Regex()}}
+	})
+}
+
+func BenchmarkLotterySearch_DigitPositions(b *testing.B) {
+	collection := setupLotteryBench(b)
+	benchmarkLotteryLookup(b, collection, mongodb.LotteryPatternFilter)
+}
@@ -2,8 +2,11 @@ package unit
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/backend-challenge/user-api/internal/application"
 	"github.com/backend-challenge/user-api/internal/domain"
@@ -24,7 +27,7 @@ func TestLotteryService_SearchLottery(t *testing.T) {
 			pattern: "123***",
 			userID:  "user-123",
 			mockSetup: func(repo *mocks.MockLotteryRepository) {
-				repo.SearchAndReserveFunc = func(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error) {
+				repo.SearchAndReserveFunc = func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
 					return []domain.LotteryTicket{
 						{Number: "123456"},
 						{Number: "123000"},
@@ -52,7 +55,7 @@ func TestLotteryService_SearchLottery(t *testing.T) {
 			pattern: "******",
 			userID:  "user-123",
 			mockSetup: func(repo *mocks.MockLotteryRepository) {
-				repo.SearchAndReserveFunc = func(ctx context.Context, pattern string, userID string, limit int) ([]domain.LotteryTicket, error) {
+				repo.SearchAndReserveFunc = func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
 					return nil, errors.New("db error")
 				}
 			},
@@ -65,7 +68,7 @@ func TestLotteryService_SearchLottery(t *testing.T) {
 			mockRepo := &mocks.MockLotteryRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := application.NewLotteryService(mockRepo)
+			service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
 			results, err := service.SearchLottery(context.Background(), tt.pattern, tt.userID)
 
 			if tt.expectError {
@@ -86,7 +89,7 @@ func TestLotteryService_SearchLottery(t *testing.T) {
 
 func TestLotteryService_GetLotteryCount(t *testing.T) {
 	mockRepo := &mocks.MockLotteryRepository{}
-	service := application.NewLotteryService(mockRepo)
+	service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
 
 	t.Run("successful count", func(t *testing.T) {
 		mockRepo.CountFunc = func(ctx context.Context) (int64, error) {
@@ -113,3 +116,672 @@ func TestLotteryService_GetLotteryCount(t *testing.T) {
 		}
 	})
 }
+
+func TestLotteryService_ReleaseReservation(t *testing.T) {
+	t.Run("successful release", func(t *testing.T) {
+		var releasedID string
+		mockRepo := &mocks.MockLotteryRepository{
+			ReleaseReservationFunc: func(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+				releasedID = ticketID
+				return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusAvailable}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		if err := service.ReleaseReservation(context.Background(), "ticket-1", "user-123"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if releasedID != "ticket-1" {
+			t.Errorf("expected ticket-1 to be released but got %q", releasedID)
+		}
+	})
+
+	t.Run("not reserved by user", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			ReleaseReservationFunc: func(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+				return nil, domain.ErrTicketNotReserved
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		err := service.ReleaseReservation(context.Background(), "ticket-1", "user-123")
+		if !errors.Is(err, domain.ErrTicketNotReserved) {
+			t.Errorf("expected not reserved error but got %v", err)
+		}
+	})
+
+	t.Run("release all", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			ReleaseAllReservationsFunc: func(ctx context.Context, userID string) (int64, error) {
+				return 3, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		released, err := service.ReleaseAllReservations(context.Background(), "user-123")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if released != 3 {
+			t.Errorf("expected 3 released but got %d", released)
+		}
+	})
+}
+
+func TestLotteryService_ReleaseExpiredReservations(t *testing.T) {
+	mockRepo := &mocks.MockLotteryRepository{
+		ReleaseExpiredReservationsFunc: func(ctx context.Context) (int64, error) {
+			return 42, nil
+		},
+	}
+	service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+	reclaimed, err := service.ReleaseExpiredReservations(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if reclaimed != 42 {
+		t.Errorf("expected 42 reclaimed but got %d", reclaimed)
+	}
+}
+
+func TestLotteryService_SearchLotteryUsesPolicy(t *testing.T) {
+	policy := domain.ReservationPolicy{TTL: 2 * time.Minute, MaxTicketsPerUser: 3}
+	mockRepo := &mocks.MockLotteryRepository{
+		SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+			if limit != 3 || ttl != 2*time.Minute {
+				t.Errorf("expected limit 3 and ttl 2m but got %d and %v", limit, ttl)
+			}
+			return []domain.LotteryTicket{{Number: "123456"}}, nil
+		},
+	}
+	service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, policy)
+
+	if _, err := service.SearchLottery(context.Background(), "123***", "user-123"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLotteryService_AddToCart(t *testing.T) {
+	policy := domain.DefaultReservationPolicy()
+	policy.MaxTicketsPerUser = 2
+
+	tests := []struct {
+		name        string
+		number      string
+		held        []domain.LotteryTicket
+		quotaFull   bool
+		reserveErr  error
+		expectError error
+	}{
+		{
+			name:   "successful add",
+			number: "123456",
+		},
+		{
+			name:        "invalid number",
+			number:      "12a456",
+			expectError: domain.ErrRequestInvalid,
+		},
+		{
+			name:        "quota exceeded",
+			number:      "123456",
+			held:        []domain.LotteryTicket{{Number: "000001"}, {Number: "000002"}},
+			quotaFull:   true,
+			expectError: domain.ErrQuotaExceeded,
+		},
+		{
+			name:   "already in cart",
+			number: "000001",
+			held:   []domain.LotteryTicket{{Number: "000001", DrawID: "open-draw"}, {Number: "000002", DrawID: "open-draw"}},
+		},
+		{
+			name:        "reserved by another user",
+			number:      "123456",
+			reserveErr:  domain.ErrTicketReserved,
+			expectError: domain.ErrTicketReserved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mocks.MockLotteryRepository{
+				FindReservationsByUserFunc: func(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+					return tt.held, nil
+				},
+			}
+			if tt.reserveErr != nil {
+				mockRepo.ReserveNumberFunc = func(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+					return nil, tt.reserveErr
+				}
+			}
+			mockQuota := &mocks.MockReservationQuota{}
+			if tt.quotaFull {
+				mockQuota.AcquireFunc = func(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+					return "", 0, nil
+				}
+			}
+			service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, mockQuota, policy)
+
+			tickets, err := service.AddToCart(context.Background(), tt.number, 0, "user-123")
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Errorf("expected error %v but got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tickets) != 1 || tickets[0].Number != tt.number {
+				t.Errorf("expected one ticket of %s but got %+v", tt.number, tickets)
+			}
+		})
+	}
+}
+
+func TestLotteryService_ExtendHold(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute)
+
+	t.Run("successful extend", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			ExtendReservationsFunc: func(ctx context.Context, userID string, extension time.Duration, maxExtensions int) (int64, error) {
+				return 1, nil
+			},
+			FindReservationsByUserFunc: func(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+				return []domain.LotteryTicket{{Number: "123456", ReservedUntil: &expiresAt, HoldExtensions: 1}}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		cart, err := service.ExtendHold(context.Background(), "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cart.CanExtend {
+			t.Error("expected cart to be no longer extendable")
+		}
+		if cart.ExpiresAt == nil || !cart.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("expected expiry %v but got %v", expiresAt, cart.ExpiresAt)
+		}
+	})
+
+	t.Run("extension limit reached", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			FindReservationsByUserFunc: func(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+				return []domain.LotteryTicket{{Number: "123456", ReservedUntil: &expiresAt, HoldExtensions: 1}}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		_, err := service.ExtendHold(context.Background(), "user-123")
+		if !errors.Is(err, domain.ErrHoldNotExtendable) {
+			t.Errorf("expected hold not extendable error but got %v", err)
+		}
+	})
+
+	t.Run("empty cart", func(t *testing.T) {
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		_, err := service.ExtendHold(context.Background(), "user-123")
+		if !errors.Is(err, domain.ErrTicketNotReserved) {
+			t.Errorf("expected not reserved error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_SearchLotteryQuota(t *testing.T) {
+	t.Run("reserves only the granted amount and commits the result", func(t *testing.T) {
+		var committed []domain.LotteryTicket
+		mockQuota := &mocks.MockReservationQuota{
+			AcquireFunc: func(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+				return "grant-1", 2, nil
+			},
+			CommitFunc: func(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error {
+				if grantID != "grant-1" || granted != 2 {
+					t.Errorf("unexpected commit of grant %s (%d)", grantID, granted)
+				}
+				committed = tickets
+				return nil
+			},
+		}
+		mockRepo := &mocks.MockLotteryRepository{
+			SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				if limit != 2 {
+					t.Errorf("expected limit 2 but got %d", limit)
+				}
+				return []domain.LotteryTicket{{ID: "a", Number: "123456"}}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, mockQuota, domain.DefaultReservationPolicy())
+
+		if _, err := service.SearchLottery(context.Background(), "123***", "user-123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(committed) != 1 {
+			t.Errorf("expected 1 committed ticket but got %d", len(committed))
+		}
+	})
+
+	t.Run("asks for one search worth of tickets under a higher cap", func(t *testing.T) {
+		mockQuota := &mocks.MockReservationQuota{
+			AcquireFunc: func(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+				if requested != 10 || max != 50 {
+					t.Errorf("expected to request 10 of a cap of 50 but got %d of %d", requested, max)
+				}
+				return "grant-1", requested, nil
+			},
+		}
+		mockRepo := &mocks.MockLotteryRepository{
+			SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				if limit != 10 {
+					t.Errorf("expected limit 10 but got %d", limit)
+				}
+				return nil, nil
+			},
+		}
+		policy := domain.DefaultReservationPolicy()
+		policy.MaxTicketsPerUser = 50
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, mockQuota, policy)
+
+		if _, err := service.SearchLottery(context.Background(), "123***", "user-123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		mockQuota := &mocks.MockReservationQuota{
+			AcquireFunc: func(ctx context.Context, userID string, requested, max int, hold time.Duration) (string, int, error) {
+				return "", 0, nil
+			},
+		}
+		mockRepo := &mocks.MockLotteryRepository{
+			SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				t.Error("repository should not be called when quota is exceeded")
+				return nil, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, mockQuota, domain.DefaultReservationPolicy())
+
+		_, err := service.SearchLottery(context.Background(), "123***", "user-123")
+		if !errors.Is(err, domain.ErrQuotaExceeded) {
+			t.Errorf("expected quota exceeded error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_BrowseLottery(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	active := time.Now().Add(time.Minute)
+
+	t.Run("pages without reserving", func(t *testing.T) {
+		var gotAfter domain.LotteryCursor
+		var gotLimit int
+		mockRepo := &mocks.MockLotteryRepository{
+			BrowseTicketsFunc: func(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error) {
+				gotAfter, gotLimit = after, limit
+				return []domain.LotteryTicket{
+					{Number: "000123", Status: domain.LotteryStatusReserved, ReservedUntil: &expired, ReservedBy: "other"},
+					{Number: "001123", Set: 2, Status: domain.LotteryStatusReserved, ReservedUntil: &active, ReservedBy: "other"},
+				}, 1000, nil
+			},
+			SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				t.Error("browse must not reserve tickets")
+				return nil, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		page, err := service.BrowseLottery(context.Background(), "***123", "", 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotAfter != (domain.LotteryCursor{}) || gotLimit != 2 {
+			t.Errorf("unexpected repository arguments %+v, %d", gotAfter, gotLimit)
+		}
+		if page.Total != 1000 || page.NextCursor == "" {
+			t.Errorf("expected total 1000 with next cursor but got %+v", page)
+		}
+		if page.Tickets[0].Status != domain.LotteryStatusAvailable {
+			t.Errorf("expected expired reservation to be shown as available but got %s", page.Tickets[0].Status)
+		}
+		if page.Tickets[1].Status != domain.LotteryStatusReserved || page.Tickets[1].ReservedBy != "" {
+			t.Errorf("expected reserved ticket without owner but got %+v", page.Tickets[1])
+		}
+
+		_, err = service.BrowseLottery(context.Background(), "***123", page.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotAfter != (domain.LotteryCursor{Number: "001123", Set: 2}) {
+			t.Errorf("expected cursor to resume after 001123 set 2 but got %+v", gotAfter)
+		}
+	})
+
+	t.Run("accepts cursors without a set", func(t *testing.T) {
+		var gotAfter domain.LotteryCursor
+		mockRepo := &mocks.MockLotteryRepository{
+			BrowseTicketsFunc: func(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error) {
+				gotAfter = after
+				return nil, 0, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		cursor := base64.RawURLEncoding.EncodeToString([]byte("001123"))
+		if _, err := service.BrowseLottery(context.Background(), "***123", cursor, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotAfter != (domain.LotteryCursor{Number: "001123"}) {
+			t.Errorf("expected cursor after every copy of 001123 but got %+v", gotAfter)
+		}
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			BrowseTicketsFunc: func(ctx context.Context, drawID string, pattern *domain.LotteryPattern, after domain.LotteryCursor, limit int) ([]domain.LotteryTicket, int64, error) {
+				return []domain.LotteryTicket{{Number: "123456", Status: domain.LotteryStatusSold}}, 1, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		page, err := service.BrowseLottery(context.Background(), "123456", "", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if page.NextCursor != "" {
+			t.Errorf("expected no next cursor but got %q", page.NextCursor)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		_, err := service.BrowseLottery(context.Background(), "123456", "!!!", 10)
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_SearchLotterySalesClosed(t *testing.T) {
+	draws := &mocks.MockLotteryDrawRepository{
+		FindOnSaleFunc: func(ctx context.Context, now time.Time) (*domain.LotteryDraw, error) {
+			return nil, domain.ErrDrawNotFound
+		},
+	}
+	repo := &mocks.MockLotteryRepository{
+		SearchAndReserveFunc: func(ctx context.Context, draw *domain.LotteryDraw, pattern *domain.LotteryPattern, userID string, limit int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+			t.Error("expected no reservation while sales are closed")
+			return nil, nil
+		},
+	}
+	service := application.NewLotteryService(repo, draws, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+	if _, err := service.SearchLottery(context.Background(), "******", "user-123"); !errors.Is(err, domain.ErrSalesClosed) {
+		t.Errorf("expected sales closed error but got %v", err)
+	}
+}
+
+func TestLotteryService_AddToCartCopies(t *testing.T) {
+	setsDraw := func(sets int) *mocks.MockLotteryDrawRepository {
+		return &mocks.MockLotteryDrawRepository{
+			FindOnSaleFunc: func(ctx context.Context, now time.Time) (*domain.LotteryDraw, error) {
+				draw := mocks.OnSaleDraw("open-draw")
+				draw.SetsPerNumber = sets
+				return draw, nil
+			},
+		}
+	}
+
+	t.Run("reserves only the missing copies", func(t *testing.T) {
+		var requested, reserved int
+		mockRepo := &mocks.MockLotteryRepository{
+			FindReservationsByUserFunc: func(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+				return []domain.LotteryTicket{{ID: "held", Number: "123456", Set: 1, DrawID: "open-draw"}}, nil
+			},
+			ReserveNumberFunc: func(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				reserved = copies
+				return []domain.LotteryTicket{{ID: "a", Number: number, Set: 2}, {ID: "b", Number: number, Set: 3}}, nil
+			},
+		}
+		mockQuota := &mocks.MockReservationQuota{
+			AcquireFunc: func(ctx context.Context, userID string, n, max int, hold time.Duration) (string, int, error) {
+				requested = n
+				return "grant", n, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, setsDraw(5), mockQuota, domain.DefaultReservationPolicy())
+
+		tickets, err := service.AddToCart(context.Background(), "123456", 3, "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if requested != 2 || reserved != 2 {
+			t.Errorf("expected 2 more copies to be requested but got quota=%d reserve=%d", requested, reserved)
+		}
+		if len(tickets) != 3 {
+			t.Errorf("expected 3 copies in cart but got %d", len(tickets))
+		}
+	})
+
+	t.Run("more copies than the round stocks", func(t *testing.T) {
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, setsDraw(2), &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		if _, err := service.AddToCart(context.Background(), "123456", 3, "user-123"); !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+
+	t.Run("partial quota reserves nothing", func(t *testing.T) {
+		mockRepo := &mocks.MockLotteryRepository{
+			ReserveNumberFunc: func(ctx context.Context, drawID string, number string, userID string, copies int, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				t.Error("expected no reservation without enough quota")
+				return nil, nil
+			},
+		}
+		mockQuota := &mocks.MockReservationQuota{
+			AcquireFunc: func(ctx context.Context, userID string, n, max int, hold time.Duration) (string, int, error) {
+				return "grant", 1, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, setsDraw(5), mockQuota, domain.DefaultReservationPolicy())
+
+		if _, err := service.AddToCart(context.Background(), "123456", 3, "user-123"); !errors.Is(err, domain.ErrQuotaExceeded) {
+			t.Errorf("expected quota exceeded error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_WatchLottery(t *testing.T) {
+	t.Run("streams events of matching tickets only", func(t *testing.T) {
+		source := make(chan domain.LotteryEvent, 3)
+		source <- domain.LotteryEvent{Type: domain.LotteryEventReserved, Number: "000999"}
+		source <- domain.LotteryEvent{Type: domain.LotteryEventReleased, Number: "000123"}
+		source <- domain.LotteryEvent{Type: domain.LotteryEventSold, Number: "555123"}
+		close(source)
+
+		var watchedDraw string
+		mockRepo := &mocks.MockLotteryRepository{
+			WatchFunc: func(ctx context.Context, drawID string) (<-chan domain.LotteryEvent, error) {
+				watchedDraw = drawID
+				return source, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		events, err := service.WatchLottery(context.Background(), "***123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []domain.LotteryEventType
+		for event := range events {
+			got = append(got, event.Type)
+		}
+		if len(got) != 2 || got[0] != domain.LotteryEventReleased || got[1] != domain.LotteryEventSold {
+			t.Errorf("expected released and sold events but got %v", got)
+		}
+		if watchedDraw != "open-draw" {
+			t.Errorf("expected the round on sale to be watched but got %q", watchedDraw)
+		}
+	})
+
+	t.Run("stream ends when the watcher leaves", func(t *testing.T) {
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := service.WatchLottery(ctx, "******")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cancel()
+
+		select {
+		case _, ok := <-events:
+			if ok {
+				t.Error("expected no events")
+			}
+		case <-time.After(time.Second):
+			t.Error("expected the stream to close")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		if _, err := service.WatchLottery(context.Background(), "12"); !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_GetLotteryStats(t *testing.T) {
+	t.Run("caches the aggregation briefly", func(t *testing.T) {
+		calls := 0
+		var gotSince time.Time
+		mockRepo := &mocks.MockLotteryRepository{
+			StatsFunc: func(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error) {
+				calls++
+				gotSince = salesSince
+				return &domain.LotteryStats{
+					Total:       3,
+					ByStatus:    map[domain.LotteryStatus]int64{domain.LotteryStatusSold: 3},
+					Pool:        domain.PoolStats{Hits: 3, Misses: 1},
+					GeneratedAt: time.Now(),
+				}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		first, err := service.GetLotteryStats(context.Background(), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := service.GetLotteryStats(context.Background(), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected one aggregation but got %d", calls)
+		}
+		if since := time.Since(gotSince); since < 24*time.Hour || since > 25*time.Hour {
+			t.Errorf("expected sales over the last day but got since %v", gotSince)
+		}
+		if first.Pool.HitRate() != 0.75 {
+			t.Errorf("expected hit rate 0.75 but got %v", first.Pool.HitRate())
+		}
+	})
+
+	t.Run("a slow round does not hold up another round", func(t *testing.T) {
+		slow := make(chan struct{})
+		var mu sync.Mutex
+		calls := map[string]int{}
+		mockRepo := &mocks.MockLotteryRepository{
+			StatsFunc: func(ctx context.Context, drawID string, salesSince time.Time, hotPatterns int) (*domain.LotteryStats, error) {
+				mu.Lock()
+				calls[drawID]++
+				mu.Unlock()
+				if drawID == "draw-slow" {
+					<-slow
+				}
+				return &domain.LotteryStats{GeneratedAt: time.Now()}, nil
+			},
+		}
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return &domain.LotteryDraw{ID: id}, nil
+			},
+		}
+		service := application.NewLotteryService(mockRepo, draws, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := service.GetLotteryStats(context.Background(), "draw-slow"); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := service.GetLotteryStats(context.Background(), "draw-fast")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("expected the stats of another round not to wait for the slow one")
+		}
+
+		close(slow)
+		wg.Wait()
+		if calls["draw-slow"] != 1 {
+			t.Errorf("expected concurrent requests of one round to share one aggregation but got %d", calls["draw-slow"])
+		}
+	})
+
+	t.Run("unknown draw", func(t *testing.T) {
+		draws := &mocks.MockLotteryDrawRepository{
+			FindByIDFunc: func(ctx context.Context, id string) (*domain.LotteryDraw, error) {
+				return nil, domain.ErrDrawNotFound
+			},
+		}
+		service := application.NewLotteryService(&mocks.MockLotteryRepository{}, draws, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+		if _, err := service.GetLotteryStats(context.Background(), "missing"); !errors.Is(err, domain.ErrDrawNotFound) {
+			t.Errorf("expected draw not found error but got %v", err)
+		}
+	})
+}
+
+func TestLotteryService_GetTicketHistory(t *testing.T) {
+	mockRepo := &mocks.MockLotteryRepository{
+		FindHistoryFunc: func(ctx context.Context, ticketID string, limit int) ([]domain.LotteryHistoryEntry, error) {
+			return []domain.LotteryHistoryEntry{
+				{Type: domain.LotteryEventReserved, Actor: "someone-else"},
+				{Type: domain.LotteryEventExpired, Actor: domain.LotteryActorSystem},
+				{Type: domain.LotteryEventReserved, Actor: "user-123"},
+				{Type: domain.LotteryEventSold, Actor: "user-123"},
+			}, nil
+		},
+	}
+	service := application.NewLotteryService(mockRepo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, domain.DefaultReservationPolicy())
+
+	entries, err := service.GetTicketHistory(context.Background(), "ticket-1", "user-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actors := []string{entries[0].Actor, entries[1].Actor, entries[2].Actor, entries[3].Actor}
+	if actors[0] != "" || actors[1] != domain.LotteryActorSystem || actors[2] != "user-123" || actors[3] != "user-123" {
+		t.Errorf("expected only other users to be hidden but got %v", actors)
+	}
+
+	if _, err := service.GetTicketHistory(context.Background(), "", "user-123"); !errors.Is(err, domain.ErrRequestInvalid) {
+		t.Errorf("expected invalid request error but got %v", err)
+	}
+}
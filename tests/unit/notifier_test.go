@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/backend-challenge/user-api/internal/adapters/notifier"
+	"github.com/backend-challenge/user-api/internal/domain"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	n := notifier.NewFileNotifier(path)
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		err := n.Send(context.Background(), domain.Notification{
+			Kind:    domain.NotificationPasswordReset,
+			To:      to,
+			Subject: "Reset your password",
+			Body:    "link",
+		})
+		if err != nil {
+			t.Fatalf("failed to send notification: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read notification file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 notifications but got %d", len(lines))
+	}
+
+	var first map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("expected a JSON line but got %q: %v", lines[0], err)
+	}
+	if first["to"] != "a@example.com" || first["kind"] != string(domain.NotificationPasswordReset) {
+		t.Errorf("unexpected notification %v", first)
+	}
+}
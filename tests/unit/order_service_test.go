@@ -0,0 +1,504 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/payment"
+	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/tests/mocks"
+)
+
+func newTestOrderService(orders *mocks.MockOrderRepository, repo *mocks.MockLotteryRepository, gateway *mocks.MockPaymentGateway) *application.OrderService {
+	return application.NewOrderService(orders, repo, &mocks.MockLotteryDrawRepository{}, &mocks.MockReservationQuota{}, gateway,
+		domain.DefaultReservationPolicy(), 15*time.Minute)
+}
+
+// pendingOrder returns a pending order for user-123 paid through "pay-1"
+func pendingOrder(ticketIDs ...string) *domain.Order {
+	draw := mocks.OnSaleDraw("open-draw")
+	tickets := make([]domain.LotteryTicket, len(ticketIDs))
+	for i, id := range ticketIDs {
+		tickets[i] = domain.LotteryTicket{ID: id, Number: "123456", Status: domain.LotteryStatusReserved, ReservedBy: "user-123", DrawID: draw.ID}
+	}
+	order, err := domain.NewOrder("user-123", draw, tickets, time.Now(), 15*time.Minute)
+	if err != nil {
+		panic(err)
+	}
+	order.ID = "order-1"
+	order.PaymentID = "pay-1"
+	return order
+}
+
+func paymentEvent(status domain.PaymentEventStatus) func(payload []byte, signature string) (*domain.PaymentEvent, error) {
+	return func(payload []byte, signature string) (*domain.PaymentEvent, error) {
+		return &domain.PaymentEvent{PaymentID: "pay-1", Status: status}, nil
+	}
+}
+
+func TestOrder_Lifecycle(t *testing.T) {
+	t.Run("prices tickets at the round price", func(t *testing.T) {
+		order := pendingOrder("a", "b", "a")
+		if len(order.Items) != 2 || order.Total != 2*domain.DefaultTicketPrice || order.Currency != domain.OrderCurrency {
+			t.Errorf("expected 2 items totalling %d THB but got %+v", 2*domain.DefaultTicketPrice, order)
+		}
+	})
+
+	t.Run("rejects tickets the user does not hold", func(t *testing.T) {
+		draw := mocks.OnSaleDraw("open-draw")
+		tickets := []domain.LotteryTicket{{ID: "a", Status: domain.LotteryStatusReserved, ReservedBy: "someone-else", DrawID: draw.ID}}
+		if _, err := domain.NewOrder("user-123", draw, tickets, time.Now(), time.Minute); !errors.Is(err, domain.ErrTicketNotReserved) {
+			t.Errorf("expected not reserved error but got %v", err)
+		}
+	})
+
+	t.Run("settled orders cannot be paid or failed", func(t *testing.T) {
+		order := pendingOrder("a")
+		if err := order.MarkPaid(time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := order.MarkPaid(time.Now()); !errors.Is(err, domain.ErrOrderStatusConflict) {
+			t.Errorf("expected status conflict but got %v", err)
+		}
+		if err := order.MarkFailed("late", time.Now()); !errors.Is(err, domain.ErrOrderStatusConflict) {
+			t.Errorf("expected status conflict but got %v", err)
+		}
+	})
+
+	t.Run("partial refund keeps the order paid", func(t *testing.T) {
+		order := pendingOrder("a", "b")
+		order.MarkPaid(time.Now())
+
+		amount, err := order.Refund([]string{"b", "b"}, time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if amount != domain.DefaultTicketPrice || order.Status != domain.OrderStatusPaid {
+			t.Errorf("expected one ticket refunded on a paid order but got %d (%s)", amount, order.Status)
+		}
+
+		amount, _ = order.Refund([]string{"a", "b"}, time.Now())
+		if amount != domain.DefaultTicketPrice || order.Status != domain.OrderStatusRefunded || order.RefundedAmount != order.Total {
+			t.Errorf("expected the rest refunded but got %d (%s)", amount, order.Status)
+		}
+	})
+
+	t.Run("pending orders cannot be refunded", func(t *testing.T) {
+		if _, err := pendingOrder("a").Refund([]string{"a"}, time.Now()); !errors.Is(err, domain.ErrOrderStatusConflict) {
+			t.Errorf("expected status conflict but got %v", err)
+		}
+	})
+}
+
+func TestOrderService_Checkout(t *testing.T) {
+	t.Run("holds tickets for the payment window and starts a payment", func(t *testing.T) {
+		var holdUntil time.Time
+		repo := &mocks.MockLotteryRepository{
+			HoldUntilFunc: func(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error) {
+				holdUntil = until
+				return (&mocks.MockLotteryRepository{}).HoldUntil(ctx, drawID, ticketIDs, userID, until)
+			},
+			MarkAsSoldFunc: func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+				t.Error("tickets must not be sold before the payment is confirmed")
+				return nil, nil
+			},
+		}
+		var saved *domain.Order
+		orders := &mocks.MockOrderRepository{
+			UpdateFunc: func(ctx context.Context, order *domain.Order, from domain.OrderStatus) error {
+				saved = order
+				return nil
+			},
+		}
+		service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{})
+
+		order, err := service.Checkout(context.Background(), []string{"a", "b", "a"}, "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if order.Status != domain.OrderStatusPending || len(order.Items) != 2 || order.Total != 160 {
+			t.Errorf("expected a pending order of 2 tickets for 160 but got %+v", order)
+		}
+		if order.PaymentID == "" || saved == nil || saved.PaymentID != order.PaymentID {
+			t.Errorf("expected the payment intent to be stored on the order")
+		}
+		if holdUntil.Before(time.Now().Add(14 * time.Minute)) {
+			t.Errorf("expected holds to cover the payment window but got %v", holdUntil)
+		}
+	})
+
+	t.Run("tickets no longer held", func(t *testing.T) {
+		repo := &mocks.MockLotteryRepository{
+			HoldUntilFunc: func(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error) {
+				return (&mocks.MockLotteryRepository{}).HoldUntil(ctx, drawID, ticketIDs[:1], userID, until)
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			CreateFunc: func(ctx context.Context, order *domain.Order) error {
+				t.Error("no order should be created")
+				return nil
+			},
+		}
+		service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{})
+
+		if _, err := service.Checkout(context.Background(), []string{"a", "b"}, "user-123"); !errors.Is(err, domain.ErrTicketNotReserved) {
+			t.Errorf("expected not reserved error but got %v", err)
+		}
+	})
+
+	t.Run("reports why a ticket is no longer held", func(t *testing.T) {
+		for _, want := range []error{domain.ErrTicketAlreadySold, domain.ErrReservationExpired} {
+			repo := &mocks.MockLotteryRepository{
+				HoldUntilFunc: func(ctx context.Context, drawID string, ticketIDs []string, userID string, until time.Time) ([]domain.LotteryTicket, error) {
+					return nil, want
+				},
+			}
+			orders := &mocks.MockOrderRepository{
+				CreateFunc: func(ctx context.Context, order *domain.Order) error {
+					t.Error("no order should be created")
+					return nil
+				},
+			}
+			service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{})
+
+			if _, err := service.Checkout(context.Background(), []string{"a", "b"}, "user-123"); !errors.Is(err, want) {
+				t.Errorf("expected %v but got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("tickets already in another order keep their previous hold", func(t *testing.T) {
+		heldUntil := time.Now().Add(5 * time.Minute)
+		var restored []domain.LotteryTicket
+		repo := &mocks.MockLotteryRepository{
+			FindReservationsByUserFunc: func(ctx context.Context, userID string) ([]domain.LotteryTicket, error) {
+				return []domain.LotteryTicket{
+					{ID: "a", Status: domain.LotteryStatusReserved, ReservedBy: userID, ReservedUntil: &heldUntil},
+					{ID: "b", Status: domain.LotteryStatusReserved, ReservedBy: userID, ReservedUntil: &heldUntil},
+					{ID: "other", Status: domain.LotteryStatusReserved, ReservedBy: userID, ReservedUntil: &heldUntil},
+				}, nil
+			},
+			RestoreHoldsFunc: func(ctx context.Context, tickets []domain.LotteryTicket, userID string) error {
+				restored = tickets
+				return nil
+			},
+		}
+		// คำสั่งซื้อที่สองของตั๋วชุดเดิมชน Unique Index ของคำสั่งซื้อที่รอชำระเงินอยู่
+		orders := &mocks.MockOrderRepository{
+			CreateFunc: func(ctx context.Context, order *domain.Order) error {
+				return domain.ErrTicketInOrder
+			},
+		}
+		quota := &mocks.MockReservationQuota{
+			CommitFunc: func(ctx context.Context, userID, grantID string, granted int, tickets []domain.LotteryTicket) error {
+				t.Error("the quota must not follow holds of an order that was not placed")
+				return nil
+			},
+		}
+		service := application.NewOrderService(orders, repo, &mocks.MockLotteryDrawRepository{}, quota, &mocks.MockPaymentGateway{},
+			domain.DefaultReservationPolicy(), 15*time.Minute)
+
+		if _, err := service.Checkout(context.Background(), []string{"a", "b"}, "user-123"); !errors.Is(err, domain.ErrTicketInOrder) {
+			t.Fatalf("expected ticket in order error but got %v", err)
+		}
+		if len(restored) != 2 || restored[0].ID != "a" || restored[1].ID != "b" || !restored[0].ReservedUntil.Equal(heldUntil) {
+			t.Errorf("expected a and b to go back to their previous hold but got %+v", restored)
+		}
+	})
+
+	t.Run("payment could not be started", func(t *testing.T) {
+		gatewayErr := errors.New("gateway down")
+		var saved *domain.Order
+		orders := &mocks.MockOrderRepository{
+			UpdateFunc: func(ctx context.Context, order *domain.Order, from domain.OrderStatus) error {
+				saved = order
+				return nil
+			},
+		}
+		gateway := &mocks.MockPaymentGateway{
+			CreateIntentFunc: func(ctx context.Context, order *domain.Order) (*domain.PaymentIntent, error) {
+				return nil, gatewayErr
+			},
+		}
+		service := newTestOrderService(orders, &mocks.MockLotteryRepository{}, gateway)
+
+		if _, err := service.Checkout(context.Background(), []string{"a"}, "user-123"); !errors.Is(err, gatewayErr) {
+			t.Errorf("expected gateway error but got %v", err)
+		}
+		if saved == nil || saved.Status != domain.OrderStatusFailed {
+			t.Errorf("expected the order to be stored as failed")
+		}
+	})
+
+	t.Run("empty ticket ids", func(t *testing.T) {
+		service := newTestOrderService(&mocks.MockOrderRepository{}, &mocks.MockLotteryRepository{}, &mocks.MockPaymentGateway{})
+
+		if _, err := service.Checkout(context.Background(), nil, "user-123"); !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestOrderService_CheckoutSet(t *testing.T) {
+	draws := &mocks.MockLotteryDrawRepository{
+		FindOnSaleFunc: func(ctx context.Context, now time.Time) (*domain.LotteryDraw, error) {
+			draw := mocks.OnSaleDraw("open-draw")
+			draw.SetsPerNumber = 3
+			return draw, nil
+		},
+	}
+
+	t.Run("orders every copy", func(t *testing.T) {
+		repo := &mocks.MockLotteryRepository{
+			ReserveSetFunc: func(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				return []domain.LotteryTicket{{ID: "a", Number: number, Set: 1}, {ID: "b", Number: number, Set: 2}, {ID: "c", Number: number, Set: 3}}, nil
+			},
+		}
+		service := application.NewOrderService(&mocks.MockOrderRepository{}, repo, draws, &mocks.MockReservationQuota{}, &mocks.MockPaymentGateway{},
+			domain.DefaultReservationPolicy(), 15*time.Minute)
+
+		order, err := service.CheckoutSet(context.Background(), "123456", "user-123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(order.Items) != 3 || order.Total != 3*domain.DefaultTicketPrice {
+			t.Errorf("expected an order for 3 copies but got %+v", order)
+		}
+	})
+
+	t.Run("set no longer complete", func(t *testing.T) {
+		repo := &mocks.MockLotteryRepository{
+			ReserveSetFunc: func(ctx context.Context, drawID string, number string, userID string, ttl time.Duration) ([]domain.LotteryTicket, error) {
+				return nil, domain.ErrSetUnavailable
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			CreateFunc: func(ctx context.Context, order *domain.Order) error {
+				t.Error("no order should be created")
+				return nil
+			},
+		}
+		service := application.NewOrderService(orders, repo, draws, &mocks.MockReservationQuota{}, &mocks.MockPaymentGateway{},
+			domain.DefaultReservationPolicy(), 15*time.Minute)
+
+		if _, err := service.CheckoutSet(context.Background(), "123456", "user-123"); !errors.Is(err, domain.ErrSetUnavailable) {
+			t.Errorf("expected set unavailable error but got %v", err)
+		}
+	})
+}
+
+func TestOrderService_HandlePaymentEvent(t *testing.T) {
+	t.Run("confirmed payment sells the tickets", func(t *testing.T) {
+		order := pendingOrder("a", "b")
+		var sold []string
+		repo := &mocks.MockLotteryRepository{
+			MarkAsSoldFunc: func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+				sold = append(sold, ticketID)
+				return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusSold}, nil
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			FindByPaymentIDFunc: func(ctx context.Context, paymentID string) (*domain.Order, error) {
+				return order, nil
+			},
+		}
+		service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{ParseEventFunc: paymentEvent(domain.PaymentSucceeded)})
+
+		settled, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "sig")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settled.Status != domain.OrderStatusPaid || len(sold) != 2 {
+			t.Errorf("expected a paid order with 2 tickets sold but got %s with %v", settled.Status, sold)
+		}
+	})
+
+	t.Run("tickets that cannot be sold are refunded", func(t *testing.T) {
+		order := pendingOrder("a", "b")
+		repo := &mocks.MockLotteryRepository{
+			MarkAsSoldFunc: func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+				if ticketID == "b" {
+					return nil, domain.ErrReservationExpired
+				}
+				return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusSold}, nil
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			FindByPaymentIDFunc: func(ctx context.Context, paymentID string) (*domain.Order, error) {
+				return order, nil
+			},
+		}
+		var refunded int64
+		gateway := &mocks.MockPaymentGateway{
+			ParseEventFunc: paymentEvent(domain.PaymentSucceeded),
+			RefundFunc: func(ctx context.Context, paymentID string, amount int64) error {
+				refunded += amount
+				return nil
+			},
+		}
+		service := newTestOrderService(orders, repo, gateway)
+
+		settled, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "sig")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settled.Status != domain.OrderStatusPaid || refunded != domain.DefaultTicketPrice || !settled.Items[1].Refunded {
+			t.Errorf("expected ticket b refunded on a paid order but got %s, refunded %d", settled.Status, refunded)
+		}
+	})
+
+	t.Run("failed payment releases the reservations", func(t *testing.T) {
+		order := pendingOrder("a", "b")
+		var released []string
+		repo := &mocks.MockLotteryRepository{
+			ReleaseReservationFunc: func(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+				released = append(released, ticketID)
+				return &domain.LotteryTicket{ID: ticketID, Status: domain.LotteryStatusAvailable}, nil
+			},
+			MarkAsSoldFunc: func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+				t.Error("tickets of a failed payment must not be sold")
+				return nil, nil
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			FindByPaymentIDFunc: func(ctx context.Context, paymentID string) (*domain.Order, error) {
+				return order, nil
+			},
+		}
+		service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{ParseEventFunc: paymentEvent(domain.PaymentFailed)})
+
+		settled, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "sig")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settled.Status != domain.OrderStatusFailed || len(released) != 2 {
+			t.Errorf("expected a failed order with 2 tickets released but got %s with %v", settled.Status, released)
+		}
+	})
+
+	t.Run("repeated callback leaves a paid order alone", func(t *testing.T) {
+		order := pendingOrder("a")
+		order.MarkPaid(time.Now())
+		repo := &mocks.MockLotteryRepository{
+			MarkAsSoldFunc: func(ctx context.Context, ticketID string, userID string, drawID string) (*domain.LotteryTicket, error) {
+				t.Error("tickets must not be sold twice")
+				return nil, nil
+			},
+		}
+		orders := &mocks.MockOrderRepository{
+			FindByPaymentIDFunc: func(ctx context.Context, paymentID string) (*domain.Order, error) {
+				return order, nil
+			},
+		}
+		service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{ParseEventFunc: paymentEvent(domain.PaymentSucceeded)})
+
+		if _, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "sig"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("late payment of an expired order is refunded", func(t *testing.T) {
+		order := pendingOrder("a", "b")
+		order.MarkFailed("payment window expired", time.Now())
+		orders := &mocks.MockOrderRepository{
+			FindByPaymentIDFunc: func(ctx context.Context, paymentID string) (*domain.Order, error) {
+				return order, nil
+			},
+		}
+		var refunded int64
+		gateway := &mocks.MockPaymentGateway{
+			ParseEventFunc: paymentEvent(domain.PaymentSucceeded),
+			RefundFunc: func(ctx context.Context, paymentID string, amount int64) error {
+				refunded = amount
+				return nil
+			},
+		}
+		service := newTestOrderService(orders, &mocks.MockLotteryRepository{}, gateway)
+
+		settled, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "sig")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if settled.Status != domain.OrderStatusRefunded || refunded != order.Total {
+			t.Errorf("expected the whole order refunded but got %s, refunded %d", settled.Status, refunded)
+		}
+	})
+
+	t.Run("unverified callback", func(t *testing.T) {
+		service := newTestOrderService(&mocks.MockOrderRepository{}, &mocks.MockLotteryRepository{}, &mocks.MockPaymentGateway{})
+
+		if _, err := service.HandlePaymentEvent(context.Background(), []byte("{}"), "bad"); !errors.Is(err, domain.ErrInvalidWebhook) {
+			t.Errorf("expected invalid webhook error but got %v", err)
+		}
+	})
+}
+
+func TestOrderService_ExpirePendingOrders(t *testing.T) {
+	expired := []*domain.Order{pendingOrder("a")}
+	orders := &mocks.MockOrderRepository{
+		FindExpiredPendingFunc: func(ctx context.Context, now time.Time, limit int) ([]*domain.Order, error) {
+			batch := expired
+			expired = nil
+			return batch, nil
+		},
+	}
+	released := 0
+	repo := &mocks.MockLotteryRepository{
+		ReleaseReservationFunc: func(ctx context.Context, ticketID string, userID string) (*domain.LotteryTicket, error) {
+			released++
+			return &domain.LotteryTicket{ID: ticketID}, nil
+		},
+	}
+	service := newTestOrderService(orders, repo, &mocks.MockPaymentGateway{})
+
+	count, err := service.ExpirePendingOrders(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 || released != 1 {
+		t.Errorf("expected 1 order expired and 1 ticket released but got %d and %d", count, released)
+	}
+}
+
+func TestFakeGateway(t *testing.T) {
+	gateway := payment.NewFakeGateway("secret", "https://pay.example/")
+	order := pendingOrder("a")
+
+	intent, err := gateway.CreateIntent(context.Background(), order)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if intent.Amount != order.Total || intent.CheckoutURL != "https://pay.example/"+intent.ID {
+		t.Errorf("unexpected intent %+v", intent)
+	}
+
+	t.Run("verifies signed callbacks", func(t *testing.T) {
+		payload := []byte(`{"paymentId":"` + intent.ID + `","status":"succeeded"}`)
+		event, err := gateway.ParseEvent(payload, gateway.Sign(payload))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if event.PaymentID != intent.ID || event.Status != domain.PaymentSucceeded {
+			t.Errorf("unexpected event %+v", event)
+		}
+	})
+
+	t.Run("rejects tampered callbacks", func(t *testing.T) {
+		signature := gateway.Sign([]byte(`{"paymentId":"x","status":"failed"}`))
+		if _, err := gateway.ParseEvent([]byte(`{"paymentId":"x","status":"succeeded"}`), signature); !errors.Is(err, domain.ErrInvalidWebhook) {
+			t.Errorf("expected invalid webhook error but got %v", err)
+		}
+	})
+
+	t.Run("refunds at most the paid amount", func(t *testing.T) {
+		if err := gateway.Refund(context.Background(), intent.ID, order.Total); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := gateway.Refund(context.Background(), intent.ID, 1); err == nil {
+			t.Error("expected refunding more than was paid to fail")
+		}
+	})
+}
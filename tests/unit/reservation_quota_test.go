@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/redis"
+	"github.com/backend-challenge/user-api/internal/domain"
+	redisClient "github.com/redis/go-redis/v9"
+)
+
+// Note: This test requires a redis server to be running on localhost:6379
+func TestReservationQuota(t *testing.T) {
+	client := redisClient.NewClient(&redisClient.Options{
+		Addr: "localhost:6379",
+	})
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skip("Redis is not running on localhost:6379, skipping ReservationQuota tests")
+		return
+	}
+
+	quota := redis.NewReservationQuota(client)
+	userID := "test-quota-user"
+	client.Del(ctx, "lottery_quota:"+userID)
+	defer client.Del(ctx, "lottery_quota:"+userID)
+
+	t.Run("parallel acquire never exceeds max", func(t *testing.T) {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			total int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, granted, err := quota.Acquire(ctx, userID, 3, 10, time.Minute)
+				if err != nil {
+					t.Errorf("failed to acquire: %v", err)
+					return
+				}
+				mu.Lock()
+				total += granted
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if total != 10 {
+			t.Errorf("expected 10 granted slots but got %d", total)
+		}
+	})
+
+	t.Run("commit frees unused slots and release frees tickets", func(t *testing.T) {
+		client.Del(ctx, "lottery_quota:"+userID)
+
+		grantID, granted, err := quota.Acquire(ctx, userID, 5, 5, time.Minute)
+		if err != nil || granted != 5 {
+			t.Fatalf("expected 5 granted slots but got %d (%v)", granted, err)
+		}
+
+		until := time.Now().Add(time.Minute)
+		tickets := []domain.LotteryTicket{{ID: "t1", ReservedUntil: &until}, {ID: "t2", ReservedUntil: &until}}
+		if err := quota.Commit(ctx, userID, grantID, granted, tickets); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+
+		_, granted, _ = quota.Acquire(ctx, userID, 5, 5, time.Minute)
+		if granted != 3 {
+			t.Errorf("expected 3 free slots after commit but got %d", granted)
+		}
+
+		if err := quota.Release(ctx, userID, "t1"); err != nil {
+			t.Fatalf("failed to release: %v", err)
+		}
+		_, granted, _ = quota.Acquire(ctx, userID, 5, 5, time.Minute)
+		if granted != 1 {
+			t.Errorf("expected 1 free slot after release but got %d", granted)
+		}
+	})
+}
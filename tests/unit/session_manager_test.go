@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/backend-challenge/user-api/internal/adapters/redis"
+	"github.com/backend-challenge/user-api/internal/domain"
 	redisClient "github.com/redis/go-redis/v9"
 )
 
@@ -56,4 +57,62 @@ func TestSessionManager(t *testing.T) {
 			t.Error("expected empty value after delete")
 		}
 	})
+
+	t.Run("Index User Sessions", func(t *testing.T) {
+		userID := "test-user"
+		defer client.Del(ctx, "session:user:"+userID, "session:info:session-1", "session:info:session-2")
+
+		now := time.Now()
+		newSession := func(id string) *domain.LoginSession {
+			return &domain.LoginSession{ID: id, Device: "Chrome on Linux", IP: "10.0.0.1",
+				CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(ttl)}
+		}
+		if err := mgr.IndexUserSession(ctx, userID, newSession("session-1")); err != nil {
+			t.Fatalf("failed to index session: %v", err)
+		}
+		if err := mgr.IndexUserSession(ctx, userID, newSession("session-2")); err != nil {
+			t.Fatalf("failed to index session: %v", err)
+		}
+		if err := mgr.UnindexUserSession(ctx, userID, "session-1"); err != nil {
+			t.Fatalf("failed to unindex session: %v", err)
+		}
+
+		ids, err := mgr.UserSessionIDs(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to list sessions: %v", err)
+		}
+		if len(ids) != 1 || ids[0] != "session-2" {
+			t.Errorf("expected [session-2] but got %v", ids)
+		}
+
+		// Indexing again after a refresh keeps the creation time
+		refreshed := newSession("session-2")
+		refreshed.IP = "10.0.0.2"
+		refreshed.CreatedAt = now.Add(time.Minute)
+		if err := mgr.IndexUserSession(ctx, userID, refreshed); err != nil {
+			t.Fatalf("failed to index session: %v", err)
+		}
+		seenAt := now.Add(2 * time.Minute)
+		if err := mgr.TouchUserSession(ctx, "session-2", seenAt); err != nil {
+			t.Fatalf("failed to touch session: %v", err)
+		}
+
+		sessions, err := mgr.UserSessions(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to list sessions: %v", err)
+		}
+		if len(sessions) != 1 {
+			t.Fatalf("expected 1 session but got %d", len(sessions))
+		}
+		got := sessions[0]
+		if got.ID != "session-2" || got.Device != "Chrome on Linux" || got.IP != "10.0.0.2" {
+			t.Errorf("unexpected session %+v", got)
+		}
+		if got.CreatedAt.UnixMilli() != now.UnixMilli() {
+			t.Errorf("expected created at %v but got %v", now, got.CreatedAt)
+		}
+		if got.LastSeenAt.UnixMilli() != seenAt.UnixMilli() {
+			t.Errorf("expected last seen at %v but got %v", seenAt, got.LastSeenAt)
+		}
+	})
 }
@@ -0,0 +1,58 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/backend-challenge/user-api/internal/adapters/mongodb"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUserRepository_MigrateEmailVerified(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil || client.Ping(ctx, nil) != nil {
+		t.Skip("MongoDB is not running on localhost:27017, skipping UserRepository tests")
+	}
+	db := client.Database("user_repository_test")
+	db.Drop(context.Background())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	repo := mongodb.NewUserRepository(db)
+
+	// บัญชีที่สร้างก่อนมีการยืนยันอีเมลไม่มีฟิลด์ email_verified
+	if _, err := db.Collection("users").InsertOne(context.Background(), bson.M{
+		"_id": "existing-id", "name": "Existing", "email": "existing@example.com", "roles": []string{"user"},
+	}); err != nil {
+		t.Fatalf("failed to insert the existing user: %v", err)
+	}
+	if err := repo.Create(context.Background(), &domain.User{ID: "new-id", Name: "New", Email: "new@example.com"}); err != nil {
+		t.Fatalf("failed to create the new user: %v", err)
+	}
+
+	migrated, err := repo.MigrateEmailVerified(context.Background())
+	if err != nil || migrated != 1 {
+		t.Fatalf("expected one user to be migrated but got %d, %v", migrated, err)
+	}
+	if migrated, err := repo.MigrateEmailVerified(context.Background()); err != nil || migrated != 0 {
+		t.Errorf("expected nothing left to migrate but got %d, %v", migrated, err)
+	}
+
+	existing, err := repo.FindByID(context.Background(), "existing-id")
+	if err != nil || !existing.EmailVerified {
+		t.Errorf("expected the existing user to be verified but got %+v, %v", existing, err)
+	}
+	created, err := repo.FindByID(context.Background(), "new-id")
+	if err != nil || created.EmailVerified {
+		t.Errorf("expected the new user to still need verification but got %+v, %v", created, err)
+	}
+}
@@ -0,0 +1,264 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	httpAdapter "github.com/backend-challenge/user-api/internal/adapters/http"
+	"github.com/backend-challenge/user-api/internal/adapters/jwt"
+	"github.com/backend-challenge/user-api/internal/application"
+	"github.com/backend-challenge/user-api/internal/domain"
+	"github.com/backend-challenge/user-api/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newMemorySessionManager keeps sessions and the per-user session index in memory, so a test
+// can follow a login through refreshes and revocations like the Redis adapter would
+func newMemorySessionManager() *mocks.MockSessionManager {
+	var mu sync.Mutex
+	sessions := make(map[string]string)
+	index := make(map[string]map[string]domain.LoginSession)
+
+	return &mocks.MockSessionManager{
+		StoreSessionFunc: func(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			sessions[key] = string(raw)
+			return nil
+		},
+		GetSessionFunc: func(ctx context.Context, key string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return sessions[key], nil
+		},
+		TakeSessionFunc: func(ctx context.Context, key string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			value := sessions[key]
+			delete(sessions, key)
+			return value, nil
+		},
+		DeleteSessionFunc: func(ctx context.Context, key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(sessions, key)
+			return nil
+		},
+		IndexUserSessionFunc: func(ctx context.Context, userID string, session *domain.LoginSession) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if index[userID] == nil {
+				index[userID] = make(map[string]domain.LoginSession)
+			}
+			index[userID][session.ID] = *session
+			return nil
+		},
+		UserSessionIDsFunc: func(ctx context.Context, userID string) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var ids []string
+			for id := range index[userID] {
+				ids = append(ids, id)
+			}
+			return ids, nil
+		},
+		UserSessionsFunc: func(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []domain.LoginSession
+			for _, session := range index[userID] {
+				list = append(list, session)
+			}
+			return list, nil
+		},
+		UnindexUserSessionFunc: func(ctx context.Context, userID, sessionID string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(index[userID], sessionID)
+			return nil
+		},
+	}
+}
+
+// newMemoryUserRepository keeps the given users in memory
+func newMemoryUserRepository(users ...*domain.User) *mocks.MockUserRepository {
+	var mu sync.Mutex
+	byID := make(map[string]*domain.User)
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	return &mocks.MockUserRepository{
+		FindByIDFunc: func(ctx context.Context, id string) (*domain.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			user, ok := byID[id]
+			if !ok {
+				return nil, domain.ErrUserNotFound
+			}
+			copied := *user
+			return &copied, nil
+		},
+		FindByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, user := range byID {
+				if user.Email == email {
+					copied := *user
+					return &copied, nil
+				}
+			}
+			return nil, domain.ErrUserNotFound
+		},
+		UpdateRolesFunc: func(ctx context.Context, id string, roles []domain.Role) error {
+			mu.Lock()
+			defer mu.Unlock()
+			user, ok := byID[id]
+			if !ok {
+				return domain.ErrUserNotFound
+			}
+			user.Roles = roles
+			return nil
+		},
+		DeleteFunc: func(ctx context.Context, id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := byID[id]; !ok {
+				return domain.ErrUserNotFound
+			}
+			delete(byID, id)
+			return nil
+		},
+	}
+}
+
+// accountRoutes is the API router over in-memory users and sessions with two accounts signed in
+type accountRoutes struct {
+	router      *gin.Engine
+	authService *application.AuthService
+	tokens      map[string][2]string
+}
+
+func newAccountRoutes(t *testing.T) *accountRoutes {
+	gin.SetMode(gin.TestMode)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	repo := newMemoryUserRepository(
+		&domain.User{ID: "admin-id", Email: "admin@example.com", Password: string(hash), Roles: []domain.Role{domain.RoleUser, domain.RoleAdmin}},
+		&domain.User{ID: "user-id", Email: "user@example.com", Password: string(hash), Roles: domain.DefaultRoles()},
+	)
+	authService := application.NewAuthService(repo, newMemorySessionManager(), jwt.NewTokenService("test-secret", 900, 3600),
+		&mocks.MockNotifier{}, domain.DefaultAccountPolicy())
+	userService := application.NewUserService(repo, authService)
+
+	routes := &accountRoutes{
+		router:      httpAdapter.SetupRouter(userService, authService, nil, nil, nil, nil, time.Minute, false),
+		authService: authService,
+		tokens:      make(map[string][2]string),
+	}
+	for _, email := range []string{"admin@example.com", "user@example.com"} {
+		access, refresh, err := authService.Login(context.Background(), email, "Password123!", domain.ClientInfo{})
+		if err != nil {
+			t.Fatalf("failed to log in %s: %v", email, err)
+		}
+		routes.tokens[email] = [2]string{access, refresh}
+	}
+	return routes
+}
+
+func (r *accountRoutes) send(method, path, accessToken, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+	return w
+}
+
+func (r *accountRoutes) refresh(email string) int {
+	body, _ := json.Marshal(map[string]string{"refreshToken": r.tokens[email][1]})
+	return r.send(http.MethodPost, "/api/v1/auth/refresh", "", string(body)).Code
+}
+
+func TestDeleteUserRoutesRevokeSessions(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		caller string
+	}{
+		{name: "admin deletes the account", path: "/api/v1/users/user-id", caller: "admin@example.com"},
+		{name: "user deletes their account by id", path: "/api/v1/users/user-id", caller: "user@example.com"},
+		{name: "user deletes their own account", path: "/api/v1/users/me", caller: "user@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := newAccountRoutes(t)
+
+			if w := routes.send(http.MethodDelete, tt.path, routes.tokens[tt.caller][0], ""); w.Code != http.StatusOK {
+				t.Fatalf("expected the account to be deleted but got %d: %s", w.Code, w.Body.String())
+			}
+
+			if got := routes.refresh("user@example.com"); got != http.StatusUnauthorized {
+				t.Errorf("expected the deleted user's refresh token to be rejected but got %d", got)
+			}
+			if w := routes.send(http.MethodGet, "/api/v1/users/me", routes.tokens["user@example.com"][0], ""); w.Code != http.StatusUnauthorized {
+				t.Errorf("expected the deleted user's access token to be rejected but got %d", w.Code)
+			}
+			if got := routes.refresh("admin@example.com"); tt.caller == "admin@example.com" && got != http.StatusOK {
+				t.Errorf("expected the admin to stay signed in but got %d", got)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRolesRouteRevokesSessions(t *testing.T) {
+	routes := newAccountRoutes(t)
+	admin := routes.tokens["admin@example.com"][0]
+	setRoles := func(roles string) {
+		w := routes.send(http.MethodPut, "/api/v1/users/user-id/roles", admin, `{"roles": `+roles+`}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the roles to be updated but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	login := func() string {
+		access, _, err := routes.authService.Login(context.Background(), "user@example.com", "Password123!", domain.ClientInfo{})
+		if err != nil {
+			t.Fatalf("failed to log in: %v", err)
+		}
+		return access
+	}
+
+	setRoles(`["operator"]`)
+	if w := routes.send(http.MethodGet, "/api/v1/users/admin-id", routes.tokens["user@example.com"][0], ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the session from before the promotion to be revoked but got %d", w.Code)
+	}
+	if got := routes.refresh("user@example.com"); got != http.StatusUnauthorized {
+		t.Errorf("expected the refresh token from before the promotion to be rejected but got %d", got)
+	}
+
+	operator := login()
+	if w := routes.send(http.MethodGet, "/api/v1/users/admin-id", operator, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected a new login to carry the operator role but got %d", w.Code)
+	}
+
+	// ผู้ที่ถูกลดสิทธิ์ต้องไม่ใช้สิทธิ์เดิมต่อได้จนกว่า Access Token จะหมดอายุ
+	setRoles(`["user"]`)
+	if w := routes.send(http.MethodGet, "/api/v1/users/admin-id", operator, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the demoted operator to be signed out but got %d", w.Code)
+	}
+	if w := routes.send(http.MethodGet, "/api/v1/users/admin-id", login(), ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected a new login without the operator role to be forbidden but got %d", w.Code)
+	}
+}
@@ -73,14 +73,26 @@ func TestUserService_CreateUser(t *testing.T) {
 			mockRepo := &mocks.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := application.NewUserService(mockRepo)
+			var sent []string
+			service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{
+				SendVerificationFunc: func(ctx context.Context, user *domain.User) error {
+					sent = append(sent, user.Email)
+					return nil
+				},
+			})
 			user, err := service.CreateUser(context.Background(), tt.userName, tt.email, tt.password)
 
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("expected error but got none")
 				}
+				if len(sent) != 0 {
+					t.Errorf("expected no verification link for an account that was not created but got %v", sent)
+				}
 			} else {
+				if len(sent) != 1 || sent[0] != tt.email {
+					t.Errorf("expected a verification link for %s but got %v", tt.email, sent)
+				}
 				if err != nil {
 					t.Errorf("unexpected error: %v", err)
 				}
@@ -97,7 +109,7 @@ func TestUserService_CreateUser(t *testing.T) {
 
 func TestUserService_GetUserByID(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{})
 
 	t.Run("user found", func(t *testing.T) {
 		expectedUser := &domain.User{
@@ -133,7 +145,7 @@ func TestUserService_GetUserByID(t *testing.T) {
 
 func TestUserService_UpdateUser(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{})
 
 	t.Run("successful update", func(t *testing.T) {
 		existingUser := &domain.User{
@@ -159,6 +171,41 @@ func TestUserService_UpdateUser(t *testing.T) {
 		}
 	})
 
+	t.Run("changing the email resends verification", func(t *testing.T) {
+		var sent []string
+		service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{
+			SendVerificationFunc: func(ctx context.Context, user *domain.User) error {
+				sent = append(sent, user.Email)
+				return nil
+			},
+		})
+		var saved *domain.User
+		mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Name: "John Doe", Email: "john@example.com", EmailVerified: true}, nil
+		}
+		mockRepo.UpdateFunc = func(ctx context.Context, user *domain.User) error {
+			saved = user
+			return nil
+		}
+
+		if _, err := service.UpdateUser(context.Background(), "test-id", "Jane Doe", "john@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !saved.EmailVerified || len(sent) != 0 {
+			t.Errorf("expected the same email to stay verified without a new link but got %+v, %v", saved, sent)
+		}
+
+		if _, err := service.UpdateUser(context.Background(), "test-id", "Jane Doe", "jane@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if saved.EmailVerified {
+			t.Error("expected a changed email to need verification again")
+		}
+		if len(sent) != 1 || sent[0] != "jane@example.com" {
+			t.Errorf("expected a verification link for jane@example.com but got %v", sent)
+		}
+	})
+
 	t.Run("user not found", func(t *testing.T) {
 		mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
 			return nil, domain.ErrUserNotFound
@@ -171,9 +218,215 @@ func TestUserService_UpdateUser(t *testing.T) {
 	})
 }
 
+func TestUserService_PatchUser(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	var sent []string
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{
+		SendVerificationFunc: func(ctx context.Context, user *domain.User) error {
+			sent = append(sent, user.Email)
+			return errors.New("mail server unavailable")
+		},
+	})
+
+	mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+		return &domain.User{ID: id, Name: "John Doe", Email: "john@example.com"}, nil
+	}
+	var saved *domain.User
+	mockRepo.UpdateFunc = func(ctx context.Context, user *domain.User) error {
+		saved = user
+		return nil
+	}
+
+	t.Run("name only", func(t *testing.T) {
+		name := "Jane Doe"
+		user, err := service.PatchUser(context.Background(), "test-id", &name, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user.Name != "Jane Doe" || saved.Email != "john@example.com" {
+			t.Errorf("expected only the name to change but got %+v", saved)
+		}
+	})
+
+	t.Run("email only", func(t *testing.T) {
+		email := "jane@example.com"
+		user, err := service.PatchUser(context.Background(), "test-id", nil, &email)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user.Email != "jane@example.com" || saved.Name != "John Doe" {
+			t.Errorf("expected only the email to change but got %+v", saved)
+		}
+	})
+
+	t.Run("changing the email clears verification and resends it", func(t *testing.T) {
+		mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Name: "John Doe", Email: "john@example.com", EmailVerified: true}, nil
+		}
+		sent = nil
+
+		same := "john@example.com"
+		if _, err := service.PatchUser(context.Background(), "test-id", nil, &same); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !saved.EmailVerified || len(sent) != 0 {
+			t.Errorf("expected the same email to stay verified without a new link but got %+v, %v", saved, sent)
+		}
+
+		// ส่งอีเมลไม่สำเร็จไม่ทำให้การเปลี่ยนอีเมลล้มเหลว ผู้ใช้ขอลิงก์ใหม่ได้
+		changed := "jane@example.com"
+		if _, err := service.PatchUser(context.Background(), "test-id", nil, &changed); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if saved.EmailVerified {
+			t.Error("expected a changed email to need verification again")
+		}
+		if len(sent) != 1 || sent[0] != "jane@example.com" {
+			t.Errorf("expected a verification link for jane@example.com but got %v", sent)
+		}
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		email := "not-an-email"
+		_, err := service.PatchUser(context.Background(), "test-id", nil, &email)
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+
+	t.Run("nothing to change", func(t *testing.T) {
+		_, err := service.PatchUser(context.Background(), "test-id", nil, nil)
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestUserService_UpdateUserRoles(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	var revoked []string
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{
+		RevokeAllSessionsFunc: func(ctx context.Context, userID, keepSessionID string) (int, error) {
+			revoked = append(revoked, userID)
+			return 1, nil
+		},
+	})
+
+	mockRepo.FindByIDFunc = func(ctx context.Context, id string) (*domain.User, error) {
+		return &domain.User{ID: id, Roles: domain.DefaultRoles()}, nil
+	}
+
+	t.Run("keeps the user role", func(t *testing.T) {
+		var saved []domain.Role
+		mockRepo.UpdateRolesFunc = func(ctx context.Context, id string, roles []domain.Role) error {
+			saved = roles
+			return nil
+		}
+
+		user, err := service.UpdateUserRoles(context.Background(), "test-id", []string{"operator", "operator"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(saved) != 2 || saved[0] != domain.RoleUser || saved[1] != domain.RoleOperator {
+			t.Errorf("expected roles [user operator] but got %v", saved)
+		}
+		if !user.HasRole(domain.RoleOperator) {
+			t.Errorf("expected the returned user to be an operator but got %v", user.Roles)
+		}
+	})
+
+	t.Run("a change signs the user out everywhere", func(t *testing.T) {
+		revoked = nil
+		mockRepo.UpdateRolesFunc = func(ctx context.Context, id string, roles []domain.Role) error {
+			return nil
+		}
+
+		if _, err := service.UpdateUserRoles(context.Background(), "test-id", []string{"admin"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(revoked) != 1 || revoked[0] != "test-id" {
+			t.Errorf("expected the sessions of test-id to be revoked but got %v", revoked)
+		}
+	})
+
+	t.Run("unchanged roles keep the sessions", func(t *testing.T) {
+		revoked = nil
+		mockRepo.UpdateRolesFunc = func(ctx context.Context, id string, roles []domain.Role) error {
+			t.Error("expected unchanged roles not to be saved")
+			return nil
+		}
+
+		if _, err := service.UpdateUserRoles(context.Background(), "test-id", []string{"user"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(revoked) != 0 {
+			t.Errorf("expected no session to be revoked but got %v", revoked)
+		}
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		mockRepo.UpdateRolesFunc = func(ctx context.Context, id string, roles []domain.Role) error {
+			t.Error("expected roles not to be saved")
+			return nil
+		}
+
+		_, err := service.UpdateUserRoles(context.Background(), "test-id", []string{"root"})
+		if !errors.Is(err, domain.ErrRequestInvalid) {
+			t.Errorf("expected invalid request error but got %v", err)
+		}
+	})
+}
+
+func TestUserService_GrantAdmin(t *testing.T) {
+	mockRepo := &mocks.MockUserRepository{}
+	var revoked []string
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{
+		RevokeAllSessionsFunc: func(ctx context.Context, userID, keepSessionID string) (int, error) {
+			revoked = append(revoked, userID)
+			return 1, nil
+		},
+	})
+
+	mockRepo.FindByEmailFunc = func(ctx context.Context, email string) (*domain.User, error) {
+		switch email {
+		case "user@example.com":
+			return &domain.User{ID: "user-id", Roles: domain.DefaultRoles()}, nil
+		case "admin@example.com":
+			return &domain.User{ID: "admin-id", Roles: []domain.Role{domain.RoleUser, domain.RoleAdmin}}, nil
+		}
+		return nil, domain.ErrUserNotFound
+	}
+	var promoted []string
+	mockRepo.UpdateRolesFunc = func(ctx context.Context, id string, roles []domain.Role) error {
+		if !domain.HasAnyRole(roles, domain.RoleAdmin) {
+			t.Errorf("expected %s to be given the admin role but got %v", id, roles)
+		}
+		promoted = append(promoted, id)
+		return nil
+	}
+
+	count, err := service.GrantAdmin(context.Background(), []string{"user@example.com", "admin@example.com", "missing@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 || len(promoted) != 1 || promoted[0] != "user-id" {
+		t.Errorf("expected only user-id to be promoted but got count=%d promoted=%v", count, promoted)
+	}
+	if len(revoked) != 1 || revoked[0] != "user-id" {
+		t.Errorf("expected the sessions of the promoted user-id to be revoked but got %v", revoked)
+	}
+}
+
 func TestUserService_DeleteUser(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	var revoked []string
+	security := &mocks.MockAccountSecurity{
+		RevokeAllSessionsFunc: func(ctx context.Context, userID, keepSessionID string) (int, error) {
+			revoked = append(revoked, userID+"/"+keepSessionID)
+			return 1, nil
+		},
+	}
+	service := application.NewUserService(mockRepo, security)
 
 	t.Run("successful delete", func(t *testing.T) {
 		mockRepo.DeleteFunc = func(ctx context.Context, id string) error {
@@ -184,6 +437,9 @@ func TestUserService_DeleteUser(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
+		if len(revoked) != 1 || revoked[0] != "test-id/" {
+			t.Errorf("expected every session of test-id to be revoked but got %v", revoked)
+		}
 	})
 
 	t.Run("user not found", func(t *testing.T) {
@@ -200,7 +456,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 
 func TestUserService_ListUsers(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{})
 
 	t.Run("successful list", func(t *testing.T) {
 		expectedUsers := []*domain.User{
@@ -224,7 +480,7 @@ func TestUserService_ListUsers(t *testing.T) {
 
 func TestUserService_GetUserCount(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{})
 
 	t.Run("successful count", func(t *testing.T) {
 		mockRepo.CountFunc = func(ctx context.Context) (int64, error) {
@@ -254,7 +510,7 @@ func TestUserService_GetUserCount(t *testing.T) {
 
 func TestUserService_RepositoryErrors(t *testing.T) {
 	mockRepo := &mocks.MockUserRepository{}
-	service := application.NewUserService(mockRepo)
+	service := application.NewUserService(mockRepo, &mocks.MockAccountSecurity{})
 	ctx := context.Background()
 	dbErr := errors.New("db error")
 